@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/alimosavifard/zyros-backend/migrations"
+	"gorm.io/gorm"
+)
+
+const usage = `usage:
+  migrate up        apply all pending migrations
+  migrate down N    revert the last N applied migrations
+  migrate status    list migrations and whether they are applied
+  seed              create default roles, permissions and the admin user`
+
+// Run executes a maintenance subcommand such as "migrate up" or "seed".
+func Run(args []string, db *gorm.DB) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", usage)
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runMigrate(args[1:], db)
+	case "seed":
+		return migrations.Seed(db)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func runMigrate(args []string, db *gorm.DB) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate subcommand\n%s", usage)
+	}
+
+	switch args[0] {
+	case "up":
+		return migrations.Up(db)
+	case "down":
+		if len(args) < 2 {
+			return fmt.Errorf("migrate down requires the number of migrations to revert\n%s", usage)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid migration count %q: %w", args[1], err)
+		}
+		return migrations.Down(db, n)
+	case "status":
+		return printStatus(db)
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q\n%s", args[0], usage)
+	}
+}
+
+func printStatus(db *gorm.DB) error {
+	statuses, err := migrations.Status(db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", "-"
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
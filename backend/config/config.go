@@ -2,46 +2,149 @@ package config
 
 import (
 	"os"
+	"strings"
 )
 
 // Config holds all application-wide configuration settings.
 type Config struct {
-	PORT              string
-	DB_HOST           string
-	DB_USER           string
-	DB_PASSWORD       string
-	DB_NAME           string
-	DB_PORT           string
-	DB_MAX_OPEN_CONNS string
-	DB_MAX_IDLE_CONNS string
-	JWT_SECRET        string
-	JWT_EXPIRATION    string
-	CSRF_SECRET       string
-	REDIS_ADDR        string
-	REDIS_PASSWORD    string
-	REDIS_DB          string
-	ALLOWED_ORIGINS   string
-	RATE_LIMIT        string
+	PORT                     string
+	DB_HOST                  string
+	DB_USER                  string
+	DB_PASSWORD              string
+	DB_NAME                  string
+	DB_PORT                  string
+	DB_MAX_OPEN_CONNS        string
+	DB_MAX_IDLE_CONNS        string
+	DB_AUTO_MIGRATE          string
+	JWT_SECRET               string
+	JWT_EXPIRATION           string
+	JWT_ALGORITHM            string
+	JWT_ACCEPT_HS256         string
+	JWT_KEY_ROTATION         string
+	JWT_KEY_OVERLAP          string
+	JWT_KEY_ENCRYPTION_KEY   string
+	REFRESH_TOKEN_EXPIRATION string
+	CSRF_SECRET              string
+	REDIS_ADDR               string
+	REDIS_PASSWORD           string
+	REDIS_DB                 string
+	ALLOWED_ORIGINS          string
+	RATE_LIMIT               string
+	SCHEDULER_INTERVAL       string
+	POST_REVISION_LIMIT      string
+	APP_URL                  string
+	MAIL_DRIVER              string
+	MAIL_FROM                string
+	MAIL_OUTBOX_DIR          string
+	SMTP_HOST                string
+	SMTP_PORT                string
+	SMTP_USERNAME            string
+	SMTP_PASSWORD            string
+	MFA_ENCRYPTION_KEY       string
+	MFA_ENFORCE_PRIVILEGED   string
+	LOGIN_MAX_FAILURES       string
+	LOGIN_IP_MAX_FAILURES    string
+	LOGIN_LOCKOUT            string
+	LOGIN_MAX_LOCKOUT        string
+	AUDIT_HMAC_KEY           string
+	OIDC_REDIRECT_URL        string
+	OIDC_PROVIDERS           []OIDCProvider
+	STORAGE_DRIVER           string
+	STORAGE_DIR              string
+	STORAGE_PUBLIC_URL       string
+	STORAGE_SIGNING_KEY      string
+	S3_ENDPOINT              string
+	S3_REGION                string
+	S3_BUCKET                string
+	S3_ACCESS_KEY_ID         string
+	S3_SECRET_ACCESS_KEY     string
+	S3_PATH_STYLE            string
+}
+
+// OIDCProvider is an OpenID Connect identity provider named in OIDC_PROVIDERS. Its
+// settings come from OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET and _SCOPES.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       string
 }
 
 // NewConfig loads the environment variables into a Config struct.
 func NewConfig() *Config {
 	return &Config{
-		PORT:              os.Getenv("PORT"),
-		DB_HOST:           os.Getenv("DB_HOST"),
-		DB_USER:           os.Getenv("DB_USER"),
-		DB_PASSWORD:       os.Getenv("DB_PASSWORD"),
-		DB_NAME:           os.Getenv("DB_NAME"),
-		DB_PORT:           os.Getenv("DB_PORT"),
-		DB_MAX_OPEN_CONNS: os.Getenv("DB_MAX_OPEN_CONNS"),
-		DB_MAX_IDLE_CONNS: os.Getenv("DB_MAX_IDLE_CONNS"),
-		JWT_SECRET:        os.Getenv("JWT_SECRET"),
-		JWT_EXPIRATION:    os.Getenv("JWT_EXPIRATION"),
-		CSRF_SECRET:       os.Getenv("CSRF_SECRET"),
-		REDIS_ADDR:        os.Getenv("REDIS_ADDR"),
-		REDIS_PASSWORD:    os.Getenv("REDIS_PASSWORD"),
-		REDIS_DB:          os.Getenv("REDIS_DB"),
-		ALLOWED_ORIGINS:   os.Getenv("ALLOWED_ORIGINS"),
-		RATE_LIMIT:        os.Getenv("RATE_LIMIT"),
+		PORT:                     os.Getenv("PORT"),
+		DB_HOST:                  os.Getenv("DB_HOST"),
+		DB_USER:                  os.Getenv("DB_USER"),
+		DB_PASSWORD:              os.Getenv("DB_PASSWORD"),
+		DB_NAME:                  os.Getenv("DB_NAME"),
+		DB_PORT:                  os.Getenv("DB_PORT"),
+		DB_MAX_OPEN_CONNS:        os.Getenv("DB_MAX_OPEN_CONNS"),
+		DB_MAX_IDLE_CONNS:        os.Getenv("DB_MAX_IDLE_CONNS"),
+		DB_AUTO_MIGRATE:          os.Getenv("DB_AUTO_MIGRATE"),
+		JWT_SECRET:               os.Getenv("JWT_SECRET"),
+		JWT_EXPIRATION:           os.Getenv("JWT_EXPIRATION"),
+		JWT_ALGORITHM:            os.Getenv("JWT_ALGORITHM"),
+		JWT_ACCEPT_HS256:         os.Getenv("JWT_ACCEPT_HS256"),
+		JWT_KEY_ROTATION:         os.Getenv("JWT_KEY_ROTATION"),
+		JWT_KEY_OVERLAP:          os.Getenv("JWT_KEY_OVERLAP"),
+		JWT_KEY_ENCRYPTION_KEY:   os.Getenv("JWT_KEY_ENCRYPTION_KEY"),
+		REFRESH_TOKEN_EXPIRATION: os.Getenv("REFRESH_TOKEN_EXPIRATION"),
+		CSRF_SECRET:              os.Getenv("CSRF_SECRET"),
+		REDIS_ADDR:               os.Getenv("REDIS_ADDR"),
+		REDIS_PASSWORD:           os.Getenv("REDIS_PASSWORD"),
+		REDIS_DB:                 os.Getenv("REDIS_DB"),
+		ALLOWED_ORIGINS:          os.Getenv("ALLOWED_ORIGINS"),
+		RATE_LIMIT:               os.Getenv("RATE_LIMIT"),
+		SCHEDULER_INTERVAL:       os.Getenv("SCHEDULER_INTERVAL"),
+		POST_REVISION_LIMIT:      os.Getenv("POST_REVISION_LIMIT"),
+		APP_URL:                  os.Getenv("APP_URL"),
+		MAIL_DRIVER:              os.Getenv("MAIL_DRIVER"),
+		MAIL_FROM:                os.Getenv("MAIL_FROM"),
+		MAIL_OUTBOX_DIR:          os.Getenv("MAIL_OUTBOX_DIR"),
+		SMTP_HOST:                os.Getenv("SMTP_HOST"),
+		SMTP_PORT:                os.Getenv("SMTP_PORT"),
+		SMTP_USERNAME:            os.Getenv("SMTP_USERNAME"),
+		SMTP_PASSWORD:            os.Getenv("SMTP_PASSWORD"),
+		MFA_ENCRYPTION_KEY:       os.Getenv("MFA_ENCRYPTION_KEY"),
+		MFA_ENFORCE_PRIVILEGED:   os.Getenv("MFA_ENFORCE_PRIVILEGED"),
+		LOGIN_MAX_FAILURES:       os.Getenv("LOGIN_MAX_FAILURES"),
+		LOGIN_IP_MAX_FAILURES:    os.Getenv("LOGIN_IP_MAX_FAILURES"),
+		LOGIN_LOCKOUT:            os.Getenv("LOGIN_LOCKOUT"),
+		LOGIN_MAX_LOCKOUT:        os.Getenv("LOGIN_MAX_LOCKOUT"),
+		AUDIT_HMAC_KEY:           os.Getenv("AUDIT_HMAC_KEY"),
+		OIDC_REDIRECT_URL:        os.Getenv("OIDC_REDIRECT_URL"),
+		OIDC_PROVIDERS:           loadOIDCProviders(os.Getenv("OIDC_PROVIDERS")),
+		STORAGE_DRIVER:           os.Getenv("STORAGE_DRIVER"),
+		STORAGE_DIR:              os.Getenv("STORAGE_DIR"),
+		STORAGE_PUBLIC_URL:       os.Getenv("STORAGE_PUBLIC_URL"),
+		STORAGE_SIGNING_KEY:      os.Getenv("STORAGE_SIGNING_KEY"),
+		S3_ENDPOINT:              os.Getenv("S3_ENDPOINT"),
+		S3_REGION:                os.Getenv("S3_REGION"),
+		S3_BUCKET:                os.Getenv("S3_BUCKET"),
+		S3_ACCESS_KEY_ID:         os.Getenv("S3_ACCESS_KEY_ID"),
+		S3_SECRET_ACCESS_KEY:     os.Getenv("S3_SECRET_ACCESS_KEY"),
+		S3_PATH_STYLE:            os.Getenv("S3_PATH_STYLE"),
+	}
+}
+
+// loadOIDCProviders reads the settings of each provider in the comma-separated list.
+func loadOIDCProviders(names string) []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       os.Getenv(prefix + "SCOPES"),
+		})
 	}
-}
\ No newline at end of file
+	return providers
+}
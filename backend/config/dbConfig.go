@@ -9,7 +9,6 @@ import (
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"github.com/alimosavifard/zyros-backend/migrations"
 	"github.com/alimosavifard/zyros-backend/utils"
 )
 
@@ -39,10 +38,6 @@ func ConnectDB(cfg *Config) *gorm.DB {
 		sqlDB.SetMaxIdleConns(maxIdleConns)
 		sqlDB.SetConnMaxLifetime(0)
 		logger.Info().Msg("Database connection established successfully")
-
-		if err := migrations.RunMigrations(DB); err != nil {
-			logger.Fatal().Err(err).Msg("Failed to run migrations")
-		}
 	})
 	return DB
 }
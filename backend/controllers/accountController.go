@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountController serves the password reset and email verification flows. The
+// request endpoints answer the same way whether or not the address is known.
+type AccountController struct {
+	accountService *services.AccountService
+}
+
+func NewAccountController(accountService *services.AccountService) *AccountController {
+	return &AccountController{accountService: accountService}
+}
+
+func (c *AccountController) ForgotPassword(ctx *gin.Context) {
+	var req requests.EmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	if err := c.accountService.RequestPasswordReset(ctx, req.Email); err != nil {
+		utils.InitLogger().Error().Err(err).Msg("Failed to start password reset")
+	}
+	utils.SendSuccess(ctx, "If a verified account uses this address, a reset link has been sent", nil, nil)
+}
+
+func (c *AccountController) ResetPassword(ctx *gin.Context) {
+	var req requests.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	if err := c.accountService.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		c.sendError(ctx, "Failed to reset password", err)
+		return
+	}
+
+	clearAuthCookies(ctx)
+	utils.SendSuccess(ctx, "Password reset successfully", nil, nil)
+}
+
+func (c *AccountController) RequestEmailVerification(ctx *gin.Context) {
+	var req requests.EmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	if err := c.accountService.RequestEmailVerification(ctx, req.Email); err != nil {
+		utils.InitLogger().Error().Err(err).Msg("Failed to start email verification")
+	}
+	utils.SendSuccess(ctx, "If an unverified account uses this address, a verification link has been sent", nil, nil)
+}
+
+func (c *AccountController) VerifyEmail(ctx *gin.Context) {
+	var req requests.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	if err := c.accountService.VerifyEmail(ctx, req.Token); err != nil {
+		c.sendError(ctx, "Failed to verify email", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Email verified successfully", nil, nil)
+}
+
+func (c *AccountController) sendError(ctx *gin.Context, message string, err error) {
+	if errors.Is(err, utils.ErrInvalidAccountToken) {
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	utils.SendError(ctx, http.StatusInternalServerError, message, err)
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController lets users manage the API keys their scripts log in with.
+type APIKeyController struct {
+	apiKeyService *services.APIKeyService
+	authService   *services.AuthService
+}
+
+func NewAPIKeyController(apiKeyService *services.APIKeyService, authService *services.AuthService) *APIKeyController {
+	return &APIKeyController{apiKeyService: apiKeyService, authService: authService}
+}
+
+func (c *APIKeyController) GetMyAPIKeys(ctx *gin.Context) {
+	keys, err := c.apiKeyService.List(ctx, ctx.GetUint("userID"))
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve API keys", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "API keys retrieved successfully", gin.H{"api_keys": keys}, nil)
+}
+
+// CreateAPIKey returns the new key in clear. It can't be retrieved later.
+func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	var req requests.APIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	user, err := c.authService.FindUser(ctx, ctx.GetUint("userID"))
+	if err != nil {
+		c.sendError(ctx, "Failed to load user", err)
+		return
+	}
+
+	key, err := c.apiKeyService.Create(ctx, user, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		c.sendError(ctx, "Failed to create API key", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "API key created; copy it now, it won't be shown again", key, nil)
+}
+
+func (c *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid API key ID", err)
+		return
+	}
+
+	if err := c.apiKeyService.Revoke(ctx, ctx.GetUint("userID"), uint(id)); err != nil {
+		c.sendError(ctx, "Failed to revoke API key", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "API key revoked successfully", nil, nil)
+}
+
+func (c *APIKeyController) sendError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, utils.ErrAPIKeyNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "API key not found", nil)
+	case errors.Is(err, utils.ErrUserNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "User not found", nil)
+	case errors.Is(err, utils.ErrInvalidScope), errors.Is(err, utils.ErrInvalidExpiry):
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
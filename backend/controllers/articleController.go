@@ -44,10 +44,15 @@ func (ctrl *ArticleController) CreateArticle(c *gin.Context) {
         ImageUrl: req.ImageUrl,
     }
 
-    if err := ctrl.service.CreatePost(c, article); err != nil {
+    postResp, err := ctrl.service.CreatePost(c, article, req.CategoryIDs, req.Tags)
+    if err != nil {
+        if isTaxonomyInputError(err) {
+            utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
+            return
+        }
         utils.SendError(c, http.StatusInternalServerError, "Failed to create article", err)
         return
     }
 
-    utils.SendSuccess(c, "Article created successfully", article, nil)
+    utils.SendSuccess(c, "Article created successfully", postResp, nil)
 }
\ No newline at end of file
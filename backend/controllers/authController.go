@@ -1,6 +1,12 @@
 package controllers
 
 import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/alimosavifard/zyros-backend/models"
 	"github.com/alimosavifard/zyros-backend/requests"
 	"github.com/alimosavifard/zyros-backend/services"
@@ -10,11 +16,12 @@ import (
 )
 
 type AuthController struct {
-	authService *services.AuthService
+	authService    *services.AuthService
+	accountService *services.AccountService
 }
 
-func NewAuthController(authService *services.AuthService) *AuthController {
-	return &AuthController{authService: authService}
+func NewAuthController(authService *services.AuthService, accountService *services.AccountService) *AuthController {
+	return &AuthController{authService: authService, accountService: accountService}
 }
 
 func (c *AuthController) Register(ctx *gin.Context) {
@@ -33,15 +40,26 @@ func (c *AuthController) Register(ctx *gin.Context) {
 		Username: req.Username,
 		Password: req.Password,
 	}
+	if req.Email != "" {
+		email := services.NormalizeEmail(req.Email)
+		user.Email = &email
+	}
 
-	token, err := c.authService.Register(user)
+	tokens, err := c.authService.Register(ctx, user, clientInfo(ctx))
 	if err != nil {
-		utils.SendError(ctx, http.StatusBadRequest, "Failed to register", err)
+		// Saying which of username or email is taken would tell anyone whose email has an account
+		if errors.Is(err, utils.ErrAccountExists) {
+			utils.SendError(ctx, http.StatusBadRequest, "Failed to register", nil)
+			return
+		}
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to register", err)
 		return
 	}
+	if err := c.accountService.SendEmailVerification(ctx, user); err != nil {
+		utils.InitLogger().Error().Err(err).Uint("userID", user.ID).Msg("Failed to start email verification")
+	}
 
-	// Set JWT token as an HTTP-only cookie
-	ctx.SetCookie("token", token, 3600*24, "/", "", true, true)
+	setAuthCookies(ctx, tokens)
 	utils.SendSuccess(ctx, "Registration successful", nil, nil)
 }
 
@@ -57,23 +75,145 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
-	token, err := c.authService.Login(&req)
+	result, err := c.authService.Login(ctx, req.Username, req.Password, clientInfo(ctx))
 	if err != nil {
 		if err.Error() == utils.ErrInvalidCredentials.Error() {
 			utils.SendError(ctx, http.StatusUnauthorized, "Invalid username or password", nil)
 			return
 		}
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			utils.SendError(ctx, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+			return
+		}
+		if errors.Is(err, utils.ErrAccountSuspended) {
+			utils.SendError(ctx, http.StatusForbidden, err.Error(), nil)
+			return
+		}
 		utils.SendError(ctx, http.StatusInternalServerError, "Failed to login", err)
 		return
 	}
 
-	// Set JWT token as an HTTP-only cookie
-	ctx.SetCookie("token", token, 3600*24, "/", "", true, true)
+	// No cookies yet: the client answers the challenge at /auth/mfa/verify, or enrolls
+	// at /auth/mfa/enroll first
+	if result.Challenge != nil {
+		utils.SendSuccess(ctx, "Two-factor authentication required", result.Challenge, nil)
+		return
+	}
+
+	setAuthCookies(ctx, result.Tokens)
 	utils.SendSuccess(ctx, "Login successful", nil, nil)
 }
 
+// Refresh rotates the refresh token from the cookie (or the request body, for clients
+// without cookies) and issues a new access token.
+func (c *AuthController) Refresh(ctx *gin.Context) {
+	var req requests.RefreshRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+			return
+		}
+	}
+	refreshToken := req.RefreshToken
+	if refreshToken == "" {
+		refreshToken, _ = ctx.Cookie(refreshCookie)
+	}
+	if refreshToken == "" {
+		utils.SendError(ctx, http.StatusUnauthorized, "Refresh token required", nil)
+		return
+	}
+
+	tokens, err := c.authService.Refresh(ctx, refreshToken, clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidRefreshToken) || errors.Is(err, utils.ErrRefreshTokenReused) {
+			clearAuthCookies(ctx)
+			utils.SendError(ctx, http.StatusUnauthorized, err.Error(), nil)
+			return
+		}
+		if errors.Is(err, utils.ErrAccountSuspended) {
+			clearAuthCookies(ctx)
+			utils.SendError(ctx, http.StatusForbidden, err.Error(), nil)
+			return
+		}
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	setAuthCookies(ctx, tokens)
+	utils.SendSuccess(ctx, "Token refreshed successfully", nil, nil)
+}
+
+// Logout revokes the caller's session. It succeeds even when the tokens are already
+// invalid, so clients can always use it to clear their cookies.
+func (c *AuthController) Logout(ctx *gin.Context) {
+	var req requests.RefreshRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+			return
+		}
+	}
+	refreshToken := req.RefreshToken
+	if refreshToken == "" {
+		refreshToken, _ = ctx.Cookie(refreshCookie)
+	}
+	accessToken := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if accessToken == "" {
+		accessToken, _ = ctx.Cookie(accessCookie)
+	}
+
+	if err := c.authService.Logout(ctx, accessToken, refreshToken); err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to log out", err)
+		return
+	}
+
+	clearAuthCookies(ctx)
+	utils.SendSuccess(ctx, "Logout successful", nil, nil)
+}
+
+// JWKS publishes the public keys access tokens are signed with, for other services
+// to verify them. Verifiers should refetch when they meet an unknown kid.
+func (c *AuthController) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.authService.JWKS())
+}
+
 func (c *AuthController) GetCSRFToken(ctx *gin.Context) {
 	// The CSRF token is already set in an HTTP-only cookie by the CSRF middleware.
 	// You can just send a success response.
 	utils.SendSuccess(ctx, "CSRF token is set in cookie", nil, nil)
-}
\ No newline at end of file
+}
+
+const (
+	accessCookie  = "token"
+	refreshCookie = "refresh_token"
+	// The refresh token is only ever sent to the refresh and logout endpoints
+	refreshCookiePath = "/api/v1/auth"
+)
+
+// setAuthCookies stores both tokens in HTTP-only cookies that expire with them.
+// They are SameSite=Strict: refresh and logout are authenticated by the refresh
+// cookie alone, outside the CSRF check, so other sites must not be able to send it.
+func setAuthCookies(ctx *gin.Context, tokens *services.TokenPair) {
+	ctx.SetSameSite(http.SameSiteStrictMode)
+	ctx.SetCookie(accessCookie, tokens.AccessToken, int(time.Until(tokens.AccessExpiresAt).Seconds()), "/", "", true, true)
+	ctx.SetCookie(refreshCookie, tokens.RefreshToken, int(time.Until(tokens.RefreshExpiresAt).Seconds()), refreshCookiePath, "", true, true)
+}
+
+// clientInfo describes the requesting device; the IP is resolved the same way
+// RateLimitMiddleware does it.
+func clientInfo(ctx *gin.Context) services.ClientInfo {
+	userAgent := ctx.Request.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	return services.ClientInfo{UserAgent: userAgent, IPAddress: ctx.ClientIP()}
+}
+
+func clearAuthCookies(ctx *gin.Context) {
+	ctx.SetSameSite(http.SameSiteStrictMode)
+	ctx.SetCookie(accessCookie, "", -1, "/", "", true, true)
+	ctx.SetCookie(refreshCookie, "", -1, refreshCookiePath, "", true, true)
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type CommentController struct {
+	commentService *services.CommentService
+	authService    *services.AuthService
+}
+
+func NewCommentController(commentService *services.CommentService, authService *services.AuthService) *CommentController {
+	return &CommentController{commentService: commentService, authService: authService}
+}
+
+func (c *CommentController) GetComments(ctx *gin.Context) {
+	postID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+	page, limit := paginationParams(ctx)
+
+	viewer, ok := loadViewer(ctx, c.authService)
+	if !ok {
+		return
+	}
+
+	comments, total, err := c.commentService.GetComments(ctx, uint(postID), page, limit, viewer)
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve comments", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Comments retrieved successfully", gin.H{"comments": comments},
+		gin.H{"page": page, "limit": limit, "total": total})
+}
+
+func (c *CommentController) CreateComment(ctx *gin.Context) {
+	postID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	var req requests.CommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	actor, ok := loadActor(ctx, c.authService)
+	if !ok {
+		return
+	}
+
+	comment, err := c.commentService.CreateComment(ctx, uint(postID), actor, req.Body, req.ParentID)
+	if err != nil {
+		c.sendError(ctx, "Failed to create comment", err)
+		return
+	}
+
+	message := "Comment submitted for moderation"
+	if comment.Status == models.CommentStatusApproved {
+		message = "Comment created successfully"
+	}
+	utils.SendSuccess(ctx, message, comment, nil)
+}
+
+func (c *CommentController) UpdateComment(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid comment ID", err)
+		return
+	}
+
+	var req requests.UpdateCommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	actor, ok := loadActor(ctx, c.authService)
+	if !ok {
+		return
+	}
+
+	comment, err := c.commentService.UpdateComment(ctx, uint(id), actor, req.Body)
+	if err != nil {
+		c.sendError(ctx, "Failed to update comment", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Comment updated successfully", comment, nil)
+}
+
+func (c *CommentController) DeleteComment(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid comment ID", err)
+		return
+	}
+
+	actor, ok := loadActor(ctx, c.authService)
+	if !ok {
+		return
+	}
+
+	if err := c.commentService.DeleteComment(ctx, uint(id), actor); err != nil {
+		c.sendError(ctx, "Failed to delete comment", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Comment deleted successfully", nil, nil)
+}
+
+func (c *CommentController) GetModerationQueue(ctx *gin.Context) {
+	status := ctx.DefaultQuery("status", models.CommentStatusPending)
+	switch status {
+	case models.CommentStatusPending, models.CommentStatusApproved, models.CommentStatusSpam, models.CommentStatusRejected:
+	default:
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid status", nil)
+		return
+	}
+	page, limit := paginationParams(ctx)
+
+	actor, ok := loadActor(ctx, c.authService)
+	if !ok {
+		return
+	}
+
+	comments, total, err := c.commentService.GetModerationQueue(ctx, status, page, limit, actor)
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve moderation queue", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Moderation queue retrieved successfully", gin.H{"comments": comments},
+		gin.H{"page": page, "limit": limit, "total": total})
+}
+
+func (c *CommentController) ModerateComment(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid comment ID", err)
+		return
+	}
+
+	var req requests.ModerateCommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	actor, ok := loadActor(ctx, c.authService)
+	if !ok {
+		return
+	}
+
+	comment, err := c.commentService.ModerateComment(ctx, uint(id), actor, req.Status, req.Note)
+	if err != nil {
+		c.sendError(ctx, "Failed to moderate comment", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Comment moderated successfully", comment, nil)
+}
+
+func (c *CommentController) sendError(ctx *gin.Context, message string, err error) {
+	var denied *policy.DenyError
+	switch {
+	case errors.Is(err, utils.ErrPostNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Post not found", nil)
+	case errors.Is(err, utils.ErrCommentNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Comment not found", nil)
+	case errors.As(err, &denied):
+		utils.SendErrorWithMeta(ctx, http.StatusForbidden, "Forbidden", denied.Decision)
+	case errors.Is(err, utils.ErrCommentsClosed):
+		utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, utils.ErrInvalidCommentParent), errors.Is(err, utils.ErrEmptyComment):
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
@@ -4,12 +4,11 @@ import (
     "github.com/alimosavifard/zyros-backend/config"
     "github.com/alimosavifard/zyros-backend/utils"
     "github.com/gin-gonic/gin"
-    "net/http"
 )
 
 func HealthCheck(ctx *gin.Context) {
     dbStatus := "up"
-    if db, err := config.ConnectDB().DB(); err != nil {
+    if db, err := config.DB.DB(); err != nil {
         dbStatus = "down"
     } else {
         if err := db.Ping(); err != nil {
@@ -18,7 +17,7 @@ func HealthCheck(ctx *gin.Context) {
     }
 
     redisStatus := "up"
-    if err := config.ConnectRedis().Ping(ctx).Err(); err != nil {
+    if err := config.RedisClient.Ping(ctx).Err(); err != nil {
         redisStatus = "down"
     }
 
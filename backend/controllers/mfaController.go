@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// MFAController handles the second step of login and lets users manage their
+// authenticator and recovery codes.
+type MFAController struct {
+	authService *services.AuthService
+	mfaService  *services.MFAService
+}
+
+func NewMFAController(authService *services.AuthService, mfaService *services.MFAService) *MFAController {
+	return &MFAController{authService: authService, mfaService: mfaService}
+}
+
+// Verify answers a login challenge and sets the session cookies.
+func (c *MFAController) Verify(ctx *gin.Context) {
+	var req requests.MFAChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	tokens, err := c.authService.VerifyMFA(ctx, req.MFAToken, req.Code, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to verify code", err)
+		return
+	}
+
+	setAuthCookies(ctx, tokens)
+	utils.SendSuccess(ctx, "Login successful", nil, nil)
+}
+
+// BeginEnrollment returns a new secret for an account that has to enroll before its
+// login can finish.
+func (c *MFAController) BeginEnrollment(ctx *gin.Context) {
+	var req requests.MFAEnrollRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	setup, err := c.authService.BeginMFAEnrollment(ctx, req.MFAToken)
+	if err != nil {
+		c.sendError(ctx, "Failed to start enrollment", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Scan the code with an authenticator app", setup, nil)
+}
+
+// ConfirmEnrollment finishes enrollment during login, sets the session cookies and
+// returns the recovery codes.
+func (c *MFAController) ConfirmEnrollment(ctx *gin.Context) {
+	var req requests.MFAChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	tokens, codes, err := c.authService.CompleteMFAEnrollment(ctx, req.MFAToken, req.Code, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to enable two-factor authentication", err)
+		return
+	}
+
+	setAuthCookies(ctx, tokens)
+	utils.SendSuccess(ctx, "Two-factor authentication enabled", gin.H{"recovery_codes": codes}, nil)
+}
+
+func (c *MFAController) GetStatus(ctx *gin.Context) {
+	user, err := c.authService.FindUser(ctx, ctx.GetUint("userID"))
+	if err != nil {
+		c.sendError(ctx, "Failed to load user", err)
+		return
+	}
+
+	status, err := c.mfaService.Status(ctx, user)
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve two-factor status", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Two-factor status retrieved successfully", status, nil)
+}
+
+func (c *MFAController) Setup(ctx *gin.Context) {
+	user, err := c.authService.FindUser(ctx, ctx.GetUint("userID"))
+	if err != nil {
+		c.sendError(ctx, "Failed to load user", err)
+		return
+	}
+
+	setup, err := c.mfaService.Setup(ctx, user)
+	if err != nil {
+		c.sendError(ctx, "Failed to start enrollment", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Scan the code with an authenticator app", setup, nil)
+}
+
+func (c *MFAController) Enable(ctx *gin.Context) {
+	req, ok := bindMFACode(ctx)
+	if !ok {
+		return
+	}
+
+	codes, err := c.mfaService.Enable(ctx, ctx.GetUint("userID"), req.Code)
+	if err != nil {
+		c.sendError(ctx, "Failed to enable two-factor authentication", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Two-factor authentication enabled", gin.H{"recovery_codes": codes}, nil)
+}
+
+func (c *MFAController) Disable(ctx *gin.Context) {
+	req, ok := bindMFACode(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := c.authService.FindUser(ctx, ctx.GetUint("userID"))
+	if err != nil {
+		c.sendError(ctx, "Failed to load user", err)
+		return
+	}
+
+	if err := c.mfaService.Disable(ctx, user, req.Code); err != nil {
+		c.sendError(ctx, "Failed to disable two-factor authentication", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Two-factor authentication disabled", nil, nil)
+}
+
+func (c *MFAController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	req, ok := bindMFACode(ctx)
+	if !ok {
+		return
+	}
+
+	codes, err := c.mfaService.RegenerateRecoveryCodes(ctx, ctx.GetUint("userID"), req.Code)
+	if err != nil {
+		c.sendError(ctx, "Failed to regenerate recovery codes", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Recovery codes regenerated", gin.H{"recovery_codes": codes}, nil)
+}
+
+// bindMFACode reads and validates the code in the body. It writes the error response
+// itself and returns ok=false on failure.
+func bindMFACode(ctx *gin.Context) (*requests.MFACodeRequest, bool) {
+	var req requests.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return nil, false
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return nil, false
+	}
+	return &req, true
+}
+
+func (c *MFAController) sendError(ctx *gin.Context, message string, err error) {
+	var locked *services.LoginLockedError
+	switch {
+	case errors.Is(err, utils.ErrInvalidMFAChallenge), errors.Is(err, utils.ErrInvalidMFACode):
+		utils.SendError(ctx, http.StatusUnauthorized, err.Error(), nil)
+	case errors.Is(err, utils.ErrMFAAlreadyEnabled), errors.Is(err, utils.ErrMFANotEnabled):
+		utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, utils.ErrMFARequired), errors.Is(err, utils.ErrAccountSuspended):
+		utils.SendError(ctx, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, utils.ErrUserNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "User not found", nil)
+	case errors.As(err, &locked):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		utils.SendError(ctx, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
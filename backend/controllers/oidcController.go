@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	// The state cookie only goes back to the callback
+	oidcCookiePath = "/api/v1/auth/oidc"
+)
+
+// OIDCController runs browser logins with external identity providers. Both
+// endpoints are navigated to rather than fetched, so they answer with redirects:
+// to the provider, and back to the frontend at appURL.
+type OIDCController struct {
+	oidcService *services.OIDCService
+	appURL      string
+}
+
+func NewOIDCController(oidcService *services.OIDCService, appURL string) *OIDCController {
+	return &OIDCController{oidcService: oidcService, appURL: strings.TrimSuffix(appURL, "/")}
+}
+
+// GetProviders lists the providers a login page can offer.
+func (c *OIDCController) GetProviders(ctx *gin.Context) {
+	utils.SendSuccess(ctx, "Identity providers retrieved successfully", c.oidcService.Providers(), nil)
+}
+
+// Login sends the browser to the provider. The state is also kept in a cookie, so
+// the callback only completes a login this browser started.
+func (c *OIDCController) Login(ctx *gin.Context) {
+	authURL, state, err := c.oidcService.Begin(ctx, ctx.Param("provider"))
+	if err != nil {
+		if errors.Is(err, utils.ErrUnknownProvider) {
+			utils.SendError(ctx, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		utils.SendError(ctx, http.StatusBadGateway, "Failed to reach identity provider", err)
+		return
+	}
+
+	// Lax, because the provider's redirect back is a cross-site navigation
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, state, 600, oidcCookiePath, "", true, true)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// Callback completes the login the provider redirects back with. Failures go to the
+// frontend's login page with an error code; a second-factor challenge goes to its
+// MFA page with the token in the fragment, which browsers don't send on.
+func (c *OIDCController) Callback(ctx *gin.Context) {
+	cookieState, _ := ctx.Cookie(oidcStateCookie)
+	ctx.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", true, true)
+
+	if reason := ctx.Query("error"); reason != "" {
+		utils.InitLogger().Warn().Str("provider", ctx.Param("provider")).Str("error", reason).Msg("Identity provider refused login")
+		c.redirect(ctx, "/login", url.Values{"error": {"oidc_denied"}})
+		return
+	}
+	state := ctx.Query("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		c.redirect(ctx, "/login", url.Values{"error": {"oidc_state"}})
+		return
+	}
+
+	result, err := c.oidcService.Complete(ctx, ctx.Param("provider"), state, ctx.Query("code"), clientInfo(ctx))
+	if errors.Is(err, utils.ErrAccountSuspended) {
+		c.redirect(ctx, "/login", url.Values{"error": {"suspended"}})
+		return
+	}
+	if err != nil {
+		utils.InitLogger().Error().Err(err).Str("provider", ctx.Param("provider")).Msg("External login failed")
+		c.redirect(ctx, "/login", url.Values{"error": {"oidc_failed"}})
+		return
+	}
+
+	if result.Challenge != nil {
+		fragment := url.Values{
+			"mfa_token":           {result.Challenge.Token},
+			"enrollment_required": {strconv.FormatBool(result.Challenge.EnrollmentRequired)},
+		}
+		ctx.Redirect(http.StatusFound, c.appURL+"/login/mfa#"+fragment.Encode())
+		return
+	}
+
+	setAuthCookies(ctx, result.Tokens)
+	c.redirect(ctx, "/", nil)
+}
+
+func (c *OIDCController) redirect(ctx *gin.Context, path string, query url.Values) {
+	target := c.appURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	ctx.Redirect(http.StatusFound, target)
+}
@@ -1,26 +1,34 @@
 package controllers
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+	"github.com/alimosavifard/zyros-backend/repositories"
 	"github.com/alimosavifard/zyros-backend/requests"
 	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/storage"
 	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/alimosavifard/zyros-backend/workflow"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type PostController struct {
 	postService *services.PostService
+	authService *services.AuthService
+	storage     storage.Storage
+	auditor     services.Auditor
 }
 
-func NewPostController(postService *services.PostService) *PostController {
-	return &PostController{postService: postService}
+func NewPostController(postService *services.PostService, authService *services.AuthService, store storage.Storage, auditor services.Auditor) *PostController {
+	return &PostController{postService: postService, authService: authService, storage: store, auditor: auditor}
 }
 
 func (c *PostController) CreatePost(ctx *gin.Context) {
@@ -46,22 +54,47 @@ func (c *PostController) CreatePost(ctx *gin.Context) {
 		Content:  req.Content,
 		Type:     req.Type,
 		Lang:     req.Lang,
+		Slug:     req.Slug,
 		UserID:   userID.(uint),
 		ImageUrl: req.ImageUrl,
 	}
 
-	if err := c.postService.CreatePost(ctx, post); err != nil {
+	postResp, err := c.postService.CreatePost(ctx, post, req.CategoryIDs, req.Tags)
+	if err != nil {
+		if errors.Is(err, utils.ErrSlugTaken) {
+			utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+			return
+		}
+		if isTaxonomyInputError(err) {
+			utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
 		utils.SendError(ctx, http.StatusInternalServerError, "Failed to create post", err)
 		return
 	}
 
-	utils.SendSuccess(ctx, "Post created successfully", post, nil)
+	utils.SendSuccess(ctx, "Post created successfully", postResp, nil)
 }
 
 func (c *PostController) GetPosts(ctx *gin.Context) {
-	 log.Println("Handling /api/v1/posts request...")
-    // ...
-    postResponses, err := c.postService.GetPosts(ctx, lang, postType, page, limit, userID)
+	lang := ctx.DefaultQuery("lang", "fa")
+	postType := ctx.DefaultQuery("type", "post")
+	status := ctx.Query("status")
+	page, limit := paginationParams(ctx)
+	filter := repositories.PostFilter{
+		Lang:     lang,
+		Type:     postType,
+		Status:   status,
+		Category: ctx.Query("category"),
+		Tag:      ctx.Query("tag"),
+	}
+
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+
+    postResponses, err := c.postService.GetPosts(ctx, filter, page, limit, viewer)
     if err != nil {
         utils.InitLogger().Error().Err(err).Msg("Failed to retrieve posts") // log خطا
         utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve posts", err)
@@ -79,13 +112,12 @@ func (c *PostController) GetPostByID(ctx *gin.Context) {
 		return
 	}
 
-	userIDInterface, _ := ctx.Get("userID")
-	var userID uint
-	if userIDInterface != nil {
-		userID = userIDInterface.(uint)
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
 	}
 
-	postResp, err := c.postService.GetPostByID(ctx, uint(id), userID)
+	postResp, err := c.postService.GetPostByID(ctx, uint(id), viewer)
 	if err != nil {
 		if err.Error() == "record not found" {
 			utils.SendError(ctx, http.StatusNotFound, "Post not found", err)
@@ -95,6 +127,45 @@ func (c *PostController) GetPostByID(ctx *gin.Context) {
 		return
 	}
 
+	sendPost(ctx, postResp)
+}
+
+func (c *PostController) GetPostBySlug(ctx *gin.Context) {
+	lang := ctx.Param("lang")
+	if lang != "fa" && lang != "en" {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid language", nil)
+		return
+	}
+
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+
+	postResp, moved, err := c.postService.GetPostBySlug(ctx, lang, ctx.Param("slug"), viewer)
+	if err != nil {
+		c.sendLifecycleError(ctx, "Failed to get post", err)
+		return
+	}
+	if moved != nil {
+		ctx.Redirect(http.StatusMovedPermanently, "/api/v1/posts/by-slug/"+moved.Lang+"/"+url.PathEscape(moved.Slug))
+		return
+	}
+
+	sendPost(ctx, postResp)
+}
+
+// sendPost writes a single post with its version as the ETag, the same tag writes
+// return, so readers can revalidate and editors can send it back in If-Match.
+func sendPost(ctx *gin.Context, postResp *services.PostResponse) {
+	etag := postETag(postResp.Version)
+	ctx.Header("ETag", etag)
+	ctx.Header("Vary", "Authorization")
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
 	utils.SendSuccess(ctx, "Post retrieved successfully", postResp, nil)
 }
 
@@ -106,9 +177,10 @@ func (c *PostController) UploadImage(ctx *gin.Context) {
         return
     }
 
-    allowedMIMETypes := map[string]bool{
-        "image/jpeg": true,
-        "image/png":  true,
+    // Stored files are named by their detected type, never by the client's filename
+    extensions := map[string]string{
+        "image/jpeg": ".jpg",
+        "image/png":  ".png",
     }
 
     fileHeader, err := file.Open()
@@ -126,24 +198,475 @@ func (c *PostController) UploadImage(ctx *gin.Context) {
     }
 
     contentType := http.DetectContentType(buffer)
-    if !allowedMIMETypes[contentType] {
+    ext, ok := extensions[contentType]
+    if !ok {
         utils.SendError(ctx, http.StatusBadRequest, "Invalid file content. File is not a valid image format", nil)
         return
     }
-
-    uploadDir := "./uploads"
-    if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-        utils.SendError(ctx, http.StatusInternalServerError, "Failed to create upload directory", err)
+    if _, err := fileHeader.Seek(0, io.SeekStart); err != nil {
+        utils.SendError(ctx, http.StatusInternalServerError, "Failed to read file", err)
         return
     }
 
-    filename := fmt.Sprintf("%d-%s", time.Now().UnixNano(), file.Filename)
-    filepath := filepath.Join(uploadDir, filename)
-    
-    if err := ctx.SaveUploadedFile(file, filepath); err != nil {
-        utils.SendError(ctx, http.StatusInternalServerError, fmt.Sprintf("Failed to save image to %s", filepath), err)
+    name, err := utils.RandomToken(16)
+    if err != nil {
+        utils.SendError(ctx, http.StatusInternalServerError, "Failed to save image", err)
+        return
+    }
+    key := fmt.Sprintf("images/%s/%s%s", time.Now().UTC().Format("2006/01"), name, ext)
+    if err := c.storage.Put(ctx, key, fileHeader, file.Size, contentType); err != nil {
+        utils.SendError(ctx, http.StatusInternalServerError, "Failed to save image", err)
         return
     }
 
-    utils.SendSuccess(ctx, "Image uploaded successfully", gin.H{"url": "/uploads/" + filename}, nil)
-}
\ No newline at end of file
+    imageURL := c.storage.URL(key)
+    c.auditor.Record(ctx, services.AuditEvent{
+        Action:     "upload.create",
+        ActorID:    ctx.GetUint("userID"),
+        TargetType: "upload",
+        Details: map[string]interface{}{
+            "key":          key,
+            "url":          imageURL,
+            "filename":     file.Filename,
+            "content_type": contentType,
+            "size":         file.Size,
+        },
+    })
+
+    utils.SendSuccess(ctx, "Image uploaded successfully", gin.H{"url": imageURL}, nil)
+}
+
+func (c *PostController) UpdatePost(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	var req requests.UpdatePostRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	if ctx.Request.Method == http.MethodPut && !req.IsComplete() {
+		utils.SendError(ctx, http.StatusBadRequest, "PUT requires title, content, type and lang", nil)
+		return
+	}
+
+	ifMatch := ctx.GetHeader("If-Match")
+	if ifMatch == "" {
+		utils.SendError(ctx, http.StatusPreconditionRequired, "If-Match header with the post's ETag is required", nil)
+		return
+	}
+	if isWeakETag(ifMatch) {
+		utils.SendError(ctx, http.StatusPreconditionFailed, "If-Match needs a strong ETag", nil)
+		return
+	}
+	version, ok := parseETag(ifMatch)
+	if !ok {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid If-Match header", nil)
+		return
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	postResp, err := c.postService.UpdatePost(ctx, uint(id), actor, &req, version)
+	if err != nil {
+		c.sendLifecycleError(ctx, "Failed to update post", err)
+		return
+	}
+
+	ctx.Header("ETag", postETag(postResp.Version))
+	utils.SendSuccess(ctx, "Post updated successfully", postResp, nil)
+}
+
+func (c *PostController) DeletePost(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.postService.DeletePost(ctx, uint(id), actor); err != nil {
+		c.sendLifecycleError(ctx, "Failed to delete post", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Post moved to trash", nil, nil)
+}
+
+func (c *PostController) RestorePost(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.postService.RestorePost(ctx, uint(id), actor); err != nil {
+		c.sendLifecycleError(ctx, "Failed to restore post", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Post restored successfully", nil, nil)
+}
+
+func (c *PostController) PurgePost(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.postService.PurgePost(ctx, uint(id), actor); err != nil {
+		c.sendLifecycleError(ctx, "Failed to purge post", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Post permanently deleted", nil, nil)
+}
+
+func (c *PostController) TransitionPost(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	var req requests.TransitionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	postResp, err := c.postService.TransitionPost(ctx, uint(id), actor, services.TransitionInput{
+		Transition:  workflow.Transition(req.Transition),
+		Note:        req.Note,
+		PublishAt:   req.PublishAt,
+		UnpublishAt: req.UnpublishAt,
+	})
+	if err != nil {
+		c.sendLifecycleError(ctx, "Failed to change post status", err)
+		return
+	}
+
+	ctx.Header("ETag", postETag(postResp.Version))
+	utils.SendSuccess(ctx, "Post status changed successfully", postResp, nil)
+}
+
+func (c *PostController) GetTransitions(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	transitions, err := c.postService.GetPostTransitions(ctx, uint(id), actor)
+	if err != nil {
+		c.sendLifecycleError(ctx, "Failed to retrieve post history", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Post history retrieved successfully", gin.H{"transitions": transitions}, nil)
+}
+
+func (c *PostController) GetRevisions(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	revisions, err := c.postService.GetPostRevisions(ctx, uint(id), actor)
+	if err != nil {
+		c.sendLifecycleError(ctx, "Failed to retrieve post revisions", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Post revisions retrieved successfully", gin.H{"revisions": revisions}, nil)
+}
+
+func (c *PostController) DiffRevision(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	rev, err := strconv.Atoi(ctx.Param("rev"))
+	if err != nil || rev < 1 {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid revision", err)
+		return
+	}
+
+	against := 0
+	if value := ctx.Query("against"); value != "" {
+		if against, err = strconv.Atoi(value); err != nil || against < 1 {
+			utils.SendError(ctx, http.StatusBadRequest, "Invalid revision to compare against", err)
+			return
+		}
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	diff, err := c.postService.DiffPostRevisions(ctx, uint(id), rev, against, actor)
+	if err != nil {
+		c.sendLifecycleError(ctx, "Failed to compare post revisions", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Post revision diff retrieved successfully", diff, nil)
+}
+
+func (c *PostController) RestoreRevision(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	rev, err := strconv.Atoi(ctx.Param("rev"))
+	if err != nil || rev < 1 {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid revision", err)
+		return
+	}
+
+	// If-Match is optional here; without it the restore applies to whatever version is current
+	var version uint
+	if ifMatch := ctx.GetHeader("If-Match"); ifMatch != "" {
+		if isWeakETag(ifMatch) {
+			utils.SendError(ctx, http.StatusPreconditionFailed, "If-Match needs a strong ETag", nil)
+			return
+		}
+		var ok bool
+		if version, ok = parseETag(ifMatch); !ok {
+			utils.SendError(ctx, http.StatusBadRequest, "Invalid If-Match header", nil)
+			return
+		}
+	}
+
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+
+	postResp, err := c.postService.RestorePostRevision(ctx, uint(id), rev, actor, version)
+	if err != nil {
+		c.sendLifecycleError(ctx, "Failed to restore post revision", err)
+		return
+	}
+
+	ctx.Header("ETag", postETag(postResp.Version))
+	utils.SendSuccess(ctx, "Post revision restored successfully", postResp, nil)
+}
+
+func (c *PostController) Search(ctx *gin.Context) {
+	q := strings.TrimSpace(ctx.Query("q"))
+	if q == "" || len([]rune(q)) > 200 {
+		utils.SendError(ctx, http.StatusBadRequest, "Search query must be between 1 and 200 characters", nil)
+		return
+	}
+	lang := ctx.Query("lang")
+	if lang != "" && lang != "fa" && lang != "en" {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid language", nil)
+		return
+	}
+	postType := ctx.Query("type")
+	if postType != "" && postType != "post" && postType != "article" {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid type", nil)
+		return
+	}
+	page, limit := paginationParams(ctx)
+
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+
+	hits, total, err := c.postService.SearchPosts(ctx, repositories.SearchQuery{Text: q, Lang: lang, Type: postType}, page, limit, viewer)
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to search posts", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Search completed successfully", gin.H{"results": hits},
+		gin.H{"page": page, "limit": limit, "total": total})
+}
+
+func (c *PostController) GetTrash(ctx *gin.Context) {
+	actor, ok := c.actor(ctx)
+	if !ok {
+		return
+	}
+	page, limit := paginationParams(ctx)
+
+	posts, err := c.postService.GetTrashedPosts(ctx, actor, page, limit)
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve trash", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Trash retrieved successfully", gin.H{"posts": posts}, nil)
+}
+
+// actor loads the authenticated user as a policy subject.
+// It writes the error response itself and returns ok=false on failure.
+func (c *PostController) actor(ctx *gin.Context) (*policy.Subject, bool) {
+	return loadActor(ctx, c.authService)
+}
+
+// viewer loads the optional reader of a public route; it is nil for anonymous requests.
+// It writes the error response itself and returns ok=false on failure.
+func (c *PostController) viewer(ctx *gin.Context) (*policy.Subject, bool) {
+	return loadViewer(ctx, c.authService)
+}
+
+func loadActor(ctx *gin.Context, authService *services.AuthService) (*policy.Subject, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.SendError(ctx, http.StatusUnauthorized, "Unauthorized", nil)
+		return nil, false
+	}
+
+	subject, err := authService.Subject(ctx, userID.(uint))
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to load user permissions", err)
+		return nil, false
+	}
+	if scopes, ok := ctx.Get("scopes"); ok {
+		subject.Restrict(scopes.([]string))
+	}
+	return subject, true
+}
+
+func loadViewer(ctx *gin.Context, authService *services.AuthService) (*policy.Subject, bool) {
+	if _, exists := ctx.Get("userID"); !exists {
+		return nil, true
+	}
+	return loadActor(ctx, authService)
+}
+
+func (c *PostController) sendLifecycleError(ctx *gin.Context, message string, err error) {
+	var denied *policy.DenyError
+	var conflict *repositories.VersionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		ctx.Header("ETag", postETag(conflict.Current))
+		utils.SendErrorWithMeta(ctx, http.StatusPreconditionFailed, "Post was modified by someone else", gin.H{"current_version": conflict.Current})
+	case errors.Is(err, utils.ErrPostNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Post not found", nil)
+	case errors.Is(err, utils.ErrRevisionNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Revision not found", nil)
+	case errors.As(err, &denied):
+		utils.SendErrorWithMeta(ctx, http.StatusForbidden, "Forbidden", denied.Decision)
+	case errors.Is(err, workflow.ErrInvalidTransition):
+		utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, workflow.ErrInvalidSchedule):
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+	case isTaxonomyInputError(err):
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, utils.ErrSlugTaken):
+		utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
+
+// postETag formats a post version as a strong entity tag.
+func postETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// isWeakETag reports whether an If-Match header holds a weak entity tag, which
+// never matches there: If-Match compares strongly (RFC 9110, section 13.1.1).
+func isWeakETag(header string) bool {
+	return strings.HasPrefix(strings.TrimSpace(header), "W/")
+}
+
+// parseETag reads the post version out of a single strong If-Match entity tag.
+// "*" matches whatever version is current and yields 0.
+func parseETag(header string) (uint, bool) {
+	tag := strings.TrimSpace(header)
+	if tag == "*" {
+		return 0, true
+	}
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 32)
+	if err != nil || version == 0 {
+		return 0, false
+	}
+	return uint(version), true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing weakly.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// paginationParams reads page and limit from the query string, falling back to page 1 of 10.
+func paginationParams(ctx *gin.Context) (int, int) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+	return page, limit
+}
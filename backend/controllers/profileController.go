@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ProfileController serves public profiles and the signed-in user's account settings.
+type ProfileController struct {
+	profileService *services.ProfileService
+	authService    *services.AuthService
+}
+
+func NewProfileController(profileService *services.ProfileService, authService *services.AuthService) *ProfileController {
+	return &ProfileController{profileService: profileService, authService: authService}
+}
+
+func (c *ProfileController) GetMe(ctx *gin.Context) {
+	account, err := c.profileService.Me(ctx, ctx.GetUint("userID"))
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve account", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Account retrieved successfully", account, nil)
+}
+
+func (c *ProfileController) UpdateMe(ctx *gin.Context) {
+	var req requests.UpdateProfileRequest
+	if !bindRequest(ctx, &req) {
+		return
+	}
+
+	account, err := c.profileService.UpdateMe(ctx, ctx.GetUint("userID"), &req)
+	if err != nil {
+		c.sendError(ctx, "Failed to update profile", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Profile updated successfully", account, nil)
+}
+
+// ChangePassword signs out every other session; the one making the request stays.
+func (c *ProfileController) ChangePassword(ctx *gin.Context) {
+	var req requests.ChangePasswordRequest
+	if !bindRequest(ctx, &req) {
+		return
+	}
+
+	err := c.profileService.ChangePassword(ctx, ctx.GetUint("userID"), ctx.GetString("sessionID"), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		c.sendError(ctx, "Failed to change password", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Password changed successfully", nil, nil)
+}
+
+func (c *ProfileController) GetProfile(ctx *gin.Context) {
+	profile, err := c.profileService.PublicProfile(ctx, ctx.Param("username"))
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve profile", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Profile retrieved successfully", profile, nil)
+}
+
+// GetProfilePosts lists the author's published posts, filtered like GetPosts.
+func (c *ProfileController) GetProfilePosts(ctx *gin.Context) {
+	page, limit := paginationParams(ctx)
+	filter := repositories.PostFilter{
+		Lang:     ctx.DefaultQuery("lang", "fa"),
+		Type:     ctx.DefaultQuery("type", "post"),
+		Category: ctx.Query("category"),
+		Tag:      ctx.Query("tag"),
+	}
+
+	viewer, ok := loadViewer(ctx, c.authService)
+	if !ok {
+		return
+	}
+
+	posts, err := c.profileService.AuthorPosts(ctx, ctx.Param("username"), filter, page, limit, viewer)
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve posts", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Posts retrieved successfully", gin.H{"posts": posts}, nil)
+}
+
+func (c *ProfileController) sendError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, utils.ErrUserNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "User not found", nil)
+	case errors.Is(err, utils.ErrWrongPassword):
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RoleController is the admin API for roles, permissions and role assignments.
+type RoleController struct {
+	roleService *services.RoleService
+}
+
+func NewRoleController(roleService *services.RoleService) *RoleController {
+	return &RoleController{roleService: roleService}
+}
+
+func (c *RoleController) GetRoles(ctx *gin.Context) {
+	roles, err := c.roleService.ListRoles(ctx)
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve roles", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Roles retrieved successfully", roles, nil)
+}
+
+func (c *RoleController) GetRole(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid role ID")
+	if !ok {
+		return
+	}
+
+	role, err := c.roleService.GetRole(ctx, id)
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve role", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Role retrieved successfully", role, nil)
+}
+
+func (c *RoleController) CreateRole(ctx *gin.Context) {
+	var req requests.RoleRequest
+	if !bindRequest(ctx, &req) {
+		return
+	}
+
+	role, err := c.roleService.CreateRole(ctx, ctx.GetUint("userID"), req.Name, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to create role", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Role created successfully", role, nil)
+}
+
+func (c *RoleController) UpdateRole(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid role ID")
+	if !ok {
+		return
+	}
+	var req requests.RoleRequest
+	if !bindRequest(ctx, &req) {
+		return
+	}
+
+	role, err := c.roleService.RenameRole(ctx, ctx.GetUint("userID"), id, req.Name, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to update role", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Role updated successfully", role, nil)
+}
+
+func (c *RoleController) DeleteRole(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid role ID")
+	if !ok {
+		return
+	}
+
+	if err := c.roleService.DeleteRole(ctx, ctx.GetUint("userID"), id, clientInfo(ctx)); err != nil {
+		c.sendError(ctx, "Failed to delete role", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Role deleted successfully", nil, nil)
+}
+
+// AttachPermission grants the :permission named in the path to the role.
+func (c *RoleController) AttachPermission(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid role ID")
+	if !ok {
+		return
+	}
+
+	role, err := c.roleService.AttachPermission(ctx, ctx.GetUint("userID"), id, ctx.Param("permission"), clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to grant permission", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Permission granted successfully", role, nil)
+}
+
+func (c *RoleController) DetachPermission(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid role ID")
+	if !ok {
+		return
+	}
+
+	role, err := c.roleService.DetachPermission(ctx, ctx.GetUint("userID"), id, ctx.Param("permission"), clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to revoke permission", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Permission revoked successfully", role, nil)
+}
+
+func (c *RoleController) GetPermissions(ctx *gin.Context) {
+	permissions, err := c.roleService.ListPermissions(ctx)
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve permissions", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Permissions retrieved successfully", permissions, nil)
+}
+
+func (c *RoleController) CreatePermission(ctx *gin.Context) {
+	var req requests.PermissionRequest
+	if !bindRequest(ctx, &req) {
+		return
+	}
+
+	permission, err := c.roleService.CreatePermission(ctx, ctx.GetUint("userID"), req.Name, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to create permission", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Permission created successfully", permission, nil)
+}
+
+func (c *RoleController) UpdatePermission(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid permission ID")
+	if !ok {
+		return
+	}
+	var req requests.PermissionRequest
+	if !bindRequest(ctx, &req) {
+		return
+	}
+
+	permission, err := c.roleService.RenamePermission(ctx, ctx.GetUint("userID"), id, req.Name, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to update permission", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Permission updated successfully", permission, nil)
+}
+
+func (c *RoleController) DeletePermission(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid permission ID")
+	if !ok {
+		return
+	}
+
+	if err := c.roleService.DeletePermission(ctx, ctx.GetUint("userID"), id, clientInfo(ctx)); err != nil {
+		c.sendError(ctx, "Failed to delete permission", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Permission deleted successfully", nil, nil)
+}
+
+func (c *RoleController) GetUserRoles(ctx *gin.Context) {
+	userID, ok := parseID(ctx, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	roles, err := c.roleService.GetUserRoles(ctx, userID)
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve roles", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Roles retrieved successfully", roles, nil)
+}
+
+func (c *RoleController) AssignRole(ctx *gin.Context) {
+	userID, ok := parseID(ctx, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+	roleID, ok := parseID(ctx, "roleId", "Invalid role ID")
+	if !ok {
+		return
+	}
+
+	if err := c.roleService.AssignRole(ctx, ctx.GetUint("userID"), userID, roleID, clientInfo(ctx)); err != nil {
+		c.sendError(ctx, "Failed to assign role", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Role assigned successfully", nil, nil)
+}
+
+func (c *RoleController) RevokeRole(ctx *gin.Context) {
+	userID, ok := parseID(ctx, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+	roleID, ok := parseID(ctx, "roleId", "Invalid role ID")
+	if !ok {
+		return
+	}
+
+	if err := c.roleService.RevokeRole(ctx, ctx.GetUint("userID"), userID, roleID, clientInfo(ctx)); err != nil {
+		c.sendError(ctx, "Failed to revoke role", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Role revoked successfully", nil, nil)
+}
+
+// parseID reads a numeric path parameter. It writes the error response itself and
+// returns ok=false on failure.
+func parseID(ctx *gin.Context, param, message string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(param), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, message, err)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bindRequest reads and validates the body into req. It writes the error response
+// itself and returns ok=false on failure.
+func bindRequest(ctx *gin.Context, req requests.Validatable) bool {
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return false
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return false
+	}
+	return true
+}
+
+func (c *RoleController) sendError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, utils.ErrRoleNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Role not found", nil)
+	case errors.Is(err, utils.ErrPermissionNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Permission not found", nil)
+	case errors.Is(err, utils.ErrUserNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "User not found", nil)
+	case errors.Is(err, utils.ErrRoleExists), errors.Is(err, utils.ErrPermissionExists):
+		utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, utils.ErrProtectedRole), errors.Is(err, utils.ErrProtectedPermission), errors.Is(err, utils.ErrAdminLockout):
+		utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, utils.ErrInvalidName):
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionController lets users see and end their logins, and admins do the same for
+// any user and lift login lockouts.
+type SessionController struct {
+	authService *services.AuthService
+}
+
+func NewSessionController(authService *services.AuthService) *SessionController {
+	return &SessionController{authService: authService}
+}
+
+func (c *SessionController) GetMySessions(ctx *gin.Context) {
+	sessions, err := c.authService.GetSessions(ctx, ctx.GetUint("userID"), ctx.GetString("sessionID"))
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve sessions", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Sessions retrieved successfully", gin.H{"sessions": sessions}, nil)
+}
+
+func (c *SessionController) RevokeMySession(ctx *gin.Context) {
+	if err := c.authService.RevokeSession(ctx, ctx.GetUint("userID"), ctx.Param("id")); err != nil {
+		c.sendError(ctx, "Failed to revoke session", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Session revoked successfully", nil, nil)
+}
+
+// RevokeMyOtherSessions logs the user out everywhere except the current session.
+func (c *SessionController) RevokeMyOtherSessions(ctx *gin.Context) {
+	revoked, err := c.authService.RevokeOtherSessions(ctx, ctx.GetUint("userID"), ctx.GetString("sessionID"))
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Other sessions revoked successfully", gin.H{"revoked": revoked}, nil)
+}
+
+func (c *SessionController) GetUserSessions(ctx *gin.Context) {
+	userID, ok := c.targetUser(ctx)
+	if !ok {
+		return
+	}
+
+	sessions, err := c.authService.GetSessions(ctx, userID, "")
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve sessions", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Sessions retrieved successfully", gin.H{"sessions": sessions}, nil)
+}
+
+func (c *SessionController) RevokeUserSession(ctx *gin.Context) {
+	userID, ok := c.targetUser(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.authService.RevokeSession(ctx, userID, ctx.Param("sid")); err != nil {
+		c.sendError(ctx, "Failed to revoke session", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Session revoked successfully", nil, nil)
+}
+
+// RevokeUserSessions logs a user out of every session.
+func (c *SessionController) RevokeUserSessions(ctx *gin.Context) {
+	userID, ok := c.targetUser(ctx)
+	if !ok {
+		return
+	}
+
+	revoked, err := c.authService.RevokeOtherSessions(ctx, userID, "")
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Sessions revoked successfully", gin.H{"revoked": revoked}, nil)
+}
+
+// UnlockUser lets a locked-out user log in again before the lockout expires.
+func (c *SessionController) UnlockUser(ctx *gin.Context) {
+	userID, ok := c.targetUser(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.authService.UnlockLogin(ctx, ctx.GetUint("userID"), userID, clientInfo(ctx)); err != nil {
+		c.sendError(ctx, "Failed to unlock user", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "User unlocked successfully", nil, nil)
+}
+
+// targetUser reads the :id of an admin route and checks that the user exists.
+// It writes the error response itself and returns ok=false on failure.
+func (c *SessionController) targetUser(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid user ID", err)
+		return 0, false
+	}
+	if _, err := c.authService.FindUser(ctx, uint(id)); err != nil {
+		c.sendError(ctx, "Failed to load user", err)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (c *SessionController) sendError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, utils.ErrSessionNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Session not found", nil)
+	case errors.Is(err, utils.ErrUserNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "User not found", nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type TaxonomyController struct {
+	service *services.TaxonomyService
+}
+
+func NewTaxonomyController(service *services.TaxonomyService) *TaxonomyController {
+	return &TaxonomyController{service: service}
+}
+
+func (c *TaxonomyController) GetCategories(ctx *gin.Context) {
+	categories, err := c.service.GetCategoryTree(ctx, ctx.Query("lang"))
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve categories", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Categories retrieved successfully", gin.H{"categories": categories}, nil)
+}
+
+func (c *TaxonomyController) GetCategory(ctx *gin.Context) {
+	category, err := c.service.GetCategory(ctx, ctx.Param("slug"), ctx.Query("lang"))
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve category", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Category retrieved successfully", category, nil)
+}
+
+func (c *TaxonomyController) CreateCategory(ctx *gin.Context) {
+	var req requests.CategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	category, err := c.service.CreateCategory(ctx, &req)
+	if err != nil {
+		c.sendError(ctx, "Failed to create category", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Category created successfully", category, nil)
+}
+
+func (c *TaxonomyController) UpdateCategory(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid category ID", err)
+		return
+	}
+
+	var req requests.UpdateCategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	category, err := c.service.UpdateCategory(ctx, uint(id), &req)
+	if err != nil {
+		c.sendError(ctx, "Failed to update category", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Category updated successfully", category, nil)
+}
+
+func (c *TaxonomyController) DeleteCategory(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid category ID", err)
+		return
+	}
+
+	if err := c.service.DeleteCategory(ctx, uint(id)); err != nil {
+		c.sendError(ctx, "Failed to delete category", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Category deleted successfully", nil, nil)
+}
+
+func (c *TaxonomyController) GetTags(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	tags, err := c.service.GetTags(ctx, ctx.Query("q"), limit)
+	if err != nil {
+		utils.SendError(ctx, http.StatusInternalServerError, "Failed to retrieve tags", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Tags retrieved successfully", gin.H{"tags": tags}, nil)
+}
+
+func (c *TaxonomyController) CreateTag(ctx *gin.Context) {
+	var req requests.TagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	tag, err := c.service.CreateTag(ctx, &req)
+	if err != nil {
+		c.sendError(ctx, "Failed to create tag", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Tag created successfully", tag, nil)
+}
+
+func (c *TaxonomyController) UpdateTag(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid tag ID", err)
+		return
+	}
+
+	var req requests.TagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return
+	}
+
+	tag, err := c.service.UpdateTag(ctx, uint(id), &req)
+	if err != nil {
+		c.sendError(ctx, "Failed to update tag", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Tag updated successfully", tag, nil)
+}
+
+func (c *TaxonomyController) DeleteTag(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid tag ID", err)
+		return
+	}
+
+	if err := c.service.DeleteTag(ctx, uint(id)); err != nil {
+		c.sendError(ctx, "Failed to delete tag", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Tag deleted successfully", nil, nil)
+}
+
+func (c *TaxonomyController) sendError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, utils.ErrCategoryNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Category not found", nil)
+	case errors.Is(err, utils.ErrTagNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "Tag not found", nil)
+	case errors.Is(err, utils.ErrSlugTaken), errors.Is(err, utils.ErrCategoryHasChildren):
+		utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, utils.ErrInvalidSlug), errors.Is(err, utils.ErrInvalidCategoryParent):
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
+
+// isTaxonomyInputError reports whether err was caused by categories or tags given with a post.
+func isTaxonomyInputError(err error) bool {
+	return errors.Is(err, utils.ErrCategoryNotFound) || errors.Is(err, utils.ErrInvalidSlug)
+}
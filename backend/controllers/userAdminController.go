@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UserAdminController is the admin console for user accounts.
+type UserAdminController struct {
+	userAdminService *services.UserAdminService
+}
+
+func NewUserAdminController(userAdminService *services.UserAdminService) *UserAdminController {
+	return &UserAdminController{userAdminService: userAdminService}
+}
+
+// GetUsers lists users. It takes q to search, role, status (active, suspended or
+// deleted) and sort, e.g. "-created_at".
+func (c *UserAdminController) GetUsers(ctx *gin.Context) {
+	status := ctx.Query("status")
+	switch status {
+	case "", repositories.UserStatusActive, repositories.UserStatusSuspended, repositories.UserStatusDeleted:
+	default:
+		utils.SendError(ctx, http.StatusBadRequest, "Invalid status", nil)
+		return
+	}
+	page, limit := paginationParams(ctx)
+
+	users, total, err := c.userAdminService.ListUsers(ctx, services.UserListQuery{
+		Search: ctx.Query("q"),
+		Role:   ctx.Query("role"),
+		Status: status,
+		Sort:   ctx.Query("sort"),
+	}, page, limit)
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve users", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Users retrieved successfully", gin.H{"users": users},
+		gin.H{"page": page, "limit": limit, "total": total})
+}
+
+func (c *UserAdminController) GetUser(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	user, err := c.userAdminService.GetUser(ctx, id)
+	if err != nil {
+		c.sendError(ctx, "Failed to retrieve user", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "User retrieved successfully", user, nil)
+}
+
+func (c *UserAdminController) SuspendUser(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+	var req requests.SuspendUserRequest
+	if !bindRequest(ctx, &req) {
+		return
+	}
+
+	user, err := c.userAdminService.Suspend(ctx, ctx.GetUint("userID"), id, req.Reason, req.Until, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to suspend user", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "User suspended successfully", user, nil)
+}
+
+func (c *UserAdminController) UnsuspendUser(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	user, err := c.userAdminService.Unsuspend(ctx, ctx.GetUint("userID"), id, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to lift suspension", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Suspension lifted successfully", user, nil)
+}
+
+func (c *UserAdminController) ForcePasswordReset(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	mailed, err := c.userAdminService.ForcePasswordReset(ctx, ctx.GetUint("userID"), id, clientInfo(ctx))
+	if err != nil {
+		c.sendError(ctx, "Failed to reset password", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "Password reset successfully", gin.H{"mailed": mailed}, nil)
+}
+
+// DeleteUser takes the author to hand the user's posts to in ?reassign_to.
+func (c *UserAdminController) DeleteUser(ctx *gin.Context) {
+	id, ok := parseID(ctx, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+	var reassignTo uint
+	if param := ctx.Query("reassign_to"); param != "" {
+		parsed, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			utils.SendError(ctx, http.StatusBadRequest, "Invalid reassign_to", err)
+			return
+		}
+		reassignTo = uint(parsed)
+	}
+
+	if err := c.userAdminService.DeleteUser(ctx, ctx.GetUint("userID"), id, reassignTo, clientInfo(ctx)); err != nil {
+		c.sendError(ctx, "Failed to delete user", err)
+		return
+	}
+
+	utils.SendSuccess(ctx, "User deleted successfully", nil, nil)
+}
+
+func (c *UserAdminController) sendError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, utils.ErrUserNotFound):
+		utils.SendError(ctx, http.StatusNotFound, "User not found", nil)
+	case errors.Is(err, utils.ErrInvalidSortField), errors.Is(err, utils.ErrInvalidExpiry), errors.Is(err, utils.ErrInvalidReassign):
+		utils.SendError(ctx, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, utils.ErrSelfAction), errors.Is(err, utils.ErrReassignRequired):
+		utils.SendError(ctx, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.SendError(ctx, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,215 @@
+// Package keyring holds the asymmetric keys access tokens are signed with. Each key
+// has a kid; the newest key signs, and older keys keep verifying until they expire,
+// so tokens issued just before a rotation stay valid.
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Supported signing algorithms, named as in the JWT alg header.
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+// Key is one signing key. It signs from NotBefore until RetiresAt and verifies
+// until ExpiresAt.
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	CreatedAt time.Time
+	NotBefore time.Time
+	RetiresAt time.Time
+	ExpiresAt time.Time
+}
+
+// Generate creates a key for algorithm with a random kid. It starts signing after
+// delay, which gives verifiers time to learn the key before tokens carry its kid.
+func Generate(algorithm string, now time.Time, delay, signFor, overlap time.Duration) (*Key, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &Key{
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		Algorithm: algorithm,
+		Private:   private,
+		CreatedAt: now,
+		NotBefore: now.Add(delay),
+		RetiresAt: now.Add(delay + signFor),
+		ExpiresAt: now.Add(delay + signFor + overlap),
+	}, nil
+}
+
+// Method returns the jwt signing method for the key.
+func (k *Key) Method() jwt.SigningMethod {
+	if k.Algorithm == EdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// Public returns the key that verifies the key's signatures.
+func (k *Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// MarshalPrivate encodes the private key as PKCS #8 PEM.
+func (k *Key) MarshalPrivate() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParsePrivate decodes a private key written by MarshalPrivate and checks that it
+// fits algorithm.
+func ParsePrivate(algorithm, encoded string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == RS256 {
+			return key, nil
+		}
+	case ed25519.PrivateKey:
+		if algorithm == EdDSA {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("private key does not match algorithm %q", algorithm)
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK describes the key's public half.
+func (k *Key) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+	switch public := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// Keyring is the set of keys in use. It is safe for concurrent use; Set replaces
+// the whole set, which is how new and expired keys come and go.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+func New() *Keyring {
+	return &Keyring{}
+}
+
+// Set replaces the keys, keeping them newest first.
+func (r *Keyring) Set(keys []*Key) {
+	sorted := append([]*Key(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+	r.mu.Lock()
+	r.keys = sorted
+	r.mu.Unlock()
+}
+
+// Signing returns the newest key that has started signing and hasn't retired.
+func (r *Keyring) Signing(now time.Time) (*Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		if !now.Before(key.NotBefore) && now.Before(key.RetiresAt) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// Find returns the unexpired key with the given kid.
+func (r *Keyring) Find(kid string, now time.Time) (*Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		if key.ID == kid && now.Before(key.ExpiresAt) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS returns every unexpired key, so verifiers also accept tokens signed by keys
+// that have just retired, and learn about new keys before they sign.
+func (r *Keyring) JWKS(now time.Time) JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if now.Before(key.ExpiresAt) {
+			set.Keys = append(set.Keys, key.JWK())
+		}
+	}
+	return set
+}
@@ -0,0 +1,121 @@
+package keyring
+
+import (
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Now()
+	for _, algorithm := range []string{RS256, EdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := Generate(algorithm, now, 0, time.Hour, time.Hour)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			ring := New()
+			ring.Set([]*Key{key})
+
+			token := jwt.NewWithClaims(key.Method(), jwt.MapClaims{"sub": "1"})
+			token.Header["kid"] = key.ID
+			signed, err := token.SignedString(key.Private)
+			if err != nil {
+				t.Fatalf("SignedString: %v", err)
+			}
+
+			parsed, err := jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+				kid, _ := token.Header["kid"].(string)
+				found, ok := ring.Find(kid, now)
+				if !ok {
+					t.Fatalf("kid %q not found", kid)
+				}
+				return found.Public(), nil
+			})
+			if err != nil || !parsed.Valid {
+				t.Fatalf("Parse: %v", err)
+			}
+			if parsed.Method.Alg() != algorithm {
+				t.Errorf("alg = %s, want %s", parsed.Method.Alg(), algorithm)
+			}
+		})
+	}
+}
+
+func TestMarshalPrivate(t *testing.T) {
+	key, err := Generate(EdDSA, time.Now(), 0, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := key.MarshalPrivate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParsePrivate(EdDSA, encoded)
+	if err != nil {
+		t.Fatalf("ParsePrivate: %v", err)
+	}
+	if !key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(parsed.Public()) {
+		t.Error("parsed key differs from the original")
+	}
+	if _, err := ParsePrivate(RS256, encoded); err == nil {
+		t.Error("Ed25519 key accepted as RS256")
+	}
+	if _, err := ParsePrivate(EdDSA, "not pem"); err == nil {
+		t.Error("garbage accepted as a key")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	old, _ := Generate(EdDSA, start, 0, 24*time.Hour, 2*time.Hour)
+	next, _ := Generate(EdDSA, start.Add(23*time.Hour), time.Hour, 24*time.Hour, 2*time.Hour)
+	ring := New()
+	ring.Set([]*Key{old, next})
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		signing *Key
+		oldOK   bool
+		jwks    int
+	}{
+		{"old key signs until its successor's not-before", start.Add(23*time.Hour + 30*time.Minute), old, true, 2},
+		{"newest key signs from its not-before", start.Add(24 * time.Hour), next, true, 2},
+		{"old key verifies during overlap", start.Add(25 * time.Hour), next, true, 2},
+		{"old key expires after overlap", start.Add(26 * time.Hour), next, false, 1},
+		{"nothing signs after the last key retires", start.Add(48 * time.Hour), nil, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signing, ok := ring.Signing(tt.at)
+			if signing != tt.signing || ok != (tt.signing != nil) {
+				t.Errorf("Signing = %v, want %v", signing, tt.signing)
+			}
+			if _, ok := ring.Find(old.ID, tt.at); ok != tt.oldOK {
+				t.Errorf("Find(old) = %v, want %v", ok, tt.oldOK)
+			}
+			if got := len(ring.JWKS(tt.at).Keys); got != tt.jwks {
+				t.Errorf("JWKS has %d keys, want %d", got, tt.jwks)
+			}
+		})
+	}
+}
+
+func TestJWK(t *testing.T) {
+	rsaKey, _ := Generate(RS256, time.Now(), 0, time.Hour, time.Hour)
+	jwk := rsaKey.JWK()
+	if jwk.KeyType != "RSA" || jwk.E != "AQAB" || jwk.N == "" || jwk.KeyID != rsaKey.ID || jwk.Algorithm != RS256 {
+		t.Errorf("unexpected RSA JWK %+v", jwk)
+	}
+
+	edKey, _ := Generate(EdDSA, time.Now(), 0, time.Hour, time.Hour)
+	jwk = edKey.JWK()
+	if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" || len(jwk.X) != 43 || jwk.N != "" {
+		t.Errorf("unexpected Ed25519 JWK %+v", jwk)
+	}
+}
@@ -2,15 +2,28 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/alimosavifard/zyros-backend/commands"
 	"github.com/alimosavifard/zyros-backend/config"
 	"github.com/alimosavifard/zyros-backend/controllers"
+	"github.com/alimosavifard/zyros-backend/keyring"
+	"github.com/alimosavifard/zyros-backend/mailer"
 	"github.com/alimosavifard/zyros-backend/middleware"
+	"github.com/alimosavifard/zyros-backend/migrations"
+	"github.com/alimosavifard/zyros-backend/oidc"
+	"github.com/alimosavifard/zyros-backend/policy"
 	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/scheduler"
 	"github.com/alimosavifard/zyros-backend/services"
+	"github.com/alimosavifard/zyros-backend/storage"
 	"github.com/alimosavifard/zyros-backend/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -28,6 +41,18 @@ func main() {
 
 	logger := utils.InitLogger()
 
+	// Maintenance commands: `migrate up|down N|status` and `seed`
+	if len(os.Args) > 1 {
+		if err := commands.Run(os.Args[1:], config.ConnectDB(cfg)); err != nil {
+			logger.Fatal().Err(err).Msg("Command failed")
+		}
+		return
+	}
+
+	// Stop serving on SIGINT or SIGTERM; background workers stop with ctx too
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 	r.SetTrustedProxies([]string{"127.0.0.1"})
@@ -37,25 +62,98 @@ func main() {
 	db := config.ConnectDB(cfg)
 	redisClient := config.ConnectRedis(cfg)
 
+	if cfg.DB_AUTO_MIGRATE == "true" {
+		if err := migrations.Up(db); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to run migrations")
+		}
+	}
+
 	// Initialize repositories with context-aware methods
 	userRepo := repositories.NewUserRepository(db)
 	roleRepo := repositories.NewRoleRepository(db)
 	postRepo := repositories.NewPostRepository(db)
 	likeRepo := repositories.NewLikeRepository(db)
+	taxonomyRepo := repositories.NewTaxonomyRepository(db)
+	commentRepo := repositories.NewCommentRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(db)
+	accountTokenRepo := repositories.NewAccountTokenRepository(db)
+	mfaRepo := repositories.NewMFARepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	identityRepo := repositories.NewIdentityRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
 
 	// اصلاح ترتیب: likeService را اول تعریف کنید
 	likeService := services.NewLikeService(likeRepo)
-	authService := services.NewAuthService(userRepo, roleRepo, redisClient, cfg)
-	postService := services.NewPostService(postRepo, redisClient, likeService) // حالا likeService تعریف شده
+	auditLog := services.NewAuditLog(auditRepo, utils.SystemClock{}, []byte(requireKey("AUDIT_HMAC_KEY", cfg.AUDIT_HMAC_KEY)))
+	// The audit log outlives ctx: requests still finishing during shutdown record
+	// events, so it is stopped, and drained, only once the server is done
+	auditCtx, stopAudit := context.WithCancel(context.Background())
+	auditDone := make(chan struct{})
+	go func() {
+		defer close(auditDone)
+		auditLog.Run(auditCtx)
+	}()
+	mfaService := services.NewMFAService(mfaRepo, newSecretBox("MFA_ENCRYPTION_KEY", cfg.MFA_ENCRYPTION_KEY), cfg.MFA_ENFORCE_PRIVILEGED != "false")
+	loginGuard := services.NewLoginGuard(redisClient, loginLimits(cfg), auditLog)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, roleRepo)
+	signingKeys, keyRotator := newKeyring(ctx, cfg, repositories.NewSigningKeyRepository(db), scheduler.NewRedisLocker(redisClient))
+	authService := services.NewAuthService(userRepo, roleRepo, sessionRepo, refreshTokenRepo, mfaService, loginGuard, apiKeyService, signingKeys, redisClient, auditLog, cfg)
+	if keyRotator != nil {
+		authService.OnUnknownKey(keyRotator.Reload)
+	}
+	accountService := services.NewAccountService(userRepo, accountTokenRepo, authService, newMailer(cfg), cfg.APP_URL)
+	loginGuard.OnLockout(accountService.NotifyLockout)
+	oidcService := services.NewOIDCService(oidcProviders(cfg), identityRepo, userRepo, authService, redisClient)
+	roleService := services.NewRoleService(roleRepo, authService, auditLog)
+	if err := roleService.SyncPermissions(context.Background()); err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create registered permissions")
+	}
+	// Grants may have changed while the server was down, e.g. by `seed`
+	if err := authService.InvalidateAllPermissions(context.Background()); err != nil {
+		logger.Warn().Err(err).Msg("Failed to invalidate cached permissions")
+	}
+	revisionLimit, err := strconv.Atoi(cfg.POST_REVISION_LIMIT)
+	if err != nil || revisionLimit < 0 {
+		revisionLimit = 50
+	}
+	taxonomyService := services.NewTaxonomyService(taxonomyRepo, redisClient)
+	postPolicy := policy.NewPolicy(policy.DefaultRules, policy.DefaultHierarchy)
+	postService := services.NewPostService(postRepo, taxonomyService, redisClient, likeService, postPolicy, auditLog, revisionLimit) // حالا likeService تعریف شده
+	commentService := services.NewCommentService(commentRepo, postService, postPolicy)
+	profileService := services.NewProfileService(userRepo, postService, authService)
+	userAdminService := services.NewUserAdminService(userRepo, authService, accountService, apiKeyService, postService, auditLog)
 	
 	
+	// Scheduled publishing: flips posts live at publish_at and archives them at unpublish_at
+	schedulerInterval, err := time.ParseDuration(cfg.SCHEDULER_INTERVAL)
+	if err != nil || schedulerInterval <= 0 {
+		schedulerInterval = 30 * time.Second
+	}
+	publishWorker := scheduler.NewPublishWorker(postRepo, scheduler.NewRedisLocker(redisClient), postService.InvalidatePostCaches, utils.SystemClock{}, schedulerInterval)
+	go publishWorker.Run(ctx)
+
+	fileStorage := newStorage(cfg)
+
 	// Initialize controllers
-	authController := controllers.NewAuthController(authService)
-	postController := controllers.NewPostController(postService)
+	authController := controllers.NewAuthController(authService, accountService)
+	postController := controllers.NewPostController(postService, authService, fileStorage, auditLog)
 	articleController := controllers.NewArticleController(postService)
 	likeController := controllers.NewLikeController(likeService)
+	taxonomyController := controllers.NewTaxonomyController(taxonomyService)
+	commentController := controllers.NewCommentController(commentService, authService)
+	sessionController := controllers.NewSessionController(authService)
+	accountController := controllers.NewAccountController(accountService)
+	mfaController := controllers.NewMFAController(authService, mfaService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService, authService)
+	oidcController := controllers.NewOIDCController(oidcService, cfg.APP_URL)
+	roleController := controllers.NewRoleController(roleService)
+	profileController := controllers.NewProfileController(profileService, authService)
+	userAdminController := controllers.NewUserAdminController(userAdminService)
+	auditController := controllers.NewAuditController(auditLog)
 
 	// Pass config values to middlewares
+	r.Use(middleware.RequestIDMiddleware())
 	r.Use(middleware.CORSMiddleware(cfg.ALLOWED_ORIGINS))
 	r.Use(gin.Logger())
 	r.Use(middleware.RateLimitMiddleware(redisClient, cfg.RATE_LIMIT))
@@ -63,24 +161,303 @@ func main() {
 
 	// CSRF middleware is now initialized with a secret
 	r.GET("/api/v1/health", controllers.HealthCheck)
+	r.GET("/.well-known/jwks.json", authController.JWKS)
 	r.POST("/api/v1/register", authController.Register)
 	r.POST("/api/v1/login", authController.Login)
+	r.POST("/api/v1/auth/refresh", authController.Refresh)
+	r.POST("/api/v1/auth/logout", authController.Logout)
+	r.POST("/api/v1/auth/password/forgot", accountController.ForgotPassword)
+	r.POST("/api/v1/auth/password/reset", accountController.ResetPassword)
+	r.POST("/api/v1/auth/email/verification", accountController.RequestEmailVerification)
+	r.POST("/api/v1/auth/email/verify", accountController.VerifyEmail)
+	r.POST("/api/v1/auth/mfa/verify", mfaController.Verify)
+	r.POST("/api/v1/auth/mfa/enroll", mfaController.BeginEnrollment)
+	r.POST("/api/v1/auth/mfa/enroll/confirm", mfaController.ConfirmEnrollment)
+	r.GET("/api/v1/auth/oidc", oidcController.GetProviders)
+	r.GET("/api/v1/auth/oidc/:provider/login", oidcController.Login)
+	r.GET("/api/v1/auth/oidc/:provider/callback", oidcController.Callback)
 	r.GET("/api/v1/csrf-token", authController.GetCSRFToken)
-	r.GET("/api/v1/posts", postController.GetPosts)
-	r.GET("/api/v1/posts/:id", postController.GetPostByID)
+	r.GET("/api/v1/posts", middleware.OptionalAuthMiddleware(authService), postController.GetPosts)
+	r.GET("/api/v1/posts/:id", middleware.OptionalAuthMiddleware(authService), postController.GetPostByID)
+	r.GET("/api/v1/posts/by-slug/:lang/:slug", middleware.OptionalAuthMiddleware(authService), postController.GetPostBySlug)
+	r.GET("/api/v1/posts/:id/comments", middleware.OptionalAuthMiddleware(authService), commentController.GetComments)
+	r.GET("/api/v1/search", middleware.OptionalAuthMiddleware(authService), postController.Search)
+	r.GET("/api/v1/categories", taxonomyController.GetCategories)
+	r.GET("/api/v1/categories/:slug", taxonomyController.GetCategory)
+	r.GET("/api/v1/tags", taxonomyController.GetTags)
+	r.GET("/api/v1/users/:username", profileController.GetProfile)
+	r.GET("/api/v1/users/:username/posts", middleware.OptionalAuthMiddleware(authService), profileController.GetProfilePosts)
 
 	api := r.Group("/api/v1")
 	api.Use(middleware.CSRFMiddleware(cfg.CSRF_SECRET), middleware.AuthMiddleware(authService))
 	{
 		api.POST("/posts", middleware.PermissionMiddleware(authService, "create_post"), postController.CreatePost)
+		api.GET("/posts/trash", middleware.PermissionMiddleware(authService, "delete_post"), postController.GetTrash)
+		api.PUT("/posts/:id", middleware.PermissionMiddleware(authService, "edit_post"), postController.UpdatePost)
+		api.PATCH("/posts/:id", middleware.PermissionMiddleware(authService, "edit_post"), postController.UpdatePost)
+		api.DELETE("/posts/:id", middleware.PermissionMiddleware(authService, "delete_post"), postController.DeletePost)
+		api.POST("/posts/:id/restore", middleware.PermissionMiddleware(authService, "delete_post"), postController.RestorePost)
+		api.DELETE("/posts/:id/purge", middleware.PermissionMiddleware(authService, "delete_any_post"), postController.PurgePost)
+		api.POST("/posts/:id/transitions", postController.TransitionPost)
+		api.GET("/posts/:id/transitions", postController.GetTransitions)
+		api.GET("/posts/:id/revisions", postController.GetRevisions)
+		api.GET("/posts/:id/revisions/:rev/diff", postController.DiffRevision)
+		api.POST("/posts/:id/revisions/:rev/restore", middleware.PermissionMiddleware(authService, "edit_post"), postController.RestoreRevision)
 		api.POST("/articles", middleware.PermissionMiddleware(authService, "create_article"), articleController.CreateArticle)
 		api.POST("/upload-image", middleware.PermissionMiddleware(authService, "upload_image"), postController.UploadImage)
 		api.POST("/posts/:id/like", middleware.PermissionMiddleware(authService, "like_post"), likeController.LikePost)
 		api.DELETE("/posts/:id/like", middleware.PermissionMiddleware(authService, "unlike_post"), likeController.UnlikePost)
+		api.POST("/posts/:id/comments", middleware.PermissionMiddleware(authService, "comment_post"), commentController.CreateComment)
+		api.PATCH("/comments/:id", middleware.PermissionMiddleware(authService, "comment_post"), commentController.UpdateComment)
+		api.DELETE("/comments/:id", commentController.DeleteComment)
+
+		// Account settings need a browser session; API keys can't change them
+		me := api.Group("/me", middleware.SessionOnlyMiddleware())
+		me.GET("", profileController.GetMe)
+		me.PATCH("", profileController.UpdateMe)
+		me.POST("/password", profileController.ChangePassword)
+		me.GET("/sessions", sessionController.GetMySessions)
+		me.DELETE("/sessions", sessionController.RevokeMyOtherSessions)
+		me.DELETE("/sessions/:id", sessionController.RevokeMySession)
+		me.GET("/mfa", mfaController.GetStatus)
+		me.POST("/mfa/totp", mfaController.Setup)
+		me.POST("/mfa/totp/confirm", mfaController.Enable)
+		me.DELETE("/mfa/totp", mfaController.Disable)
+		me.POST("/mfa/recovery-codes", mfaController.RegenerateRecoveryCodes)
+		me.GET("/api-keys", apiKeyController.GetMyAPIKeys)
+		me.POST("/api-keys", apiKeyController.CreateAPIKey)
+		me.DELETE("/api-keys/:id", apiKeyController.RevokeAPIKey)
+
+		moderation := api.Group("", middleware.PermissionMiddleware(authService, "moderate_comments"))
+		moderation.GET("/comments/moderation", commentController.GetModerationQueue)
+		moderation.POST("/comments/:id/moderation", commentController.ModerateComment)
+
+		taxonomy := api.Group("", middleware.PermissionMiddleware(authService, "manage_taxonomy"))
+		taxonomy.POST("/categories", taxonomyController.CreateCategory)
+		taxonomy.PATCH("/categories/:id", taxonomyController.UpdateCategory)
+		taxonomy.DELETE("/categories/:id", taxonomyController.DeleteCategory)
+		taxonomy.POST("/tags", taxonomyController.CreateTag)
+		taxonomy.PATCH("/tags/:id", taxonomyController.UpdateTag)
+		taxonomy.DELETE("/tags/:id", taxonomyController.DeleteTag)
+
+		admin := api.Group("/admin", middleware.PermissionMiddleware(authService, "manage_users"))
+		admin.GET("/users", userAdminController.GetUsers)
+		admin.GET("/users/:id", userAdminController.GetUser)
+		admin.DELETE("/users/:id", userAdminController.DeleteUser)
+		admin.PUT("/users/:id/suspension", userAdminController.SuspendUser)
+		admin.DELETE("/users/:id/suspension", userAdminController.UnsuspendUser)
+		admin.POST("/users/:id/password-reset", userAdminController.ForcePasswordReset)
+		admin.GET("/users/:id/sessions", sessionController.GetUserSessions)
+		admin.DELETE("/users/:id/sessions", sessionController.RevokeUserSessions)
+		admin.DELETE("/users/:id/sessions/:sid", sessionController.RevokeUserSession)
+		admin.POST("/users/:id/unlock", sessionController.UnlockUser)
+		admin.GET("/users/:id/roles", roleController.GetUserRoles)
+		admin.PUT("/users/:id/roles/:roleId", roleController.AssignRole)
+		admin.DELETE("/users/:id/roles/:roleId", roleController.RevokeRole)
+		admin.GET("/roles", roleController.GetRoles)
+		admin.POST("/roles", roleController.CreateRole)
+		admin.GET("/roles/:id", roleController.GetRole)
+		admin.PATCH("/roles/:id", roleController.UpdateRole)
+		admin.DELETE("/roles/:id", roleController.DeleteRole)
+		admin.PUT("/roles/:id/permissions/:permission", roleController.AttachPermission)
+		admin.DELETE("/roles/:id/permissions/:permission", roleController.DetachPermission)
+		admin.GET("/audit", auditController.GetAuditLog)
+		admin.GET("/audit/export", auditController.ExportAuditLog)
+		admin.GET("/audit/verify", auditController.VerifyAuditLog)
+		admin.GET("/permissions", roleController.GetPermissions)
+		admin.POST("/permissions", roleController.CreatePermission)
+		admin.PATCH("/permissions/:id", roleController.UpdatePermission)
+		admin.DELETE("/permissions/:id", roleController.DeletePermission)
 	}
 
-	r.Static("/uploads", "./uploads")
+	// Drivers without a public address of their own serve files from here
+	if handler, ok := fileStorage.(http.Handler); ok {
+		uploads := gin.WrapH(http.StripPrefix(uploadsPath+"/", handler))
+		r.GET(uploadsPath+"/*key", uploads)
+		r.HEAD(uploadsPath+"/*key", uploads)
+	}
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	r.Run(":" + cfg.PORT)
-}
\ No newline at end of file
+	server := &http.Server{Addr: ":" + cfg.PORT, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal().Err(err).Msg("Server failed")
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	logger.Info().Msg("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("Server did not shut down cleanly")
+	}
+	stopAudit()
+	<-auditDone
+}
+
+// shutdownTimeout is how long requests in flight get to finish on shutdown.
+const shutdownTimeout = 15 * time.Second
+
+// uploadsPath is where the local driver serves uploaded files.
+const uploadsPath = "/uploads"
+
+// newStorage picks where uploaded files are kept from STORAGE_DRIVER.
+func newStorage(cfg *config.Config) storage.Storage {
+	switch cfg.STORAGE_DRIVER {
+	case "s3":
+		store, err := storage.NewS3Storage(storage.S3Options{
+			Endpoint:        cfg.S3_ENDPOINT,
+			Region:          cfg.S3_REGION,
+			Bucket:          cfg.S3_BUCKET,
+			AccessKeyID:     cfg.S3_ACCESS_KEY_ID,
+			SecretAccessKey: cfg.S3_SECRET_ACCESS_KEY,
+			PathStyle:       cfg.S3_PATH_STYLE == "true",
+			PublicURL:       cfg.STORAGE_PUBLIC_URL,
+		})
+		if err != nil {
+			utils.InitLogger().Fatal().Err(err).Msg("Failed to initialize S3 storage")
+		}
+		return store
+	case "", "local":
+		dir := cfg.STORAGE_DIR
+		if dir == "" {
+			dir = "./uploads"
+		}
+		publicURL := cfg.STORAGE_PUBLIC_URL
+		if publicURL == "" {
+			publicURL = uploadsPath
+		}
+		key := requireKey("STORAGE_SIGNING_KEY", cfg.STORAGE_SIGNING_KEY)
+		store, err := storage.NewLocalStorage(dir, publicURL, []byte(key))
+		if err != nil {
+			utils.InitLogger().Fatal().Err(err).Msg("Failed to initialize local storage")
+		}
+		return store
+	default:
+		utils.InitLogger().Fatal().Msgf("Unsupported STORAGE_DRIVER %q", cfg.STORAGE_DRIVER)
+		return nil
+	}
+}
+
+// newMailer picks the mail transport from MAIL_DRIVER. Without SMTP settings mail
+// lands in the outbox directory, which is what local development wants.
+func newMailer(cfg *config.Config) mailer.Mailer {
+	if cfg.MAIL_DRIVER == "smtp" {
+		port := cfg.SMTP_PORT
+		if port == "" {
+			port = "587"
+		}
+		return mailer.NewSMTPMailer(cfg.SMTP_HOST, port, cfg.SMTP_USERNAME, cfg.SMTP_PASSWORD, cfg.MAIL_FROM)
+	}
+	dir := cfg.MAIL_OUTBOX_DIR
+	if dir == "" {
+		dir = "./outbox"
+	}
+	return mailer.NewOutboxMailer(dir, cfg.MAIL_FROM)
+}
+
+// requireKey returns the key in the setting called name, stopping startup when it
+// is unset. Keys never fall back to JWT_SECRET: rotating that secret would then
+// also invalidate whatever the key protects.
+func requireKey(name, key string) string {
+	if key == "" {
+		utils.InitLogger().Fatal().Msgf("%s must be set", name)
+	}
+	return key
+}
+
+// newSecretBox builds a cipher for secrets stored in the database, keyed by the
+// setting called name. Changing the key makes what it encrypted unreadable, so set
+// it once.
+func newSecretBox(name, key string) *utils.SecretBox {
+	box, err := utils.NewSecretBox(requireKey(name, key))
+	if err != nil {
+		utils.InitLogger().Fatal().Err(err).Msg("Failed to initialize secret box")
+	}
+	return box
+}
+
+// loginLimits reads the lockout settings, keeping the default for any that is unset
+// or invalid.
+func loginLimits(cfg *config.Config) services.LoginLimits {
+	limits := services.DefaultLoginLimits
+	if n, err := strconv.ParseInt(cfg.LOGIN_MAX_FAILURES, 10, 64); err == nil && n > 0 {
+		limits.MaxUserFailures = n
+	}
+	if n, err := strconv.ParseInt(cfg.LOGIN_IP_MAX_FAILURES, 10, 64); err == nil && n > 0 {
+		limits.MaxIPFailures = n
+	}
+	if d, err := time.ParseDuration(cfg.LOGIN_LOCKOUT); err == nil && d > 0 {
+		limits.BaseLockout = d
+	}
+	if d, err := time.ParseDuration(cfg.LOGIN_MAX_LOCKOUT); err == nil && d >= limits.BaseLockout {
+		limits.MaxLockout = d
+	}
+	return limits
+}
+
+// newKeyring loads the asymmetric signing keys and starts rotating them. It returns
+// nils when JWT_ALGORITHM is HS256 (the default), which keeps signing with JWT_SECRET.
+func newKeyring(ctx context.Context, cfg *config.Config, store scheduler.SigningKeyStore, locker scheduler.Locker) (*keyring.Keyring, *scheduler.KeyRotator) {
+	algorithm := cfg.JWT_ALGORITHM
+	if algorithm == "" || algorithm == "HS256" {
+		return nil, nil
+	}
+	if algorithm != keyring.RS256 && algorithm != keyring.EdDSA {
+		utils.InitLogger().Fatal().Msgf("Unsupported JWT_ALGORITHM %q", algorithm)
+	}
+
+	policy := scheduler.RotationPolicy{Algorithm: algorithm, SignFor: 30 * 24 * time.Hour, Overlap: 24 * time.Hour}
+	if d, err := time.ParseDuration(cfg.JWT_KEY_ROTATION); err == nil && d > 0 {
+		policy.SignFor = d
+	}
+	if d, err := time.ParseDuration(cfg.JWT_KEY_OVERLAP); err == nil && d > 0 {
+		policy.Overlap = d
+	}
+	// A retired key must outlive the last access token it signed
+	if jwtExp, err := time.ParseDuration(cfg.JWT_EXPIRATION); err == nil && policy.Overlap < jwtExp {
+		policy.Overlap = jwtExp
+	}
+
+	ring := keyring.New()
+	box := newSecretBox("JWT_KEY_ENCRYPTION_KEY", cfg.JWT_KEY_ENCRYPTION_KEY)
+	rotator := scheduler.NewKeyRotator(store, locker, ring, box, policy, utils.SystemClock{}, time.Minute)
+	if err := rotator.Ready(ctx, 30); err != nil {
+		utils.InitLogger().Fatal().Err(err).Msg("Failed to load signing keys")
+	}
+	go rotator.Run(ctx)
+	return ring, rotator
+}
+
+// oidcProviders sets up the identity providers listed in OIDC_PROVIDERS. Each one's
+// callback is under OIDC_REDIRECT_URL, the address browsers reach this API at.
+func oidcProviders(cfg *config.Config) map[string]*oidc.Provider {
+	providers := make(map[string]*oidc.Provider)
+	if len(cfg.OIDC_PROVIDERS) == 0 {
+		return providers
+	}
+	if cfg.OIDC_REDIRECT_URL == "" {
+		utils.InitLogger().Fatal().Msg("OIDC_REDIRECT_URL must be set to use OIDC_PROVIDERS")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, p := range cfg.OIDC_PROVIDERS {
+		if p.Issuer == "" || p.ClientID == "" {
+			utils.InitLogger().Fatal().Msgf("OIDC provider %q needs an issuer and a client ID", p.Name)
+		}
+		scopes := strings.Fields(p.Scopes)
+		if len(scopes) == 0 {
+			scopes = []string{"email", "profile"}
+		}
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  strings.TrimSuffix(cfg.OIDC_REDIRECT_URL, "/") + "/api/v1/auth/oidc/" + p.Name + "/callback",
+			Scopes:       scopes,
+		}, client, utils.SystemClock{})
+	}
+	return providers
+}
@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
-	
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/policy"
 	"github.com/alimosavifard/zyros-backend/services"
 	"github.com/alimosavifard/zyros-backend/utils"
 	"github.com/gin-gonic/gin"
@@ -26,18 +29,62 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 			return
 		}
 		
-		userID, err := authService.ValidateToken(ctx, parts[1])
+		claims, err := authService.Authenticate(ctx, parts[1])
 		if err != nil {
 			utils.SendError(ctx, http.StatusUnauthorized, "Invalid token", err)
 			ctx.Abort()
 			return
 		}
 
-		ctx.Set("userID", userID)
+		setAuthContext(ctx, claims)
+		ctx.Next()
+	}
+}
+
+// OptionalAuthMiddleware identifies the caller on public routes when a valid
+// bearer token is present, and lets anonymous requests through unchanged.
+func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		parts := strings.Split(ctx.GetHeader("Authorization"), " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := authService.Authenticate(ctx, parts[1]); err == nil {
+				setAuthContext(ctx, claims)
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// setAuthContext stores who is calling for the handlers. Requests made with an API
+// key also get "apiKeyID" and "scopes".
+func setAuthContext(ctx *gin.Context, claims *services.AccessClaims) {
+	ctx.Set("userID", claims.UserID)
+	ctx.Set("sessionID", claims.SessionID)
+	if claims.APIKeyID != 0 {
+		ctx.Set("apiKeyID", claims.APIKeyID)
+		ctx.Set("scopes", claims.Scopes)
+	}
+}
+
+// SessionOnlyMiddleware refuses API keys on routes that manage the account itself,
+// so a leaked key can't mint more keys or take over the login.
+func SessionOnlyMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := ctx.Get("apiKeyID"); ok {
+			utils.SendError(ctx, http.StatusForbidden, "Not available with an API key", nil)
+			ctx.Abort()
+			return
+		}
 		ctx.Next()
 	}
 }
 
+// usesAPIKey reports whether the request authenticates with an API key.
+func usesAPIKey(ctx *gin.Context) bool {
+	parts := strings.Split(ctx.GetHeader("Authorization"), " ")
+	return len(parts) == 2 && parts[0] == "Bearer" && services.IsAPIKey(parts[1])
+}
+
 func CORSMiddleware(allowedOrigins string) gin.HandlerFunc {
     origins := strings.Split(allowedOrigins, ",")
     if len(origins) == 0 || origins[0] == "" {
@@ -46,15 +93,19 @@ func CORSMiddleware(allowedOrigins string) gin.HandlerFunc {
 
 	return cors.New(cors.Config{
 		AllowOrigins:     origins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-CSRF-Token"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-CSRF-Token", "If-Match", "If-None-Match", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
 }
 
 func PermissionMiddleware(authService *services.AuthService, permName string) gin.HandlerFunc {
+	// Fail at startup rather than deny everyone a permission that can't be granted
+	if !policy.IsRegistered(permName) {
+		panic(fmt.Sprintf("permission %q is not registered in policy.Permissions", permName))
+	}
 	return func(ctx *gin.Context) {
 		userID, exists := ctx.Get("userID")
 		if !exists {
@@ -76,6 +127,22 @@ func PermissionMiddleware(authService *services.AuthService, permName string) gi
 			return
 		}
 
+		// An API key may only use the permissions it was scoped to
+		if scopes, ok := ctx.Get("scopes"); ok && !hasScope(scopes.([]string), permName) {
+			utils.SendError(ctx, http.StatusForbidden, "API key is not scoped for this action", nil)
+			ctx.Abort()
+			return
+		}
+
 		ctx.Next()
 	}
-}
\ No newline at end of file
+}
+
+func hasScope(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -5,20 +5,18 @@ import (
     "github.com/alimosavifard/zyros-backend/utils"
     "github.com/gin-gonic/gin"
     "github.com/gorilla/csrf"
-    "os"
 )
 
-// The auth key should be a 32-byte secret. It's best to set this as an environment variable.
-var csrfAuthKey = []byte(os.Getenv("CSRF_AUTH_KEY"))
-
-func CSRFMiddleware() gin.HandlerFunc {
-    // Check if the key is set. If not, generate a random one for development only.
+// CSRFMiddleware protects state-changing requests with gorilla/csrf.
+// The secret must be a 32-byte string (CSRF_SECRET).
+func CSRFMiddleware(secret string) gin.HandlerFunc {
+    csrfAuthKey := []byte(secret)
     if len(csrfAuthKey) != 32 {
-        utils.InitLogger().Fatal().Msg("CSRF_AUTH_KEY environment variable is not set to a 32-byte string.")
+        utils.InitLogger().Fatal().Msg("CSRF_SECRET environment variable is not set to a 32-byte string.")
     }
 
     // CSRF middleware with required settings
-    csrfMiddleware := csrf.Protect(
+    protect := csrf.Protect(
         csrfAuthKey,
         csrf.Path("/"),
         csrf.HttpOnly(true),
@@ -27,7 +25,25 @@ func CSRFMiddleware() gin.HandlerFunc {
     )
 
     return func(c *gin.Context) {
-        csrfMiddleware(c.Writer, c.Request)
+        // API keys travel in the Authorization header, which a cross-site form can't
+        // set, so there is no cookie to forge a request with
+        if usesAPIKey(c) {
+            c.Next()
+            return
+        }
+
+        passed := false
+        protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            passed = true
+            c.Request = r
+            c.Header("X-CSRF-Token", csrf.Token(r))
+        })).ServeHTTP(c.Writer, c.Request)
+
+        // gorilla/csrf has already written a 403 when the token check failed
+        if !passed {
+            c.Abort()
+            return
+        }
         c.Next()
     }
 }
\ No newline at end of file
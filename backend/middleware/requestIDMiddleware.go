@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDPattern limits the request IDs taken from clients to what is safe to
+// log and store.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestIDMiddleware gives each request an ID, reusing a well-formed X-Request-ID
+// from a proxy in front, and echoes it back. The ID and the client's IP are kept on
+// the context for the audit log.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			var err error
+			if id, err = utils.RandomToken(12); err != nil {
+				id = ""
+			}
+		}
+		c.Set("requestID", id)
+		c.Set("clientIP", c.ClientIP())
+		c.Header("X-Request-ID", id)
+		c.Next()
+	}
+}
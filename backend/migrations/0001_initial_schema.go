@@ -0,0 +1,85 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// The initial schema mirrors what AutoMigrate used to create, using IF NOT EXISTS
+// so databases bootstrapped by the old drop-and-recreate flow adopt it in place.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS users (
+					id BIGSERIAL PRIMARY KEY,
+					created_at TIMESTAMPTZ,
+					updated_at TIMESTAMPTZ,
+					deleted_at TIMESTAMPTZ,
+					username TEXT NOT NULL,
+					password TEXT NOT NULL,
+					CONSTRAINT uni_users_username UNIQUE (username)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at)`,
+				`CREATE TABLE IF NOT EXISTS roles (
+					id BIGSERIAL PRIMARY KEY,
+					created_at TIMESTAMPTZ,
+					updated_at TIMESTAMPTZ,
+					deleted_at TIMESTAMPTZ,
+					name TEXT NOT NULL,
+					CONSTRAINT uni_roles_name UNIQUE (name)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_roles_deleted_at ON roles (deleted_at)`,
+				`CREATE TABLE IF NOT EXISTS permissions (
+					id BIGSERIAL PRIMARY KEY,
+					name TEXT NOT NULL,
+					CONSTRAINT uni_permissions_name UNIQUE (name)
+				)`,
+				`CREATE TABLE IF NOT EXISTS user_roles (
+					user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					role_id BIGINT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+					created_at TIMESTAMPTZ,
+					updated_at TIMESTAMPTZ,
+					deleted_at TIMESTAMPTZ,
+					PRIMARY KEY (user_id, role_id)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_user_roles_deleted_at ON user_roles (deleted_at)`,
+				`CREATE TABLE IF NOT EXISTS role_permissions (
+					role_id BIGINT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+					permission_id BIGINT NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+					PRIMARY KEY (role_id, permission_id)
+				)`,
+				`CREATE TABLE IF NOT EXISTS posts (
+					id BIGSERIAL PRIMARY KEY,
+					title TEXT NOT NULL,
+					content TEXT NOT NULL,
+					type TEXT NOT NULL,
+					lang TEXT NOT NULL,
+					image_url TEXT,
+					user_id BIGINT NOT NULL REFERENCES users (id),
+					deleted_at TIMESTAMPTZ
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at)`,
+				`CREATE INDEX IF NOT EXISTS idx_posts_lang_type ON posts (lang, type)`,
+				`CREATE TABLE IF NOT EXISTS post_likes (
+					user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+					created_at TIMESTAMPTZ,
+					deleted_at TIMESTAMPTZ,
+					PRIMARY KEY (user_id, post_id)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_post_likes_deleted_at ON post_likes (deleted_at)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS post_likes`,
+				`DROP TABLE IF EXISTS posts`,
+				`DROP TABLE IF EXISTS role_permissions`,
+				`DROP TABLE IF EXISTS user_roles`,
+				`DROP TABLE IF EXISTS permissions`,
+				`DROP TABLE IF EXISTS roles`,
+				`DROP TABLE IF EXISTS users`,
+			)
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "post_timestamps",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()`,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS updated_at`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS created_at`,
+			)
+		},
+	})
+}
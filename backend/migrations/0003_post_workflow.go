@@ -0,0 +1,44 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Posts that existed before the workflow were already public, so they are
+// backfilled as published; new rows default to draft.
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "post_workflow",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'`,
+				`ALTER TABLE posts ALTER COLUMN status SET DEFAULT 'draft'`,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ`,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ`,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS status_changed_by BIGINT REFERENCES users (id) ON DELETE SET NULL`,
+				`UPDATE posts SET published_at = created_at WHERE status = 'published' AND published_at IS NULL`,
+				`CREATE INDEX IF NOT EXISTS idx_posts_status ON posts (status)`,
+				`CREATE TABLE IF NOT EXISTS post_transitions (
+					id BIGSERIAL PRIMARY KEY,
+					post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+					from_status TEXT NOT NULL,
+					to_status TEXT NOT NULL,
+					transition TEXT NOT NULL,
+					actor_id BIGINT REFERENCES users (id) ON DELETE SET NULL,
+					note TEXT,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_post_transitions_post_id ON post_transitions (post_id)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS post_transitions`,
+				`DROP INDEX IF EXISTS idx_posts_status`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS status_changed_by`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS status_changed_at`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS published_at`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS status`,
+			)
+		},
+	})
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "post_schedule",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ`,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ`,
+				`CREATE INDEX IF NOT EXISTS idx_posts_scheduled_publish ON posts (publish_at) WHERE status = 'scheduled'`,
+				`CREATE INDEX IF NOT EXISTS idx_posts_published_unpublish ON posts (unpublish_at) WHERE status = 'published' AND unpublish_at IS NOT NULL`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_posts_published_unpublish`,
+				`DROP INDEX IF EXISTS idx_posts_scheduled_publish`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS unpublish_at`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS publish_at`,
+			)
+		},
+	})
+}
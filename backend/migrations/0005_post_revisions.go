@@ -0,0 +1,34 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "post_revisions",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_revisions (
+					id BIGSERIAL PRIMARY KEY,
+					post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+					revision INTEGER NOT NULL,
+					title TEXT NOT NULL,
+					content TEXT NOT NULL,
+					type TEXT NOT NULL,
+					lang TEXT NOT NULL,
+					image_url TEXT,
+					author_id BIGINT REFERENCES users (id) ON DELETE SET NULL,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					CONSTRAINT uni_post_revisions_post_revision UNIQUE (post_id, revision)
+				)`,
+				// Give every existing post a first revision so it can be diffed and rolled back to
+				`INSERT INTO post_revisions (post_id, revision, title, content, type, lang, image_url, author_id, created_at)
+				SELECT id, 1, title, content, type, lang, image_url, user_id, updated_at FROM posts
+				ON CONFLICT DO NOTHING`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, `DROP TABLE IF EXISTS post_revisions`)
+		},
+	})
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "post_version",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, `ALTER TABLE posts DROP COLUMN IF EXISTS version`)
+		},
+	})
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "taxonomy",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS categories (
+					id BIGSERIAL PRIMARY KEY,
+					parent_id BIGINT REFERENCES categories (id) ON DELETE RESTRICT,
+					slug TEXT NOT NULL,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					CONSTRAINT uni_categories_slug UNIQUE (slug)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id)`,
+				`CREATE TABLE IF NOT EXISTS category_names (
+					category_id BIGINT NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+					lang TEXT NOT NULL,
+					name TEXT NOT NULL,
+					PRIMARY KEY (category_id, lang)
+				)`,
+				`CREATE TABLE IF NOT EXISTS tags (
+					id BIGSERIAL PRIMARY KEY,
+					name TEXT NOT NULL,
+					slug TEXT NOT NULL,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					CONSTRAINT uni_tags_slug UNIQUE (slug)
+				)`,
+				`CREATE TABLE IF NOT EXISTS post_categories (
+					post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+					category_id BIGINT NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+					PRIMARY KEY (post_id, category_id)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_post_categories_category_id ON post_categories (category_id)`,
+				`CREATE TABLE IF NOT EXISTS post_tags (
+					post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+					tag_id BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+					PRIMARY KEY (post_id, tag_id)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_post_tags_tag_id ON post_tags (tag_id)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS post_tags`,
+				`DROP TABLE IF EXISTS post_categories`,
+				`DROP TABLE IF EXISTS tags`,
+				`DROP TABLE IF EXISTS category_names`,
+				`DROP TABLE IF EXISTS categories`,
+			)
+		},
+	})
+}
@@ -0,0 +1,131 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "post_slugs",
+		Up: func(tx *gorm.DB) error {
+			if err := execAll(tx,
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS slug TEXT`,
+				`CREATE TABLE IF NOT EXISTS post_slug_redirects (
+					lang TEXT NOT NULL,
+					slug TEXT NOT NULL,
+					post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					PRIMARY KEY (lang, slug)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_post_slug_redirects_post_id ON post_slug_redirects (post_id)`,
+			); err != nil {
+				return err
+			}
+			if err := backfillPostSlugs(tx); err != nil {
+				return err
+			}
+			return execAll(tx,
+				`ALTER TABLE posts ALTER COLUMN slug SET NOT NULL`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_lang_slug ON posts (lang, slug)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS post_slug_redirects`,
+				`DROP INDEX IF EXISTS idx_posts_lang_slug`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS slug`,
+			)
+		},
+	})
+}
+
+// backfillPostSlugs derives a slug from the title of every post that has none,
+// oldest first, suffixing the post ID when another post in the same language has it.
+func backfillPostSlugs(tx *gorm.DB) error {
+	var posts []struct {
+		ID    uint
+		Lang  string
+		Title string
+		Slug  *string
+	}
+	if err := tx.Raw(`SELECT id, lang, title, slug FROM posts ORDER BY id`).Scan(&posts).Error; err != nil {
+		return err
+	}
+
+	taken := make(map[string]bool, len(posts))
+	for _, p := range posts {
+		if p.Slug != nil {
+			taken[p.Lang+"/"+*p.Slug] = true
+		}
+	}
+	for _, p := range posts {
+		if p.Slug != nil {
+			continue
+		}
+		slug := slugify0008(p.Title)
+		if slug == "" || taken[p.Lang+"/"+slug] {
+			slug = fmt.Sprintf("%s-%d", slug, p.ID)
+			if slug[0] == '-' {
+				slug = "post" + slug
+			}
+		}
+		taken[p.Lang+"/"+slug] = true
+		if err := tx.Exec(`UPDATE posts SET slug = ? WHERE id = ?`, slug, p.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slugify0008 is utils.Slugify as it stood when this migration was written, frozen
+// so that changing how new slugs are made can't change what the backfill produced.
+func slugify0008(s string) string {
+	s = strings.NewReplacer(
+		"ي", "ی", "ى", "ی", "ك", "ک",
+		"۰", "0", "۱", "1", "۲", "2", "۳", "3", "۴", "4",
+		"۵", "5", "۶", "6", "۷", "7", "۸", "8", "۹", "9",
+		"٠", "0", "١", "1", "٢", "2", "٣", "3", "٤", "4",
+		"٥", "5", "٦", "6", "٧", "7", "٨", "8", "٩", "9",
+	).Replace(s)
+	s = strings.ToLower(strings.Map(func(r rune) rune {
+		if (r >= '\u064b' && r <= '\u065f') || r == '\u0670' || r == '\u0640' {
+			return -1
+		}
+		return r
+	}, s))
+
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		pendingHyphen = true
+	}
+
+	const maxLength = 80
+	runes := []rune(b.String())
+	if len(runes) <= maxLength {
+		return string(runes)
+	}
+	runes = runes[:maxLength]
+	for i := len(runes) - 1; i > maxLength/2; i-- {
+		if runes[i] == '-' {
+			return string(runes[:i])
+		}
+	}
+	return strings.TrimRight(string(runes), "-")
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "post_search",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// fa_normalize mirrors utils.NormalizeSearchText: Arabic yeh/alef maksura/kaf become
+				// Persian yeh/kaf, ZWNJ becomes a space, Persian and Arabic-Indic digits become ASCII,
+				// and harakat, superscript alef and tatweel are dropped (translate deletes
+				// characters that have no counterpart in the target string).
+				`CREATE OR REPLACE FUNCTION fa_normalize(input TEXT) RETURNS TEXT
+				LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+					SELECT translate(COALESCE(input, ''),
+						U&'\064A\0649\0643\200C\06F0\06F1\06F2\06F3\06F4\06F5\06F6\06F7\06F8\06F9\0660\0661\0662\0663\0664\0665\0666\0667\0668\0669\064B\064C\064D\064E\064F\0650\0651\0652\0653\0654\0655\0656\0657\0658\0659\065A\065B\065C\065D\065E\065F\0670\0640',
+						U&'\06CC\06CC\06A9 01234567890123456789')
+				$$`,
+				// English posts are stemmed; Persian has no stemmer, so it is normalized and indexed as-is.
+				// The default parser skips HTML tags, so the sanitized content can be indexed directly.
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+					CASE WHEN lang = 'en' THEN
+						setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+						setweight(to_tsvector('english', COALESCE(content, '')), 'B')
+					ELSE
+						setweight(to_tsvector('simple', fa_normalize(title)), 'A') ||
+						setweight(to_tsvector('simple', fa_normalize(content)), 'B')
+					END
+				) STORED`,
+				`CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_posts_search_vector`,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS search_vector`,
+				`DROP FUNCTION IF EXISTS fa_normalize(TEXT)`,
+			)
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "comments",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS comments (
+					id BIGSERIAL PRIMARY KEY,
+					post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+					parent_id BIGINT REFERENCES comments (id) ON DELETE CASCADE,
+					user_id BIGINT NOT NULL REFERENCES users (id),
+					body TEXT NOT NULL,
+					status TEXT NOT NULL DEFAULT 'pending',
+					moderated_by BIGINT REFERENCES users (id) ON DELETE SET NULL,
+					moderated_at TIMESTAMPTZ,
+					moderation_note TEXT NOT NULL DEFAULT '',
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					deleted_at TIMESTAMPTZ
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_comments_post_id_status ON comments (post_id, status)`,
+				`CREATE INDEX IF NOT EXISTS idx_comments_status_created_at ON comments (status, created_at)`,
+				`CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id)`,
+				`CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at)`,
+				// Approved comment count, kept in step by the comment repository
+				`ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_count INTEGER NOT NULL DEFAULT 0`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE posts DROP COLUMN IF EXISTS comments_count`,
+				`DROP TABLE IF EXISTS comments`,
+			)
+		},
+	})
+}
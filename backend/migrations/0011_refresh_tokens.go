@@ -0,0 +1,31 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "refresh_tokens",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// Only a SHA-256 of each token is stored. Every rotation adds a row to the
+				// family started at login; used_at marks tokens that were already exchanged.
+				`CREATE TABLE IF NOT EXISTS refresh_tokens (
+					id BIGSERIAL PRIMARY KEY,
+					user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					family_id TEXT NOT NULL,
+					token_hash TEXT NOT NULL UNIQUE,
+					expires_at TIMESTAMPTZ NOT NULL,
+					used_at TIMESTAMPTZ,
+					revoked_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id)`,
+				`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, `DROP TABLE IF EXISTS refresh_tokens`)
+		},
+	})
+}
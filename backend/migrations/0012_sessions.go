@@ -0,0 +1,42 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "sessions",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// A session is one login on one device. Its ID is the refresh token family
+				// and the sid claim of every access token issued to it.
+				`CREATE TABLE IF NOT EXISTS sessions (
+					id TEXT PRIMARY KEY,
+					user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					user_agent TEXT NOT NULL DEFAULT '',
+					ip_address TEXT NOT NULL DEFAULT '',
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					expires_at TIMESTAMPTZ NOT NULL,
+					revoked_at TIMESTAMPTZ
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id)`,
+				// Families created before sessions were recorded
+				`INSERT INTO sessions (id, user_id, created_at, last_used_at, expires_at, revoked_at)
+					SELECT family_id, MIN(user_id), MIN(created_at), MAX(created_at), MAX(expires_at),
+						CASE WHEN BOOL_AND(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+					FROM refresh_tokens
+					GROUP BY family_id
+					ON CONFLICT (id) DO NOTHING`,
+				`ALTER TABLE refresh_tokens ADD CONSTRAINT fk_refresh_tokens_session
+					FOREIGN KEY (family_id) REFERENCES sessions (id) ON DELETE CASCADE`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_session`,
+				`DROP TABLE IF EXISTS sessions`,
+			)
+		},
+	})
+}
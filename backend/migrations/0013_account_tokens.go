@@ -0,0 +1,37 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "account_tokens",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS email TEXT`,
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email)`,
+				// Single-use tokens for password reset and email verification, stored as SHA-256
+				`CREATE TABLE IF NOT EXISTS account_tokens (
+					id BIGSERIAL PRIMARY KEY,
+					user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					purpose TEXT NOT NULL,
+					token_hash TEXT NOT NULL UNIQUE,
+					email TEXT NOT NULL DEFAULT '',
+					expires_at TIMESTAMPTZ NOT NULL,
+					used_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id_purpose ON account_tokens (user_id, purpose)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS account_tokens`,
+				`DROP INDEX IF EXISTS idx_users_email`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS email`,
+			)
+		},
+	})
+}
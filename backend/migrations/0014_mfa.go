@@ -0,0 +1,38 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 14,
+		Name:    "mfa",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// secret is AES-GCM encrypted; enabled_at stays NULL until the first code
+				// is confirmed. last_step is the newest time step used, to stop replays.
+				`CREATE TABLE IF NOT EXISTS user_mfa (
+					user_id BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+					secret TEXT NOT NULL,
+					enabled_at TIMESTAMPTZ,
+					last_step BIGINT NOT NULL DEFAULT 0,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				)`,
+				`CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+					id BIGSERIAL PRIMARY KEY,
+					user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					code_hash TEXT NOT NULL,
+					used_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS mfa_recovery_codes`,
+				`DROP TABLE IF EXISTS user_mfa`,
+			)
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 15,
+		Name:    "api_keys",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// Only the SHA-256 of a key is stored; prefix is its first characters in
+				// clear, so users can tell their keys apart.
+				`CREATE TABLE IF NOT EXISTS api_keys (
+					id BIGSERIAL PRIMARY KEY,
+					user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					name TEXT NOT NULL,
+					prefix TEXT NOT NULL,
+					key_hash TEXT NOT NULL,
+					expires_at TIMESTAMPTZ,
+					last_used_at TIMESTAMPTZ,
+					revoked_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					CONSTRAINT uni_api_keys_key_hash UNIQUE (key_hash)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id)`,
+				`CREATE TABLE IF NOT EXISTS api_key_scopes (
+					api_key_id BIGINT NOT NULL REFERENCES api_keys (id) ON DELETE CASCADE,
+					permission_id BIGINT NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+					PRIMARY KEY (api_key_id, permission_id)
+				)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS api_key_scopes`,
+				`DROP TABLE IF EXISTS api_keys`,
+			)
+		},
+	})
+}
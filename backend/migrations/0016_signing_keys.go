@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 16,
+		Name:    "signing_keys",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// id is the kid. A key signs until retires_at and verifies until
+				// expires_at; private_key is PEM encrypted with AES-GCM.
+				`CREATE TABLE IF NOT EXISTS signing_keys (
+					id TEXT PRIMARY KEY,
+					algorithm TEXT NOT NULL,
+					private_key TEXT NOT NULL,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					retires_at TIMESTAMPTZ NOT NULL,
+					expires_at TIMESTAMPTZ NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_signing_keys_expires_at ON signing_keys (expires_at)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, `DROP TABLE IF EXISTS signing_keys`)
+		},
+	})
+}
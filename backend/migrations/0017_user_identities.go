@@ -0,0 +1,30 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 17,
+		Name:    "user_identities",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// subject is the provider's stable id for the account; email is what the
+				// provider reported when the identity was linked, for display only.
+				`CREATE TABLE IF NOT EXISTS user_identities (
+					id BIGSERIAL PRIMARY KEY,
+					user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					provider TEXT NOT NULL,
+					subject TEXT NOT NULL,
+					email TEXT,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					last_login_at TIMESTAMPTZ,
+					CONSTRAINT uni_user_identities_provider_subject UNIQUE (provider, subject)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, `DROP TABLE IF EXISTS user_identities`)
+		},
+	})
+}
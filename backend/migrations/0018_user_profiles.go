@@ -0,0 +1,29 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 18,
+		Name:    "user_profiles",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT ''`,
+				// empty means the reader hasn't chosen and the frontend picks from the browser
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS social_links JSONB NOT NULL DEFAULT '{}'`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE users DROP COLUMN IF EXISTS social_links`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS locale`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS avatar_url`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS bio`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS display_name`,
+			)
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 19,
+		Name:    "user_suspension",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// A suspension without suspended_until is a ban. Expired ones are left in
+				// place until the next suspension or an admin lifts them.
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ`,
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMPTZ`,
+				`ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT NOT NULL DEFAULT ''`,
+				`CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_users_created_at`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS suspension_reason`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS suspended_until`,
+				`ALTER TABLE users DROP COLUMN IF EXISTS suspended_at`,
+			)
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 20,
+		Name:    "audit_log",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// Each entry's hash covers its fields and the previous entry's hash, so an
+				// edited, removed or reordered entry breaks the chain from that point on.
+				// actor_id and target_id carry no foreign keys: the log outlives what it
+				// describes.
+				`CREATE TABLE IF NOT EXISTS audit_log (
+					id BIGSERIAL PRIMARY KEY,
+					created_at TIMESTAMPTZ NOT NULL,
+					actor_id BIGINT,
+					action TEXT NOT NULL,
+					target_type TEXT NOT NULL DEFAULT '',
+					target_id BIGINT,
+					before JSONB,
+					after JSONB,
+					details JSONB,
+					ip_address TEXT NOT NULL DEFAULT '',
+					request_id TEXT NOT NULL DEFAULT '',
+					prev_hash TEXT NOT NULL,
+					hash TEXT NOT NULL,
+					CONSTRAINT uni_audit_log_hash UNIQUE (hash)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at)`,
+				`CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log (actor_id)`,
+				`CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target_type, target_id)`,
+				`CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action)`,
+				// Updates and deletes fail outright, whichever code path attempts them
+				`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+				BEGIN
+					RAISE EXCEPTION 'audit_log is append-only';
+				END;
+				$$ LANGUAGE plpgsql`,
+				`DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log`,
+				`CREATE TRIGGER audit_log_no_update BEFORE UPDATE OR DELETE ON audit_log
+					FOR EACH ROW EXECUTE FUNCTION audit_log_append_only()`,
+				`DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log`,
+				`CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+					FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only()`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS audit_log`,
+				`DROP FUNCTION IF EXISTS audit_log_append_only()`,
+			)
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 21,
+		Name:    "drop_upload_permission",
+		Up: func(tx *gorm.DB) error {
+			// Nothing checks delete_any_upload: uploads have no recorded owner, so
+			// there is no upload deletion to authorize. Grants go with it.
+			return execAll(tx,
+				`DELETE FROM permissions WHERE name = 'delete_any_upload'`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`INSERT INTO permissions (name) VALUES ('delete_any_upload') ON CONFLICT (name) DO NOTHING`,
+			)
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 22,
+		Name:    "comment_roots",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// Replies point at the top-level comment of their thread, so a page of
+				// threads can be loaded without walking the whole post
+				`ALTER TABLE comments ADD COLUMN IF NOT EXISTS root_id BIGINT REFERENCES comments (id) ON DELETE CASCADE`,
+				`WITH RECURSIVE thread AS (
+					SELECT id, id AS root_id FROM comments WHERE parent_id IS NULL
+					UNION ALL
+					SELECT c.id, thread.root_id FROM comments c JOIN thread ON c.parent_id = thread.id
+				)
+				UPDATE comments SET root_id = thread.root_id
+				FROM thread
+				WHERE comments.id = thread.id AND comments.parent_id IS NOT NULL`,
+				`CREATE INDEX IF NOT EXISTS idx_comments_root_id ON comments (root_id)`,
+				`CREATE INDEX IF NOT EXISTS idx_comments_post_roots ON comments (post_id, created_at, id) WHERE parent_id IS NULL`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_comments_post_roots`,
+				`DROP INDEX IF EXISTS idx_comments_root_id`,
+				`ALTER TABLE comments DROP COLUMN IF EXISTS root_id`,
+			)
+		},
+	})
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 23,
+		Name:    "signing_key_not_before",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				// A new key is published a rotation interval before it signs, so every
+				// replica has loaded it by the time tokens carry its kid. Existing keys
+				// have been signing since they were created.
+				`ALTER TABLE signing_keys ADD COLUMN IF NOT EXISTS not_before TIMESTAMPTZ`,
+				`UPDATE signing_keys SET not_before = created_at WHERE not_before IS NULL`,
+				`ALTER TABLE signing_keys ALTER COLUMN not_before SET NOT NULL`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, `ALTER TABLE signing_keys DROP COLUMN IF EXISTS not_before`)
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 24,
+		Name:    "drop_purge_permission",
+		Up: func(tx *gorm.DB) error {
+			// Purging is gated by delete_any_post and the admin role; a separate
+			// purge_post permission could hand it to anyone. Grants go with it.
+			return execAll(tx,
+				`DELETE FROM permissions WHERE name = 'purge_post'`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`INSERT INTO permissions (name) VALUES ('purge_post') ON CONFLICT (name) DO NOTHING`,
+			)
+		},
+	})
+}
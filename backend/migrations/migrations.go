@@ -1,101 +1,188 @@
-package migrations
-
-import (
-	"fmt"
-
-	"github.com/alimosavifard/zyros-backend/models"
-	"github.com/alimosavifard/zyros-backend/utils"
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
-)
-
-// RunMigrations executes database migrations and seeds initial data.
-func RunMigrations(db *gorm.DB) error {
-	// Drop all tables
-	if err := db.Migrator().DropTable(
-		&models.User{},
-		&models.Role{},
-		&models.Permission{},
-		&models.UserRole{},
-		&models.RolePermission{},
-		&models.Post{},
-	); err != nil {
-		return fmt.Errorf("failed to drop tables: %w", err)
-	}
-	utils.InitLogger().Info().Msg("All tables dropped successfully")
-
-	// AutoMigrate tables
-	if err := db.AutoMigrate(
-		&models.User{},
-		&models.Role{},
-		&models.Permission{},
-		&models.UserRole{},
-		&models.RolePermission{},
-		&models.Post{},
-	); err != nil {
-		return fmt.Errorf("failed to migrate tables: %w", err)
-	}
-	utils.InitLogger().Info().Msg("Database tables migrated successfully")
-
-	// Seed admin user
-	admin := &models.User{
-		Username: "admin",
-		Password: hashPassword("admin123"),
-	}
-	if err := db.Create(admin).Error; err != nil {
-		return fmt.Errorf("failed to seed admin user: %w", err)
-	}
-	utils.InitLogger().Info().Msg("Admin user seeded successfully")
-
-	// Seed roles and permissions
-	if err := seedRolesAndPermissions(db, admin); err != nil {
-		return fmt.Errorf("failed to seed roles and permissions: %w", err)
-	}
-
-	return nil
-}
-
-// seedRolesAndPermissions seeds roles, permissions, and their relationships.
-func seedRolesAndPermissions(db *gorm.DB, admin *models.User) error {
-	// Seed permissions
-	createPostPerm := &models.Permission{Name: "create_post"}
-	if err := db.Where("name = ?", createPostPerm.Name).FirstOrCreate(createPostPerm).Error; err != nil {
-		utils.InitLogger().Error().Err(err).Msg("Failed to seed create_post permission")
-		return fmt.Errorf("failed to seed create_post permission: %w", err)
-	}
-
-	// Seed roles
-	userRole := &models.Role{Name: "user"}
-	if err := db.Where("name = ?", userRole.Name).FirstOrCreate(userRole).Error; err != nil {
-		return fmt.Errorf("failed to seed user role: %w", err)
-	}
-
-	adminRole := &models.Role{Name: "admin"}
-	if err := db.Where("name = ?", adminRole.Name).FirstOrCreate(adminRole).Error; err != nil {
-		return fmt.Errorf("failed to seed admin role: %w", err)
-	}
-
-	// Assign permissions to roles
-	if err := db.Model(userRole).Association("Permissions").Append(createPostPerm); err != nil {
-		return fmt.Errorf("failed to assign create_post permission to user role: %w", err)
-	}
-	if err := db.Model(adminRole).Association("Permissions").Append(createPostPerm); err != nil {
-		return fmt.Errorf("failed to assign create_post permission to admin role: %w", err)
-	}
-
-	// Assign roles to admin user
-	if err := db.Model(admin).Association("Roles").Append([]*models.Role{userRole, adminRole}); err != nil {
-		return fmt.Errorf("failed to assign roles to admin user: %w", err)
-	}
-
-	return nil
-}
-
-// hashPassword hashes a password using bcrypt.
-func hashPassword(password string) string {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		utils.InitLogger().Error().Err(err).Msg("Failed to hash password")
-	}
-	return string(hashed)
-}
\ No newline at end of file
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+	"gorm.io/gorm"
+)
+
+// advisoryLockKey is the Postgres advisory lock key held while migrations run,
+// so replicas booting at the same time don't apply the same version twice.
+const advisoryLockKey int64 = 7263548190
+
+// Migration is a single numbered, reversible schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is a row of the schema_migrations bookkeeping table.
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"not null" json:"name"`
+	AppliedAt time.Time `gorm:"not null" json:"applied_at"`
+}
+
+// MigrationStatus describes whether a registered migration has been applied.
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+var registry []Migration
+
+// register adds a migration to the registry. Each migration file calls it from init.
+func register(m Migration) {
+	for _, existing := range registry {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("duplicate migration version %d", m.Version))
+		}
+	}
+	registry = append(registry, m)
+}
+
+// All returns the registered migrations ordered by version.
+func All() []Migration {
+	sorted := make([]Migration, len(registry))
+	copy(sorted, registry)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
+
+// Up applies every pending migration in version order.
+func Up(db *gorm.DB) error {
+	return withLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range All() {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			}); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			utils.InitLogger().Info().Msgf("Applied migration %d_%s", m.Version, m.Name)
+		}
+		return nil
+	})
+}
+
+// Down reverts the last n applied migrations, newest first.
+func Down(db *gorm.DB, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("number of migrations to revert must be positive, got %d", n)
+	}
+
+	return withLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		migrations := All()
+		for i := len(migrations) - 1; i >= 0 && n > 0; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+			}); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			utils.InitLogger().Info().Msgf("Reverted migration %d_%s", m.Version, m.Name)
+			n--
+		}
+		return nil
+	})
+}
+
+// Status reports every registered migration and whether it has been applied.
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	if err := ensureSchemaTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range All() {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// withLock runs fn on a single pooled connection holding the migration advisory lock.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) (err error) {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", advisoryLockKey).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer func() {
+			if unlockErr := conn.Exec("SELECT pg_advisory_unlock(?)", advisoryLockKey).Error; unlockErr != nil && err == nil {
+				err = fmt.Errorf("failed to release migration lock: %w", unlockErr)
+			}
+		}()
+
+		if err := ensureSchemaTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func ensureSchemaTable(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`).Error
+}
+
+func appliedVersions(db *gorm.DB) (map[int64]SchemaMigration, error) {
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied := make(map[int64]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// execAll runs each statement in order, stopping at the first error.
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Seed creates the default roles, permissions and admin user. It is idempotent
+// and never overwrites an existing admin account, so it can be re-run safely.
+func Seed(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		admin, err := seedAdminUser(tx)
+		if err != nil {
+			return fmt.Errorf("failed to seed admin user: %w", err)
+		}
+
+		if err := seedRolesAndPermissions(tx, admin); err != nil {
+			return fmt.Errorf("failed to seed roles and permissions: %w", err)
+		}
+
+		utils.InitLogger().Info().Msg("Seed data applied successfully")
+		return nil
+	})
+}
+
+// seedAdminUser returns the admin user, creating it with the default password if missing.
+func seedAdminUser(db *gorm.DB) (*models.User, error) {
+	var admin models.User
+	err := db.Where("username = ?", "admin").First(&admin).Error
+	if err == nil {
+		return &admin, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	admin = models.User{
+		Username: "admin",
+		Password: hashPassword("admin123"),
+	}
+	if err := db.Create(&admin).Error; err != nil {
+		return nil, err
+	}
+	utils.InitLogger().Info().Msg("Admin user seeded successfully")
+	return &admin, nil
+}
+
+// defaultRolePermissions lists the permissions each seeded role is granted. Every
+// name must be in policy.Permissions.
+var defaultRolePermissions = map[string][]string{
+	"user":   {"create_post", "edit_post", "delete_post", "submit_for_review", "create_article", "upload_image", "like_post", "unlike_post", "comment_post"},
+	"author": {"create_post", "edit_post", "delete_post", "submit_for_review", "create_article", "upload_image", "like_post", "unlike_post", "comment_post"},
+	"editor": {"create_post", "edit_post", "delete_post", "submit_for_review", "create_article", "upload_image", "like_post", "unlike_post", "edit_any_post", "delete_any_post", "approve_post", "publish_post", "manage_taxonomy", "comment_post", "moderate_comments"},
+	"admin":  {"create_post", "edit_post", "delete_post", "submit_for_review", "create_article", "upload_image", "like_post", "unlike_post", "edit_any_post", "delete_any_post", "approve_post", "publish_post", "manage_taxonomy", "comment_post", "moderate_comments", "manage_users"},
+}
+
+// seedRolesAndPermissions seeds roles, permissions, and their relationships.
+func seedRolesAndPermissions(db *gorm.DB, admin *models.User) error {
+	// Registered permissions exist even when no role is granted them
+	for _, registered := range policy.Permissions {
+		perm := &models.Permission{Name: registered.Name}
+		if err := db.Where("name = ?", perm.Name).FirstOrCreate(perm).Error; err != nil {
+			return fmt.Errorf("failed to seed %s permission: %w", perm.Name, err)
+		}
+	}
+
+	roles := make(map[string]*models.Role, len(defaultRolePermissions))
+	for roleName, permNames := range defaultRolePermissions {
+		// Seed role
+		role := &models.Role{Name: roleName}
+		if err := db.Where("name = ?", role.Name).FirstOrCreate(role).Error; err != nil {
+			return fmt.Errorf("failed to seed %s role: %w", roleName, err)
+		}
+		roles[roleName] = role
+
+		// Seed permissions and assign them (join rows are inserted with ON CONFLICT DO NOTHING)
+		for _, permName := range permNames {
+			perm := &models.Permission{Name: permName}
+			if err := db.Where("name = ?", perm.Name).FirstOrCreate(perm).Error; err != nil {
+				utils.InitLogger().Error().Err(err).Msgf("Failed to seed %s permission", permName)
+				return fmt.Errorf("failed to seed %s permission: %w", permName, err)
+			}
+			if err := db.Model(role).Association("Permissions").Append(perm); err != nil {
+				return fmt.Errorf("failed to assign %s permission to %s role: %w", permName, roleName, err)
+			}
+		}
+	}
+
+	// Assign roles to admin user
+	if err := db.Model(admin).Association("Roles").Append([]*models.Role{roles["user"], roles["admin"]}); err != nil {
+		return fmt.Errorf("failed to assign roles to admin user: %w", err)
+	}
+
+	return nil
+}
+
+// hashPassword hashes a password using bcrypt.
+func hashPassword(password string) string {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		utils.InitLogger().Error().Err(err).Msg("Failed to hash password")
+	}
+	return string(hashed)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"gorm.io/gorm"
 	"time"
 )
@@ -9,9 +10,25 @@ import (
 // User represents a user entity in the system.
 type User struct {
 	gorm.Model
-	Username string `gorm:"unique;not null" json:"username"`
-	Password string `gorm:"not null" json:"password"`
-	Roles    []Role `gorm:"many2many:user_roles;" json:"roles"`
+	Username        string            `gorm:"unique;not null" json:"username"`
+	Password        string            `gorm:"not null" json:"-"`             // هش bcrypt؛ هرگز در پاسخ‌ها نمی‌آید
+	Email           *string           `gorm:"unique" json:"email,omitempty"` // اختیاری، با حروف کوچک ذخیره می‌شود
+	EmailVerifiedAt *time.Time        `json:"email_verified_at,omitempty"`
+	DisplayName     string            `gorm:"not null;default:''" json:"display_name"`
+	Bio             string            `gorm:"not null;default:''" json:"bio"` // متن ساده
+	AvatarURL       string            `gorm:"not null;default:''" json:"avatar_url"`
+	Locale          string            `gorm:"not null;default:''" json:"locale"` // fa، en یا خالی
+	SocialLinks     map[string]string `gorm:"serializer:json;type:jsonb;default:'{}'" json:"social_links"`
+	Roles           []Role            `gorm:"many2many:user_roles;" json:"roles"`
+	// SuspendedAt is set while the account is suspended; without SuspendedUntil it is a ban
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `gorm:"not null;default:''" json:"suspension_reason,omitempty"`
+}
+
+// IsSuspended reports whether the user is suspended or banned at now.
+func (u *User) IsSuspended(now time.Time) bool {
+	return u.SuspendedAt != nil && (u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil))
 }
 
 
@@ -46,16 +63,39 @@ type RolePermission struct {
 }
 
 
+// Editorial workflow states of a post.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusInReview  = "in_review"
+	PostStatusApproved  = "approved"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
 type Post struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Title     string         `gorm:"not null" json:"title"`
-	Content   string         `gorm:"not null" json:"content"`
-	Type      string         `gorm:"not null" json:"type"` // "post" or "article"
-	Lang      string         `gorm:"not null" json:"lang"` // "fa" or "en"
-	ImageUrl  string         `gorm:"type:text" json:"imageUrl"` // اختیاری
-	UserID    uint           `gorm:"not null" json:"user_id"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
-	User      User           `json:"user,omitempty"` // برای preload
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	Title           string         `gorm:"not null" json:"title"`
+	Slug            string         `gorm:"not null" json:"slug"` // یکتا در هر زبان
+	Content         string         `gorm:"not null" json:"content"`
+	Type            string         `gorm:"not null" json:"type"`      // "post" or "article"
+	Lang            string         `gorm:"not null" json:"lang"`      // "fa" or "en"
+	ImageUrl        string         `gorm:"type:text" json:"imageUrl"` // اختیاری
+	UserID          uint           `gorm:"not null" json:"user_id"`
+	Version         uint           `gorm:"not null;default:1" json:"version"`        // با هر ویرایش یا تغییر وضعیت یکی زیاد می‌شود
+	CommentsCount   int64          `gorm:"not null;default:0" json:"comments_count"` // فقط دیدگاه‌های تأییدشده
+	Status          string         `gorm:"not null;default:draft;index" json:"status"`
+	PublishedAt     *time.Time     `json:"published_at,omitempty"`
+	PublishAt       *time.Time     `json:"publish_at,omitempty"`   // زمان انتشار زمان‌بندی‌شده
+	UnpublishAt     *time.Time     `json:"unpublish_at,omitempty"` // زمان اختیاری خروج از انتشار
+	StatusChangedAt *time.Time     `json:"status_changed_at,omitempty"`
+	StatusChangedBy *uint          `json:"status_changed_by,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	User            User           `json:"user,omitempty"` // برای preload
+	Categories      []Category     `gorm:"many2many:post_categories" json:"categories,omitempty"`
+	Tags            []Tag          `gorm:"many2many:post_tags" json:"tags,omitempty"`
 }
 
 // PostLike به عنوان جدول واسط برای لایک‌ها (حذف Like، فقط این نگه داشته شود)
@@ -66,4 +106,223 @@ type PostLike struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 	User      User           `gorm:"foreignKey:UserID"`
 	Post      Post           `gorm:"foreignKey:PostID"`
-}
\ No newline at end of file
+}
+
+// PostTransition records a single workflow state change of a post.
+type PostTransition struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PostID     uint      `gorm:"not null;index" json:"post_id"`
+	FromStatus string    `gorm:"not null" json:"from_status"`
+	ToStatus   string    `gorm:"not null" json:"to_status"`
+	Transition string    `gorm:"not null" json:"transition"`
+	ActorID    *uint     `json:"actor_id"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Actor      *User     `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+}
+
+// PostRevision is a snapshot of a post's editable fields taken on every save.
+type PostRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PostID    uint      `gorm:"not null;uniqueIndex:uni_post_revisions_post_revision" json:"post_id"`
+	Revision  int       `gorm:"not null;uniqueIndex:uni_post_revisions_post_revision" json:"revision"`
+	Title     string    `gorm:"not null" json:"title"`
+	Content   string    `gorm:"not null" json:"content,omitempty"`
+	Type      string    `gorm:"not null" json:"type"`
+	Lang      string    `gorm:"not null" json:"lang"`
+	ImageUrl  string    `gorm:"type:text" json:"imageUrl"`
+	AuthorID  *uint     `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Author    *User     `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+}
+
+// Category is a node in the category tree. Its display name is stored per language.
+type Category struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ParentID  *uint          `gorm:"index" json:"parent_id"`
+	Slug      string         `gorm:"not null;uniqueIndex:uni_categories_slug" json:"slug"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Names     []CategoryName `gorm:"foreignKey:CategoryID" json:"names,omitempty"`
+}
+
+// CategoryName is a category's name in one language ("fa" or "en").
+type CategoryName struct {
+	CategoryID uint   `gorm:"primaryKey" json:"-"`
+	Lang       string `gorm:"primaryKey" json:"lang"`
+	Name       string `gorm:"not null" json:"name"`
+}
+
+// Tag is a free-form label. Slug is the normalized name, so spelling variants share one tag.
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Slug      string    `gorm:"not null;uniqueIndex:uni_tags_slug" json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PostSlugRedirect remembers a slug a post used to have so old URLs keep working.
+type PostSlugRedirect struct {
+	Lang      string    `gorm:"primaryKey" json:"lang"`
+	Slug      string    `gorm:"primaryKey" json:"slug"`
+	PostID    uint      `gorm:"not null;index" json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Comment moderation states.
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusSpam     = "spam"
+	CommentStatusRejected = "rejected"
+)
+
+// Comment is a reader's response to a post. Replies point at their parent comment.
+type Comment struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	PostID         uint           `gorm:"not null;index" json:"post_id"`
+	ParentID       *uint          `gorm:"index" json:"parent_id"`
+	RootID         *uint          `gorm:"index" json:"root_id,omitempty"` // top-level comment of a reply's thread
+	UserID         uint           `gorm:"not null" json:"user_id"`
+	Body           string         `gorm:"not null" json:"body"`
+	Status         string         `gorm:"not null;default:pending" json:"status"`
+	ModeratedBy    *uint          `json:"moderated_by,omitempty"`
+	ModeratedAt    *time.Time     `json:"moderated_at,omitempty"`
+	ModerationNote string         `gorm:"not null;default:''" json:"moderation_note,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	User           User           `json:"-"`
+	Post           *Post          `json:"-"`
+}
+
+// RefreshToken is one link in a rotating refresh token chain. All tokens issued from
+// the same login share a FamilyID; only their SHA-256 is stored.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID  string     `gorm:"not null;index" json:"family_id"`
+	TokenHash string     `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Session is one login on one device. Its ID doubles as the refresh token family and
+// the sid claim of the access tokens issued to it.
+type Session struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	UserAgent  string     `gorm:"not null;default:''" json:"user_agent"`
+	IPAddress  string     `gorm:"not null;default:''" json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Purposes of an AccountToken.
+const (
+	AccountTokenPasswordReset     = "password_reset"
+	AccountTokenEmailVerification = "email_verification"
+)
+
+// AccountToken is a single-use, time-limited token mailed to a user. Only its SHA-256
+// is stored. Email is the address a verification token confirms.
+type AccountToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	Purpose   string     `gorm:"not null" json:"purpose"`
+	TokenHash string     `gorm:"not null;uniqueIndex" json:"-"`
+	Email     string     `gorm:"not null;default:''" json:"email"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// UserMFA holds a user's TOTP enrollment. The secret is encrypted at rest and the
+// enrollment only counts once EnabledAt is set.
+type UserMFA struct {
+	UserID    uint       `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Secret    string     `gorm:"not null" json:"-"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+	LastStep  int64      `gorm:"not null;default:0" json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// MFARecoveryCode is a single-use fallback for a lost authenticator, stored as SHA-256.
+type MFARecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// APIKey lets a script call the API as its user without a browser session. Only the
+// key's SHA-256 is stored. A key can do no more than its Scopes, and no more than
+// its user's roles currently allow.
+type APIKey struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	UserID     uint         `gorm:"not null;index" json:"user_id"`
+	Name       string       `gorm:"not null" json:"name"`
+	Prefix     string       `gorm:"not null" json:"prefix"`
+	KeyHash    string       `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes     []Permission `gorm:"many2many:api_key_scopes;" json:"-"`
+	ExpiresAt  *time.Time   `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time   `json:"-"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// SigningKey is a stored access token signing key; ID is its kid. PrivateKey is
+// PKCS #8 PEM, encrypted at rest. It signs from NotBefore until RetiresAt.
+type SigningKey struct {
+	ID         string    `gorm:"primaryKey" json:"kid"`
+	Algorithm  string    `gorm:"not null" json:"algorithm"`
+	PrivateKey string    `gorm:"not null" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	NotBefore  time.Time `gorm:"not null" json:"not_before"`
+	RetiresAt  time.Time `gorm:"not null" json:"retires_at"`
+	ExpiresAt  time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// UserIdentity links a user to their account at an external identity provider.
+// Subject is the provider's id for that account, unique per provider.
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Provider    string     `gorm:"not null" json:"provider"`
+	Subject     string     `gorm:"not null" json:"-"`
+	Email       *string    `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// AuditLogEntry is one entry of the append-only audit log. Hash chains it to the
+// entry before, so tampering shows up when the chain is verified.
+type AuditLogEntry struct {
+	ID         uint64          `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ActorID    *uint           `json:"actor_id,omitempty"` // nil for anonymous requests and the system
+	Action     string          `gorm:"not null" json:"action"`
+	TargetType string          `json:"target_type,omitempty"`
+	TargetID   *uint           `json:"target_id,omitempty"`
+	Before     json.RawMessage `gorm:"type:jsonb" json:"before,omitempty"`
+	After      json.RawMessage `gorm:"type:jsonb" json:"after,omitempty"`
+	Details    json.RawMessage `gorm:"type:jsonb" json:"details,omitempty"`
+	IPAddress  string          `json:"ip_address,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	PrevHash   string          `gorm:"not null" json:"prev_hash"`
+	Hash       string          `gorm:"not null" json:"hash"`
+}
+
+func (AuditLogEntry) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,292 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockIdP is a minimal identity provider: discovery, a key set, and a token endpoint
+// that redeems the codes handed out by authorize.
+type mockIdP struct {
+	server *httptest.Server
+	clock  *utils.FakeClock
+
+	mu    sync.Mutex
+	kid   string
+	key   *rsa.PrivateKey
+	codes map[string]url.Values
+	// jwksFetches counts key set requests
+	jwksFetches int
+}
+
+const (
+	testClientID     = "zyros"
+	testClientSecret = "s3cret+/"
+)
+
+func newMockIdP(t *testing.T, clock *utils.FakeClock) *mockIdP {
+	idp := &mockIdP{clock: clock, codes: map[string]url.Values{}}
+	idp.rotate(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize?prompt=login",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		idp.jwksFetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": idp.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// rotate replaces the signing key with a new one under a new kid.
+func (idp *mockIdP) rotate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.key = key
+	idp.kid, _ = utils.RandomToken(8)
+}
+
+// authorize stands in for the user logging in at the provider and returns the code
+// the provider would redirect back with.
+func (idp *mockIdP) authorize(t *testing.T, authURL string) string {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := utils.RandomToken(16)
+	idp.mu.Lock()
+	idp.codes[code] = parsed.Query()
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(reason string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": reason})
+	}
+	id, secret, _ := r.BasicAuth()
+	if id != url.QueryEscape(testClientID) || secret != url.QueryEscape(testClientSecret) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+	r.ParseForm()
+
+	idp.mu.Lock()
+	auth, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	switch {
+	case !ok:
+		fail("unknown code")
+		return
+	case r.PostForm.Get("redirect_uri") != auth.Get("redirect_uri"):
+		fail("redirect_uri mismatch")
+		return
+	case Challenge(r.PostForm.Get("code_verifier")) != auth.Get("code_challenge"):
+		fail("PKCE verification failed")
+		return
+	}
+
+	idToken := idp.sign(jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            auth.Get("client_id"),
+		"sub":            "user-42",
+		"email":          "Reader@Example.com",
+		"email_verified": true,
+		"nonce":          auth.Get("nonce"),
+		"iat":            idp.clock.Now().Unix(),
+		"exp":            idp.clock.Now().Add(5 * time.Minute).Unix(),
+	})
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+}
+
+func (idp *mockIdP) sign(claims jwt.MapClaims) string {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	signed, _ := token.SignedString(idp.key)
+	return signed
+}
+
+func newTestProvider(idp *mockIdP) *Provider {
+	return NewProvider(Config{
+		Issuer:       idp.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  "https://api.example.com/api/v1/auth/oidc/mock/callback",
+		Scopes:       []string{"email", "profile"},
+	}, idp.server.Client(), idp.clock)
+}
+
+func TestLoginFlow(t *testing.T) {
+	clock := utils.NewFakeClock(time.Now())
+	idp := newMockIdP(t, clock)
+	provider := newTestProvider(idp)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "the-state", "the-nonce", Challenge("the-verifier"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	query, _ := url.ParseQuery(authURL[strings.Index(authURL, "?")+1:])
+	for param, want := range map[string]string{
+		"prompt":                "login",
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"scope":                 "openid email profile",
+		"state":                 "the-state",
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(param); got != want {
+			t.Errorf("%s = %q, want %q", param, got, want)
+		}
+	}
+
+	claims, err := provider.Exchange(ctx, idp.authorize(t, authURL), "the-verifier", "the-nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "user-42" || claims.Email != "Reader@Example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	if _, err := provider.Exchange(ctx, idp.authorize(t, authURL), "another-verifier", "the-nonce"); err == nil {
+		t.Error("code redeemed with the wrong PKCE verifier")
+	}
+	if _, err := provider.Exchange(ctx, idp.authorize(t, authURL), "the-verifier", "another-nonce"); err == nil {
+		t.Error("ID token accepted with the wrong nonce")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	clock := utils.NewFakeClock(time.Now())
+	idp := newMockIdP(t, clock)
+	provider := newTestProvider(idp)
+	now := clock.Now()
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"aud":   testClientID,
+			"sub":   "user-42",
+			"nonce": "n",
+			"iat":   now.Unix(),
+			"exp":   now.Add(5 * time.Minute).Unix(),
+		}
+	}
+	with := func(changes map[string]interface{}) string {
+		claims := valid()
+		for k, v := range changes {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		return idp.sign(claims)
+	}
+	hs256, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte(testClientSecret))
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", with(nil), true},
+		{"several audiences with azp", with(map[string]interface{}{"aud": []string{testClientID, "other"}, "azp": testClientID}), true},
+		{"several audiences without azp", with(map[string]interface{}{"aud": []string{testClientID, "other"}}), false},
+		{"another audience", with(map[string]interface{}{"aud": "other"}), false},
+		{"another issuer", with(map[string]interface{}{"iss": "https://evil.example.com"}), false},
+		{"expired", with(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}), false},
+		{"no expiry", with(map[string]interface{}{"exp": nil}), false},
+		{"issued in the future", with(map[string]interface{}{"iat": now.Add(time.Hour).Unix()}), false},
+		{"no nonce", with(map[string]interface{}{"nonce": nil}), false},
+		{"no subject", with(map[string]interface{}{"sub": nil}), false},
+		{"signed with the client secret", hs256, false},
+		{"tampered", with(nil) + "x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(context.Background(), tt.token, "n")
+			if (err == nil) != tt.ok {
+				t.Errorf("VerifyIDToken error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	clock := utils.NewFakeClock(time.Now())
+	idp := newMockIdP(t, clock)
+	provider := newTestProvider(idp)
+	ctx := context.Background()
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{"iss": idp.server.URL, "aud": testClientID, "sub": "s", "nonce": "n", "exp": clock.Now().Add(time.Minute).Unix()}
+	}
+
+	if _, err := provider.VerifyIDToken(ctx, idp.sign(claims()), "n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A token under a kid that isn't known yet makes the provider refetch the keys,
+	// but not more than once a minute
+	idp.rotate(t)
+	if _, err := provider.VerifyIDToken(ctx, idp.sign(claims()), "n"); err == nil {
+		t.Error("keys refetched within a minute of the last fetch")
+	}
+	clock.Advance(2 * time.Minute)
+	if _, err := provider.VerifyIDToken(ctx, idp.sign(claims()), "n"); err != nil {
+		t.Errorf("token signed with the new key rejected: %v", err)
+	}
+	if idp.jwksFetches != 2 {
+		t.Errorf("key set fetched %d times, want 2", idp.jwksFetches)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newMockIdP(t, utils.NewFakeClock(time.Now()))
+	provider := NewProvider(Config{Issuer: idp.server.URL + "/", ClientID: testClientID}, idp.server.Client(), utils.SystemClock{})
+
+	if _, err := provider.AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Error("discovery accepted a document for a different issuer")
+	}
+}
@@ -0,0 +1,51 @@
+package policy
+
+// Permission is a permission the application checks somewhere.
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Permissions is the registry of every permission the routes and DefaultRules
+// check. Each is created at startup if missing, and PermissionMiddleware refuses a
+// name that isn't listed, so a route can't depend on a permission nobody can grant.
+var Permissions = []Permission{
+	{"create_post", "Write posts"},
+	{"edit_post", "Edit your own posts"},
+	{"delete_post", "Delete and restore your own posts"},
+	{"submit_for_review", "Submit your own posts for review"},
+	{"create_article", "Write articles"},
+	{"upload_image", "Upload images"},
+	{"like_post", "Like posts"},
+	{"unlike_post", "Remove your likes"},
+	{"comment_post", "Comment on posts and edit your own comments"},
+	{"edit_any_post", "Edit anyone's posts"},
+	{"delete_any_post", "Delete and restore anyone's posts"},
+	{"approve_post", "Review submitted posts"},
+	{"publish_post", "Publish and schedule posts"},
+	{"manage_taxonomy", "Manage categories and tags"},
+	{"moderate_comments", "Moderate comments"},
+	{"manage_users", "Manage users, roles and permissions"},
+}
+
+var registered = func() map[string]bool {
+	names := make(map[string]bool, len(Permissions))
+	for _, p := range Permissions {
+		names[p.Name] = true
+	}
+	return names
+}()
+
+// IsRegistered reports whether name is in Permissions.
+func IsRegistered(name string) bool {
+	return registered[name]
+}
+
+// PermissionNames returns the names in Permissions.
+func PermissionNames() []string {
+	names := make([]string, len(Permissions))
+	for i, p := range Permissions {
+		names[i] = p.Name
+	}
+	return names
+}
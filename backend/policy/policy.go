@@ -0,0 +1,282 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+// ResourceType names the kind of object an action targets.
+type ResourceType string
+
+const (
+	ResourcePost    ResourceType = "post"
+	ResourceComment ResourceType = "comment"
+)
+
+// Action is something a subject wants to do to a resource.
+type Action string
+
+const (
+	ActionPostUpdate  Action = "post.update"
+	ActionPostDelete  Action = "post.delete"
+	ActionPostRestore Action = "post.restore"
+	ActionPostPurge   Action = "post.purge"
+	ActionPostSubmit  Action = "post.submit"
+	ActionPostReview  Action = "post.review"
+	ActionPostPublish Action = "post.publish"
+	ActionPostHistory Action = "post.history"
+
+	ActionCommentUpdate   Action = "comment.update"
+	ActionCommentDelete   Action = "comment.delete"
+	ActionCommentModerate Action = "comment.moderate"
+)
+
+// Reason explains a decision in a machine-readable way.
+type Reason string
+
+const (
+	ReasonOwner             Reason = "owner"
+	ReasonAny               Reason = "any"
+	ReasonUnauthenticated   Reason = "unauthenticated"
+	ReasonUnknownAction     Reason = "unknown_action"
+	ReasonNotOwner          Reason = "not_owner"
+	ReasonMissingPermission Reason = "missing_permission"
+)
+
+// Rule pairs the permission needed to act on one's own resource with the one
+// needed to act on anybody's. AnyRole additionally grants "any" access to every
+// role at or above it in the hierarchy. Empty fields grant nothing.
+type Rule struct {
+	Own     string
+	Any     string
+	AnyRole string
+}
+
+// Hierarchy maps a role to the roles directly beneath it; a role inherits
+// everything granted to the roles it contains.
+type Hierarchy map[string][]string
+
+// Includes reports whether role is target or sits above it.
+func (h Hierarchy) Includes(role, target string) bool {
+	return h.includes(role, target, map[string]bool{})
+}
+
+func (h Hierarchy) includes(role, target string, seen map[string]bool) bool {
+	if role == target {
+		return true
+	}
+	if seen[role] {
+		return false
+	}
+	seen[role] = true
+	for _, child := range h[role] {
+		if h.includes(child, target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subject is the acting user with the roles and permissions they hold.
+type Subject struct {
+	UserID      uint
+	Roles       []string
+	Permissions map[string]bool
+}
+
+// NewSubject builds a subject from a user loaded with Roles.Permissions.
+func NewSubject(user *models.User) *Subject {
+	subject := &Subject{UserID: user.ID, Permissions: map[string]bool{}}
+	for _, role := range user.Roles {
+		subject.Roles = append(subject.Roles, role.Name)
+		for _, perm := range role.Permissions {
+			subject.Permissions[perm.Name] = true
+		}
+	}
+	return subject
+}
+
+// Can reports whether the subject holds the permission directly.
+func (s *Subject) Can(permission string) bool {
+	return permission != "" && s.Permissions[permission]
+}
+
+// Restrict narrows the subject to the scopes it also holds, as for a request made
+// with a scoped API key. Roles are dropped too, so the hierarchy can't grant
+// anything the scopes leave out.
+func (s *Subject) Restrict(scopes []string) {
+	permissions := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if s.Can(scope) {
+			permissions[scope] = true
+		}
+	}
+	s.Permissions = permissions
+	s.Roles = nil
+}
+
+// PrivilegedPermissions act on other users' content or on the site itself. Holding
+// any of them makes an account worth taking over, so two-factor login can be
+// enforced for it.
+var PrivilegedPermissions = []string{
+	"edit_any_post",
+	"delete_any_post",
+	"approve_post",
+	"publish_post",
+	"manage_taxonomy",
+	"moderate_comments",
+	"manage_users",
+}
+
+// IsPrivileged reports whether the subject holds any of PrivilegedPermissions.
+func (s *Subject) IsPrivileged() bool {
+	for _, permission := range PrivilegedPermissions {
+		if s.Can(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource identifies the object being acted on and who owns it.
+type Resource struct {
+	Type    ResourceType
+	ID      uint
+	OwnerID uint
+}
+
+// PostResource describes a post for authorization.
+func PostResource(post *models.Post) Resource {
+	return Resource{Type: ResourcePost, ID: post.ID, OwnerID: post.UserID}
+}
+
+// CommentResource describes a comment for authorization.
+func CommentResource(comment *models.Comment) Resource {
+	return Resource{Type: ResourceComment, ID: comment.ID, OwnerID: comment.UserID}
+}
+
+// Decision is the outcome of an authorization check.
+type Decision struct {
+	Allowed    bool   `json:"allowed"`
+	Action     Action `json:"action"`
+	Reason     Reason `json:"reason"`
+	Permission string `json:"permission,omitempty"`
+	Message    string `json:"message"`
+}
+
+// Err returns nil for an allowed decision and a *DenyError otherwise.
+func (d Decision) Err() error {
+	if d.Allowed {
+		return nil
+	}
+	return &DenyError{Decision: d}
+}
+
+// DenyError carries a denied decision. It matches utils.ErrForbidden with errors.Is.
+type DenyError struct {
+	Decision Decision
+}
+
+func (e *DenyError) Error() string {
+	return e.Decision.Message
+}
+
+func (e *DenyError) Unwrap() error {
+	return utils.ErrForbidden
+}
+
+// DefaultHierarchy orders the built-in roles: admin > editor > author > user.
+var DefaultHierarchy = Hierarchy{
+	"admin":  {"editor"},
+	"editor": {"author"},
+	"author": {"user"},
+}
+
+// DefaultRules are the own/any pairs enforced for each action.
+var DefaultRules = map[Action]Rule{
+	ActionPostUpdate:  {Own: "edit_post", Any: "edit_any_post", AnyRole: "editor"},
+	ActionPostDelete:  {Own: "delete_post", Any: "delete_any_post", AnyRole: "editor"},
+	ActionPostRestore: {Own: "delete_post", Any: "delete_any_post", AnyRole: "editor"},
+	// Purging can't be undone, so it is left to admins rather than a permission
+	ActionPostPurge:   {AnyRole: "admin"},
+	ActionPostSubmit:  {Own: "submit_for_review", Any: "approve_post", AnyRole: "editor"},
+	ActionPostReview:  {Any: "approve_post", AnyRole: "editor"},
+	ActionPostPublish: {Any: "publish_post", AnyRole: "editor"},
+	ActionPostHistory: {Own: "submit_for_review", Any: "approve_post", AnyRole: "editor"},
+
+	ActionCommentUpdate:   {Own: "comment_post"},
+	ActionCommentDelete:   {Own: "comment_post", Any: "moderate_comments", AnyRole: "editor"},
+	ActionCommentModerate: {Any: "moderate_comments", AnyRole: "editor"},
+}
+
+// Policy evaluates rules against subjects and resources.
+type Policy struct {
+	rules     map[Action]Rule
+	hierarchy Hierarchy
+}
+
+func NewPolicy(rules map[Action]Rule, hierarchy Hierarchy) *Policy {
+	return &Policy{rules: rules, hierarchy: hierarchy}
+}
+
+// Authorize decides whether subject may perform action on resource.
+func (p *Policy) Authorize(subject *Subject, action Action, resource Resource) Decision {
+	if subject == nil || subject.UserID == 0 {
+		return deny(action, ReasonUnauthenticated, "", "authentication required")
+	}
+
+	rule, ok := p.rules[action]
+	if !ok {
+		return deny(action, ReasonUnknownAction, "", fmt.Sprintf("no policy defined for %s", action))
+	}
+
+	if p.grantsAny(subject, rule) {
+		return Decision{Allowed: true, Action: action, Reason: ReasonAny, Permission: rule.Any}
+	}
+
+	isOwner := resource.OwnerID != 0 && resource.OwnerID == subject.UserID
+	if isOwner && subject.Can(rule.Own) {
+		return Decision{Allowed: true, Action: action, Reason: ReasonOwner, Permission: rule.Own}
+	}
+
+	if !isOwner && subject.Can(rule.Own) {
+		return deny(action, ReasonNotOwner, rule.Any,
+			fmt.Sprintf("%s %d belongs to another user", resource.Type, resource.ID))
+	}
+
+	missing := rule.Own
+	if !isOwner || missing == "" {
+		missing = rule.Any
+	}
+	return deny(action, ReasonMissingPermission, missing, fmt.Sprintf("missing permission to %s", action))
+}
+
+// CanAny reports whether subject may perform action on resources owned by anyone.
+func (p *Policy) CanAny(subject *Subject, action Action) bool {
+	if subject == nil {
+		return false
+	}
+	rule, ok := p.rules[action]
+	return ok && p.grantsAny(subject, rule)
+}
+
+func (p *Policy) grantsAny(subject *Subject, rule Rule) bool {
+	if subject.Can(rule.Any) {
+		return true
+	}
+	if rule.AnyRole == "" {
+		return false
+	}
+	for _, role := range subject.Roles {
+		if p.hierarchy.Includes(role, rule.AnyRole) {
+			return true
+		}
+	}
+	return false
+}
+
+func deny(action Action, reason Reason, permission, message string) Decision {
+	return Decision{Allowed: false, Action: action, Reason: reason, Permission: permission, Message: message}
+}
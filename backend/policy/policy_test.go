@@ -0,0 +1,204 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+func subject(userID uint, roles []string, perms ...string) *Subject {
+	s := &Subject{UserID: userID, Roles: roles, Permissions: map[string]bool{}}
+	for _, p := range perms {
+		s.Permissions[p] = true
+	}
+	return s
+}
+
+func TestAuthorize(t *testing.T) {
+	p := NewPolicy(DefaultRules, DefaultHierarchy)
+	ownPost := Resource{Type: ResourcePost, ID: 1, OwnerID: 10}
+	otherPost := Resource{Type: ResourcePost, ID: 2, OwnerID: 20}
+
+	tests := []struct {
+		name       string
+		subject    *Subject
+		action     Action
+		resource   Resource
+		allowed    bool
+		reason     Reason
+		permission string
+	}{
+		{"nil subject", nil, ActionPostUpdate, ownPost, false, ReasonUnauthenticated, ""},
+		{"anonymous subject", subject(0, nil), ActionPostUpdate, ownPost, false, ReasonUnauthenticated, ""},
+		{"unknown action", subject(10, []string{"user"}, "edit_post"), Action("post.fly"), ownPost, false, ReasonUnknownAction, ""},
+		{"author edits own post", subject(10, []string{"author"}, "edit_post"), ActionPostUpdate, ownPost, true, ReasonOwner, "edit_post"},
+		{"author edits other post", subject(10, []string{"author"}, "edit_post"), ActionPostUpdate, otherPost, false, ReasonNotOwner, "edit_any_post"},
+		{"owner without own permission", subject(10, []string{"user"}), ActionPostUpdate, ownPost, false, ReasonMissingPermission, "edit_post"},
+		{"stranger without permissions", subject(10, []string{"user"}), ActionPostUpdate, otherPost, false, ReasonMissingPermission, "edit_any_post"},
+		{"any permission edits other post", subject(10, []string{"user"}, "edit_any_post"), ActionPostUpdate, otherPost, true, ReasonAny, "edit_any_post"},
+		{"editor role edits other post", subject(10, []string{"editor"}), ActionPostUpdate, otherPost, true, ReasonAny, "edit_any_post"},
+		{"admin inherits editor", subject(10, []string{"admin"}), ActionPostDelete, otherPost, true, ReasonAny, "delete_any_post"},
+		{"author role is below editor", subject(10, []string{"author"}, "delete_post"), ActionPostDelete, otherPost, false, ReasonNotOwner, "delete_any_post"},
+		{"author restores own post", subject(10, []string{"author"}, "delete_post"), ActionPostRestore, ownPost, true, ReasonOwner, "delete_post"},
+		{"editor cannot purge", subject(10, []string{"editor"}, "edit_any_post", "delete_any_post"), ActionPostPurge, otherPost, false, ReasonMissingPermission, ""},
+		{"owner cannot purge own post", subject(10, []string{"author"}, "delete_post"), ActionPostPurge, ownPost, false, ReasonMissingPermission, ""},
+		{"admin purges", subject(10, []string{"admin"}, "delete_any_post"), ActionPostPurge, otherPost, true, ReasonAny, ""},
+		{"author submits own draft", subject(10, []string{"author"}, "submit_for_review"), ActionPostSubmit, ownPost, true, ReasonOwner, "submit_for_review"},
+		{"author cannot approve own post", subject(10, []string{"author"}, "submit_for_review"), ActionPostReview, ownPost, false, ReasonMissingPermission, "approve_post"},
+		{"reviewer approves", subject(10, []string{"user"}, "approve_post"), ActionPostReview, otherPost, true, ReasonAny, "approve_post"},
+		{"reviewer without publish permission", subject(10, []string{"user"}, "approve_post"), ActionPostPublish, otherPost, false, ReasonMissingPermission, "publish_post"},
+		{"editor publishes", subject(10, []string{"editor"}), ActionPostPublish, otherPost, true, ReasonAny, "publish_post"},
+		{"commenter edits own comment", subject(10, []string{"user"}, "comment_post"), ActionCommentUpdate, Resource{Type: ResourceComment, ID: 5, OwnerID: 10}, true, ReasonOwner, "comment_post"},
+		{"moderator cannot edit another user's comment", subject(10, []string{"editor"}, "comment_post", "moderate_comments"), ActionCommentUpdate, Resource{Type: ResourceComment, ID: 5, OwnerID: 20}, false, ReasonNotOwner, ""},
+		{"moderator deletes any comment", subject(10, []string{"user"}, "moderate_comments"), ActionCommentDelete, Resource{Type: ResourceComment, ID: 5, OwnerID: 20}, true, ReasonAny, "moderate_comments"},
+		{"commenter cannot moderate own comment", subject(10, []string{"user"}, "comment_post"), ActionCommentModerate, Resource{Type: ResourceComment, ID: 5, OwnerID: 10}, false, ReasonMissingPermission, "moderate_comments"},
+		{"ownerless resource is never own", subject(10, []string{"author"}, "edit_post"), ActionPostUpdate, Resource{Type: ResourcePost, ID: 4}, false, ReasonNotOwner, "edit_any_post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := p.Authorize(tt.subject, tt.action, tt.resource)
+			if d.Allowed != tt.allowed {
+				t.Fatalf("Allowed = %v, want %v (decision %+v)", d.Allowed, tt.allowed, d)
+			}
+			if d.Reason != tt.reason {
+				t.Errorf("Reason = %q, want %q", d.Reason, tt.reason)
+			}
+			if d.Permission != tt.permission {
+				t.Errorf("Permission = %q, want %q", d.Permission, tt.permission)
+			}
+			if d.Action != tt.action {
+				t.Errorf("Action = %q, want %q", d.Action, tt.action)
+			}
+		})
+	}
+}
+
+func TestHierarchyIncludes(t *testing.T) {
+	h := Hierarchy{
+		"admin":  {"editor"},
+		"editor": {"author"},
+		"author": {"user"},
+		"loop-a": {"loop-b"},
+		"loop-b": {"loop-a"},
+	}
+
+	tests := []struct {
+		role, target string
+		want         bool
+	}{
+		{"admin", "admin", true},
+		{"admin", "editor", true},
+		{"admin", "user", true},
+		{"editor", "author", true},
+		{"editor", "admin", false},
+		{"user", "author", false},
+		{"guest", "user", false},
+		{"loop-a", "user", false},
+		{"loop-a", "loop-b", true},
+	}
+
+	for _, tt := range tests {
+		if got := h.Includes(tt.role, tt.target); got != tt.want {
+			t.Errorf("Includes(%q, %q) = %v, want %v", tt.role, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCanAny(t *testing.T) {
+	p := NewPolicy(DefaultRules, DefaultHierarchy)
+
+	tests := []struct {
+		name    string
+		subject *Subject
+		action  Action
+		want    bool
+	}{
+		{"nil subject", nil, ActionPostRestore, false},
+		{"author", subject(10, []string{"author"}, "delete_post"), ActionPostRestore, false},
+		{"editor role", subject(10, []string{"editor"}), ActionPostRestore, true},
+		{"any permission", subject(10, []string{"user"}, "delete_any_post"), ActionPostRestore, true},
+		{"unknown action", subject(10, []string{"admin"}), Action("post.fly"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.CanAny(tt.subject, tt.action); got != tt.want {
+				t.Errorf("CanAny = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecisionErr(t *testing.T) {
+	if err := (Decision{Allowed: true}).Err(); err != nil {
+		t.Fatalf("allowed decision returned error %v", err)
+	}
+
+	err := Decision{Allowed: false, Reason: ReasonNotOwner, Message: "post 2 belongs to another user"}.Err()
+	if !errors.Is(err, utils.ErrForbidden) {
+		t.Errorf("deny error does not match utils.ErrForbidden: %v", err)
+	}
+	var denied *DenyError
+	if !errors.As(err, &denied) || denied.Decision.Reason != ReasonNotOwner {
+		t.Errorf("deny error does not carry the decision: %v", err)
+	}
+}
+
+func TestIsPrivileged(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject *Subject
+		want    bool
+	}{
+		{"plain user", subject(10, []string{"user"}, "create_post", "like_post", "comment_post"), false},
+		{"author", subject(10, []string{"author"}, "edit_post", "delete_post", "submit_for_review"), false},
+		{"moderator", subject(10, []string{"user"}, "comment_post", "moderate_comments"), true},
+		{"admin", subject(10, []string{"admin"}, "manage_users"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.subject.IsPrivileged(); got != tt.want {
+				t.Errorf("IsPrivileged = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	p := NewPolicy(DefaultRules, DefaultHierarchy)
+	otherPost := Resource{Type: ResourcePost, ID: 2, OwnerID: 20}
+
+	editor := subject(10, []string{"editor"}, "create_post", "edit_post", "edit_any_post")
+	editor.Restrict([]string{"create_post", "publish_post"})
+
+	if !editor.Can("create_post") {
+		t.Error("restricted subject lost a scope it holds")
+	}
+	if editor.Can("publish_post") {
+		t.Error("restricted subject gained a scope it doesn't hold")
+	}
+	if d := p.Authorize(editor, ActionPostUpdate, otherPost); d.Allowed {
+		t.Errorf("restricted subject still edits through its role: %+v", d)
+	}
+}
+
+func TestRulesUseRegisteredPermissions(t *testing.T) {
+	for action, rule := range DefaultRules {
+		for _, name := range []string{rule.Own, rule.Any} {
+			if name != "" && !IsRegistered(name) {
+				t.Errorf("%s checks unregistered permission %q", action, name)
+			}
+		}
+	}
+	for _, name := range PrivilegedPermissions {
+		if !IsRegistered(name) {
+			t.Errorf("privileged permission %q is not registered", name)
+		}
+	}
+	if IsRegistered("no_such_permission") {
+		t.Error("unknown permission reported as registered")
+	}
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AccountTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountTokenRepository(db *gorm.DB) *AccountTokenRepository {
+	return &AccountTokenRepository{db: db}
+}
+
+// Replace stores a new token and drops the user's earlier unused ones with the same
+// purpose, so only the most recently mailed link works.
+func (r *AccountTokenRepository) Replace(ctx context.Context, token *models.AccountToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Delete(&models.AccountToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// Consume marks a live token as used and returns it. It fails with
+// gorm.ErrRecordNotFound when the token is unknown, used, expired or meant for
+// another purpose.
+func (r *AccountTokenRepository) Consume(ctx context.Context, purpose, hash string, now time.Time) (*models.AccountToken, error) {
+	var tokens []models.AccountToken
+	result := r.db.WithContext(ctx).Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(tokens) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &tokens[0], nil
+}
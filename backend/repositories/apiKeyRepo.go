@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+)
+
+// apiKeyTouchInterval limits last_used_at writes to one per key per minute, however
+// busy the script using it is.
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create stores a key together with its scopes, which must be existing permissions.
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+// ListActive returns a user's keys that are neither revoked nor expired, newest first.
+func (r *APIKeyRepository) ListActive(ctx context.Context, userID uint, now time.Time) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.WithContext(ctx).Preload("Scopes").
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+// FindActiveByHash finds a usable key with its scopes. Keys of deleted or suspended
+// users are not usable.
+func (r *APIKeyRepository) FindActiveByHash(ctx context.Context, hash string, now time.Time) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Preload("Scopes").
+		Where("key_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", hash, now).
+		Where("user_id IN (SELECT users.id FROM users WHERE users.deleted_at IS NULL AND NOT ("+suspendedSQL+"))", now).
+		First(&key).Error
+	return &key, err
+}
+
+// Touch records that a key was used, at most once per apiKeyTouchInterval.
+func (r *APIKeyRepository) Touch(ctx context.Context, id uint, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-apiKeyTouchInterval)).
+		Update("last_used_at", now).Error
+}
+
+// RevokeAll disables every key the user has.
+func (r *APIKeyRepository) RevokeAll(ctx context.Context, userID uint, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// Revoke disables one of a user's keys, reporting whether there was one to revoke.
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID, id uint, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", now)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+)
+
+// auditLockKey is the advisory lock that serializes appends, so replicas writing at
+// once still extend a single chain.
+const auditLockKey = 0x7a79726f73 // "zyros"
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Append adds entries to the end of the log. seal is given the hash of the entry
+// before each one and must set its PrevHash and Hash.
+func (r *AuditRepository) Append(ctx context.Context, entries []models.AuditLogEntry, seal func(prevHash string, entry *models.AuditLogEntry)) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditLockKey).Error; err != nil {
+			return err
+		}
+		var last []string
+		if err := tx.Model(&models.AuditLogEntry{}).Order("id DESC").Limit(1).Pluck("hash", &last).Error; err != nil {
+			return err
+		}
+		prevHash := ""
+		if len(last) > 0 {
+			prevHash = last[0]
+		}
+		for i := range entries {
+			seal(prevHash, &entries[i])
+			prevHash = entries[i].Hash
+		}
+		return tx.Create(&entries).Error
+	})
+}
+
+// AuditQuery filters the audit log. Empty fields are ignored.
+type AuditQuery struct {
+	ActorID    uint
+	Action     string // an action, or a prefix ending in "." such as "post."
+	TargetType string
+	TargetID   uint
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+func (q AuditQuery) apply(query *gorm.DB) *gorm.DB {
+	if q.ActorID != 0 {
+		query = query.Where("actor_id = ?", q.ActorID)
+	}
+	if strings.HasSuffix(q.Action, ".") {
+		query = query.Where("action LIKE ?", escapeLike(q.Action)+"%")
+	} else if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if q.TargetType != "" {
+		query = query.Where("target_type = ?", q.TargetType)
+	}
+	if q.TargetID != 0 {
+		query = query.Where("target_id = ?", q.TargetID)
+	}
+	if q.RequestID != "" {
+		query = query.Where("request_id = ?", q.RequestID)
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("created_at < ?", *q.To)
+	}
+	return query
+}
+
+// Query lists matching entries newest first, and counts every match.
+func (r *AuditRepository) Query(ctx context.Context, q AuditQuery, page, limit int) ([]models.AuditLogEntry, int64, error) {
+	query := q.apply(r.db.WithContext(ctx).Model(&models.AuditLogEntry{}))
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []models.AuditLogEntry
+	err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&entries).Error
+	return entries, total, err
+}
+
+// Each passes matching entries to fn in log order, a batch at a time, so the whole
+// log never has to fit in memory.
+func (r *AuditRepository) Each(ctx context.Context, q AuditQuery, batchSize int, fn func([]models.AuditLogEntry) error) error {
+	var batch []models.AuditLogEntry
+	return q.apply(r.db.WithContext(ctx)).Order("id").FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
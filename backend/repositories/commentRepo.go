@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+)
+
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create inserts a comment and refreshes its post's approved comment count.
+func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("User", "Post").Create(comment).Error; err != nil {
+			return err
+		}
+		return recountCommentsWithTx(tx, comment.PostID)
+	})
+}
+
+// FindByID finds a live comment with its author.
+func (r *CommentRepository) FindByID(ctx context.Context, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.db.WithContext(ctx).Preload("User").First(&comment, id).Error
+	return &comment, err
+}
+
+// ListRootsForPost returns a page of a post's top-level comments oldest first, with
+// the number of them. Only threads with something viewerID may read are counted:
+// the top-level comment itself or any reply in it, live and either approved or
+// written by viewerID. The others would show as nothing but tombstones.
+func (r *CommentRepository) ListRootsForPost(ctx context.Context, postID, viewerID uint, page, limit int) ([]models.Comment, int64, error) {
+	query := r.db.WithContext(ctx).Unscoped().Model(&models.Comment{}).
+		Where("post_id = ? AND parent_id IS NULL", postID).
+		Where(`(deleted_at IS NULL AND (status = ? OR user_id = ?)) OR EXISTS (
+			SELECT 1 FROM comments replies
+			WHERE replies.root_id = comments.id AND replies.deleted_at IS NULL
+				AND (replies.status = ? OR replies.user_id = ?)
+		)`, models.CommentStatusApproved, viewerID, models.CommentStatusApproved, viewerID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var comments []models.Comment
+	err := query.
+		Preload("User").
+		Order("created_at, id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&comments).Error
+	return comments, total, err
+}
+
+// ListReplies returns every reply in the threads of the given top-level comments
+// oldest first, in any state and including deleted ones, so the caller can keep
+// their place in the thread when they have replies.
+func (r *CommentRepository) ListReplies(ctx context.Context, rootIDs []uint) ([]models.Comment, error) {
+	if len(rootIDs) == 0 {
+		return nil, nil
+	}
+	var comments []models.Comment
+	err := r.db.WithContext(ctx).Unscoped().
+		Preload("User").
+		Where("root_id IN ?", rootIDs).
+		Order("created_at, id").
+		Find(&comments).Error
+	return comments, err
+}
+
+// Update saves a comment's body and moderation state and refreshes its post's count.
+func (r *CommentRepository) Update(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(comment).
+			Select("body", "status", "moderated_by", "moderated_at", "moderation_note", "updated_at").
+			Updates(comment).Error; err != nil {
+			return err
+		}
+		return recountCommentsWithTx(tx, comment.PostID)
+	})
+}
+
+// Delete soft-deletes a comment and refreshes its post's count. Replies stay in place.
+func (r *CommentRepository) Delete(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(comment).Error; err != nil {
+			return err
+		}
+		return recountCommentsWithTx(tx, comment.PostID)
+	})
+}
+
+// ModerationQueue lists live comments in the given state oldest first, with their
+// author and post, along with the total number of such comments.
+func (r *CommentRepository) ModerationQueue(ctx context.Context, status string, page, limit int) ([]models.Comment, int64, error) {
+	var total int64
+	query := r.db.WithContext(ctx).Model(&models.Comment{}).Where("status = ?", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var comments []models.Comment
+	err := query.
+		Preload("User").
+		Preload("Post").
+		Order("created_at, id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&comments).Error
+	return comments, total, err
+}
+
+// recountCommentsWithTx stores the number of approved, live comments on a post. The
+// column is written directly so the post's updated_at and version stay untouched.
+func recountCommentsWithTx(tx *gorm.DB, postID uint) error {
+	return tx.Exec(`UPDATE posts SET comments_count = (
+			SELECT COUNT(*) FROM comments WHERE post_id = ? AND status = ? AND deleted_at IS NULL
+		) WHERE id = ?`, postID, models.CommentStatusApproved, postID).Error
+}
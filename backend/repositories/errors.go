@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is the SQLSTATE Postgres reports for a duplicate key.
+const uniqueViolation = "23505"
+
+// IsUniqueViolation reports whether err is Postgres refusing a duplicate key in the
+// named unique constraint or index, or in any of them when constraint is empty.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation &&
+		(constraint == "" || pgErr.ConstraintName == constraint)
+}
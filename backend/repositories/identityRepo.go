@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+)
+
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// Find returns the identity a provider knows by subject.
+func (r *IdentityRepository) Find(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return &identity, err
+}
+
+// CreateWithUser creates a user together with their first identity, so a failed
+// link doesn't leave an account nobody can log in to.
+func (r *IdentityRepository) CreateWithUser(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+// Touch records a login with the identity.
+func (r *IdentityRepository) Touch(ctx context.Context, id uint, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserIdentity{}).Where("id = ?", id).Update("last_login_at", now).Error
+}
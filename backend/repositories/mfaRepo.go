@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// Find returns a user's enrollment, pending or enabled.
+func (r *MFARepository) Find(ctx context.Context, userID uint) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&mfa).Error
+	return &mfa, err
+}
+
+// SavePending stores a new, not yet confirmed secret, replacing any earlier pending one.
+func (r *MFARepository) SavePending(ctx context.Context, mfa *models.UserMFA) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled_at", "last_step", "updated_at"}),
+	}).Create(mfa).Error
+}
+
+// Enable confirms an enrollment at the given step and replaces the recovery codes.
+func (r *MFARepository) Enable(ctx context.Context, userID uint, step int64, codes []models.MFARecoveryCode, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserMFA{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"enabled_at": now,
+			"last_step":  step,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodesWithTx(tx, userID, codes)
+	})
+}
+
+// UseStep records that the code of a time step was used. It reports false when that
+// step or a later one was already used, which makes each code single-use.
+func (r *MFARepository) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserMFA{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UseRecoveryCode spends an unused recovery code, reporting whether one matched.
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID uint, hash string, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left.
+func (r *MFARepository) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// ReplaceRecoveryCodes discards a user's recovery codes for a new set.
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.MFARecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodesWithTx(tx, userID, codes)
+	})
+}
+
+// Delete removes a user's enrollment and recovery codes.
+func (r *MFARepository) Delete(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserMFA{}).Error
+	})
+}
+
+func replaceRecoveryCodesWithTx(tx *gorm.DB, userID uint, codes []models.MFARecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	for i := range codes {
+		codes[i].UserID = userID
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
@@ -2,8 +2,13 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 
@@ -11,6 +16,23 @@ type PostRepository struct {
 	db *gorm.DB
 }
 
+// PostVisibility limits which workflow states a reader may see.
+type PostVisibility struct {
+	ViewerID uint // 0 for anonymous readers, who only see published posts
+	All      bool // reviewers see posts in every state
+}
+
+func (v PostVisibility) apply(query *gorm.DB) *gorm.DB {
+	switch {
+	case v.All:
+		return query
+	case v.ViewerID != 0:
+		return query.Where("(posts.status = ? OR posts.user_id = ?)", models.PostStatusPublished, v.ViewerID)
+	default:
+		return query.Where("posts.status = ?", models.PostStatusPublished)
+	}
+}
+
 // GetDB یک متد عمومی برای دسترسی به اتصال دیتابیس
 func (r *PostRepository) GetDB() *gorm.DB {
 	return r.db
@@ -24,24 +46,412 @@ func (r *PostRepository) Create(ctx context.Context, post *models.Post) error {
 	return r.db.WithContext(ctx).Create(post).Error
 }
 
+// CreateWithTx inserts a post and links it to its already existing categories and tags.
+// A redirect another post left on the same slug is dropped, as the slug is live again.
 func (r *PostRepository) CreateWithTx(tx *gorm.DB, post *models.Post) error {
-	return tx.Create(post).Error
+	if err := tx.Omit("Categories.*", "Tags.*").Create(post).Error; err != nil {
+		return slugError(err)
+	}
+	return tx.Where("lang = ? AND slug = ?", post.Lang, post.Slug).Delete(&models.PostSlugRedirect{}).Error
+}
+
+// slugError reports a write that lost a race for a slug as utils.ErrSlugTaken.
+func slugError(err error) error {
+	if IsUniqueViolation(err, "idx_posts_lang_slug") {
+		return utils.ErrSlugTaken
+	}
+	return err
+}
+
+// SlugsWithPrefix returns the slugs in lang, trashed posts included, that equal base or
+// extend it with a hyphenated suffix, ignoring the post exceptID.
+func (r *PostRepository) SlugsWithPrefix(ctx context.Context, lang, base string, exceptID uint) ([]string, error) {
+	var slugs []string
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Post{}).
+		Where("lang = ? AND (slug = ? OR slug LIKE ?) AND id <> ?", lang, base, escapeLike(base)+"-%", exceptID).
+		Pluck("slug", &slugs).Error
+	return slugs, err
+}
+
+// FindIDBySlug returns the ID of the live post with the given slug in lang.
+func (r *PostRepository) FindIDBySlug(ctx context.Context, lang, slug string) (uint, error) {
+	var post models.Post
+	err := r.db.WithContext(ctx).Select("id").Where("lang = ? AND slug = ?", lang, slug).First(&post).Error
+	return post.ID, err
 }
 
-func (r *PostRepository) GetByLang(ctx context.Context, lang string, postType string, page, limit int) ([]models.Post, error) {
+// FindSlugRedirect looks up a slug a post used to have.
+func (r *PostRepository) FindSlugRedirect(ctx context.Context, lang, slug string) (*models.PostSlugRedirect, error) {
+	var redirect models.PostSlugRedirect
+	err := r.db.WithContext(ctx).Where("lang = ? AND slug = ?", lang, slug).First(&redirect).Error
+	return &redirect, err
+}
+
+// PostFilter narrows a post listing. Empty fields are ignored.
+type PostFilter struct {
+	Lang     string
+	Type     string
+	Status   string
+	Category string // category slug; posts in its subcategories match too
+	Tag      string // tag slug
+	AuthorID uint
+}
+
+// GetByLang lists posts the reader may see. An empty status means published posts
+// plus the reader's own posts in any state.
+func (r *PostRepository) GetByLang(ctx context.Context, filter PostFilter, visibility PostVisibility, page, limit int) ([]models.Post, error) {
 	var posts []models.Post
 	offset := (page - 1) * limit
-	err := r.db.WithContext(ctx).
-		Where("lang = ? AND type = ? AND deleted_at IS NULL", lang, postType).
+	query := r.db.WithContext(ctx).
+		Where("lang = ? AND type = ? AND deleted_at IS NULL", filter.Lang, filter.Type)
+	if filter.Status != "" {
+		query = query.Where("posts.status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		query = query.Where("posts.id IN (SELECT post_id FROM post_categories WHERE category_id IN ("+categorySubtreeSQL+"))", filter.Category)
+	}
+	if filter.Tag != "" {
+		query = query.Where("posts.id IN (SELECT pt.post_id FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE t.slug = ?)", filter.Tag)
+	}
+	if filter.AuthorID != 0 {
+		query = query.Where("posts.user_id = ?", filter.AuthorID)
+	}
+	err := visibility.apply(query).
 		Preload("User"). // Preload User برای نمایش username در frontend
+		Preload("Categories.Names").
+		Preload("Tags").
+		Order("COALESCE(posts.published_at, posts.created_at) DESC").
 		Offset(offset).Limit(limit).Find(&posts).Error
 	return posts, err
 }
 
+// SearchQuery is a full-text search over posts. Empty Lang and Type match every language and type.
+type SearchQuery struct {
+	Text string
+	Lang string
+	Type string
+}
+
+// SearchHit is a matching post with its rank and a content excerpt whose matches
+// are wrapped in <mark>. The excerpt is plain text apart from those marks.
+type SearchHit struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug"`
+	Type        string     `json:"type"`
+	Lang        string     `json:"lang"`
+	ImageUrl    string     `json:"imageUrl,omitempty"`
+	UserID      uint       `json:"user_id"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Rank        float64    `json:"rank"`
+	Snippet     string     `json:"snippet"`
+}
+
+// searchConfigs pairs each language with the text search configuration its posts are indexed with.
+var searchConfigs = []struct{ lang, config string }{
+	{"en", "english"},
+	{"fa", "simple"},
+}
+
+// Search ranks the posts the reader may see against q. Persian text in q must already
+// be normalized the way fa_normalize normalizes indexed posts.
+func (r *PostRepository) Search(ctx context.Context, q SearchQuery, visibility PostVisibility, page, limit int) ([]SearchHit, int64, error) {
+	// One indexable predicate per language, as each is matched with its own configuration
+	var conditions []string
+	var args []interface{}
+	for _, sc := range searchConfigs {
+		if q.Lang != "" && q.Lang != sc.lang {
+			continue
+		}
+		conditions = append(conditions, "(posts.lang = ? AND posts.search_vector @@ websearch_to_tsquery('"+sc.config+"', ?))")
+		args = append(args, sc.lang, q.Text)
+	}
+	if len(conditions) == 0 {
+		return []SearchHit{}, 0, nil
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.Post{}).
+		Where(strings.Join(conditions, " OR "), args...)
+	if q.Type != "" {
+		query = query.Where("posts.type = ?", q.Type)
+	}
+	query = visibility.apply(query)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	const tsQuery = `websearch_to_tsquery(CASE WHEN posts.lang = 'en' THEN 'english' ELSE 'simple' END::regconfig, ?)`
+	var hits []SearchHit
+	err := query.
+		Select(`posts.id, posts.title, posts.slug, posts.type, posts.lang, posts.image_url, posts.user_id, posts.published_at,
+			ts_rank_cd(posts.search_vector, `+tsQuery+`) AS rank,
+			ts_headline(CASE WHEN posts.lang = 'en' THEN 'english' ELSE 'simple' END::regconfig,
+				regexp_replace(CASE WHEN posts.lang = 'en' THEN posts.content ELSE fa_normalize(posts.content) END, '<[^>]*>', ' ', 'g'),
+				`+tsQuery+`,
+				'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "') AS snippet`,
+			q.Text, q.Text).
+		Order("rank DESC, COALESCE(posts.published_at, posts.created_at) DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Scan(&hits).Error
+	return hits, total, err
+}
+
 func (r *PostRepository) FindByID(ctx context.Context, id uint) (*models.Post, error) {
 	var post models.Post
 	err := r.db.WithContext(ctx).
 		Preload("User"). // Preload User
 		First(&post, id).Error
 	return &post, err
-}
\ No newline at end of file
+}
+
+// FindVisibleByID finds a post by ID only if the reader may see it.
+func (r *PostRepository) FindVisibleByID(ctx context.Context, id uint, visibility PostVisibility) (*models.Post, error) {
+	var post models.Post
+	err := visibility.apply(r.db.WithContext(ctx)).
+		Preload("User").
+		Preload("Categories.Names").
+		Preload("Tags").
+		First(&post, id).Error
+	return &post, err
+}
+
+// FindByIDWithTrashed finds a post by ID including soft-deleted ones.
+func (r *PostRepository) FindByIDWithTrashed(ctx context.Context, id uint) (*models.Post, error) {
+	var post models.Post
+	err := r.db.WithContext(ctx).Unscoped().
+		Preload("User").
+		First(&post, id).Error
+	return &post, err
+}
+
+// VersionConflictError reports a conditional write against a stale post version.
+// It matches utils.ErrVersionConflict with errors.Is.
+type VersionConflictError struct {
+	Current uint
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s: current version is %d", utils.ErrVersionConflict, e.Current)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return utils.ErrVersionConflict
+}
+
+// UpdateWithRevision saves the editable fields of a post, including zero values such as
+// an empty image, and snapshots them as a new revision in the same transaction.
+// The write only happens while the stored version still equals expected; otherwise a
+// *VersionConflictError carrying the current version is returned. Non-nil Categories
+// or Tags replace the post's stored ones.
+func (r *PostRepository) UpdateWithRevision(ctx context.Context, post *models.Post, expected uint, authorID uint, keep int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stored models.Post
+		if err := tx.Select("id", "lang", "slug").First(&stored, post.ID).Error; err != nil {
+			return err
+		}
+
+		// The UPDATE locks the row, so concurrent saves also get consecutive revision numbers
+		result := tx.Model(&models.Post{}).
+			Where("id = ? AND version = ?", post.ID, expected).
+			Updates(map[string]interface{}{
+				"title":      post.Title,
+				"slug":       post.Slug,
+				"content":    post.Content,
+				"type":       post.Type,
+				"lang":       post.Lang,
+				"image_url":  post.ImageUrl,
+				"updated_at": post.UpdatedAt,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return slugError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			var current models.Post
+			if err := tx.Select("version").First(&current, post.ID).Error; err != nil {
+				return err
+			}
+			return &VersionConflictError{Current: current.Version}
+		}
+		post.Version = expected + 1
+
+		if stored.Lang != post.Lang || stored.Slug != post.Slug {
+			if err := r.moveSlugWithTx(tx, post, stored.Lang, stored.Slug); err != nil {
+				return err
+			}
+		}
+		if post.Categories != nil {
+			if err := tx.Model(post).Association("Categories").Replace(post.Categories); err != nil {
+				return err
+			}
+		}
+		if post.Tags != nil {
+			if err := tx.Model(post).Association("Tags").Replace(post.Tags); err != nil {
+				return err
+			}
+		}
+		return r.CreateRevisionWithTx(tx, post, authorID, keep)
+	})
+}
+
+// moveSlugWithTx keeps the post's old slug as a redirect and drops any redirect
+// squatting on its new one. Older redirects store the post ID, so they follow along.
+func (r *PostRepository) moveSlugWithTx(tx *gorm.DB, post *models.Post, oldLang, oldSlug string) error {
+	if err := tx.Where("lang = ? AND slug = ?", post.Lang, post.Slug).Delete(&models.PostSlugRedirect{}).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "lang"}, {Name: "slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"post_id", "created_at"}),
+	}).Create(&models.PostSlugRedirect{Lang: oldLang, Slug: oldSlug, PostID: post.ID}).Error
+}
+
+// CreateRevisionWithTx snapshots the post as its next revision and prunes the oldest
+// revisions beyond keep (0 keeps everything).
+func (r *PostRepository) CreateRevisionWithTx(tx *gorm.DB, post *models.Post, authorID uint, keep int) error {
+	var latest int
+	if err := tx.Model(&models.PostRevision{}).
+		Where("post_id = ?", post.ID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	revision := &models.PostRevision{
+		PostID:   post.ID,
+		Revision: latest + 1,
+		Title:    post.Title,
+		Content:  post.Content,
+		Type:     post.Type,
+		Lang:     post.Lang,
+		ImageUrl: post.ImageUrl,
+		AuthorID: &authorID,
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return err
+	}
+
+	if keep > 0 {
+		return tx.Where("post_id = ? AND revision <= ?", post.ID, revision.Revision-keep).
+			Delete(&models.PostRevision{}).Error
+	}
+	return nil
+}
+
+// GetRevisions lists a post's revisions, newest first, without their content.
+func (r *PostRepository) GetRevisions(ctx context.Context, postID uint) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+	err := r.db.WithContext(ctx).
+		Omit("content").
+		Preload("Author").
+		Where("post_id = ?", postID).
+		Order("revision DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// FindRevision returns a single revision of a post.
+func (r *PostRepository) FindRevision(ctx context.Context, postID uint, revision int) (*models.PostRevision, error) {
+	var rev models.PostRevision
+	err := r.db.WithContext(ctx).
+		Where("post_id = ? AND revision = ?", postID, revision).
+		First(&rev).Error
+	return &rev, err
+}
+
+// SoftDelete moves a post into the trash by setting deleted_at.
+func (r *PostRepository) SoftDelete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Post{}, id).Error
+}
+
+// Restore brings a soft-deleted post back out of the trash.
+func (r *PostRepository) Restore(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&models.Post{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil).Error
+}
+
+// Purge permanently removes a post and its likes.
+func (r *PostRepository) Purge(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("post_id = ?", id).Delete(&models.PostLike{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Post{}, id).Error
+	})
+}
+
+// GetTrashed lists soft-deleted posts, optionally limited to a single author (userID 0 means all).
+func (r *PostRepository) GetTrashed(ctx context.Context, userID uint, page, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	offset := (page - 1) * limit
+	query := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Preload("User").
+		Order("deleted_at DESC").
+		Offset(offset).Limit(limit).Find(&posts).Error
+	return posts, err
+}
+
+// Transition moves a post from one workflow status to another and records who did it.
+// It returns gorm.ErrRecordNotFound if the post is no longer in the from status.
+func (r *PostRepository) Transition(ctx context.Context, post *models.Post, transition *models.PostTransition) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Post{}).
+			Where("id = ? AND status = ?", post.ID, transition.FromStatus).
+			Updates(map[string]interface{}{
+				"status":            post.Status,
+				"published_at":      post.PublishedAt,
+				"publish_at":        post.PublishAt,
+				"unpublish_at":      post.UnpublishAt,
+				"status_changed_at": post.StatusChangedAt,
+				"status_changed_by": post.StatusChangedBy,
+				"version":           gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		post.Version++
+		return tx.Create(transition).Error
+	})
+}
+
+// GetTransitions returns a post's workflow history, oldest first.
+func (r *PostRepository) GetTransitions(ctx context.Context, postID uint) ([]models.PostTransition, error) {
+	var transitions []models.PostTransition
+	err := r.db.WithContext(ctx).
+		Preload("Actor").
+		Where("post_id = ?", postID).
+		Order("created_at, id").
+		Find(&transitions).Error
+	return transitions, err
+}
+
+// DuePublications returns scheduled posts whose publish_at has passed.
+func (r *PostRepository) DuePublications(ctx context.Context, now time.Time, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, now).
+		Order("publish_at").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// DueUnpublications returns published posts whose unpublish_at has passed.
+func (r *PostRepository) DueUnpublications(ctx context.Context, now time.Time, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND unpublish_at IS NOT NULL AND unpublish_at <= ?", models.PostStatusPublished, now).
+		Order("unpublish_at").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/alimosavifard/zyros-backend/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RoleRepository struct {
@@ -23,9 +24,22 @@ func (r *RoleRepository) FindByName(ctx context.Context, name string) (*models.R
 	return &role, nil
 }
 
+// FindPermissions returns the permissions with the given names; unknown names are
+// skipped.
+func (r *RoleRepository) FindPermissions(ctx context.Context, names []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.db.WithContext(ctx).Where("name IN ?", names).Find(&permissions).Error
+	return permissions, err
+}
+
+// AssignRoleToUser gives a user a role. Assigning a role the user already has, or
+// once had, is not an error.
 func (r *RoleRepository) AssignRoleToUser(ctx context.Context, userID, roleID uint) error {
 	userRole := models.UserRole{UserID: userID, RoleID: roleID}
-	return r.db.WithContext(ctx).Create(&userRole).Error
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "role_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"deleted_at": nil}),
+	}).Create(&userRole).Error
 }
 
 func (r *RoleRepository) GetUserRoles(ctx context.Context, userID uint) ([]models.Role, error) {
@@ -33,4 +47,109 @@ func (r *RoleRepository) GetUserRoles(ctx context.Context, userID uint) ([]model
 	err := r.db.WithContext(ctx).Joins("JOIN user_roles ON roles.id = user_roles.role_id").
 		Where("user_roles.user_id = ?", userID).Find(&roles).Error
 	return roles, err
-}
\ No newline at end of file
+}
+
+// RevokeRoleFromUser takes a role away from a user, reporting whether they had it.
+func (r *RoleRepository) RevokeRoleFromUser(ctx context.Context, userID, roleID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("user_id = ? AND role_id = ?", userID, roleID).
+		Delete(&models.UserRole{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRoleUsers counts the users that have a role.
+func (r *RoleRepository) CountRoleUsers(ctx context.Context, roleID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.UserRole{}).
+		Joins("JOIN users ON users.id = user_roles.user_id AND users.deleted_at IS NULL").
+		Where("user_roles.role_id = ?", roleID).
+		Count(&count).Error
+	return count, err
+}
+
+// ListRoles returns every role with its permissions, by name.
+func (r *RoleRepository) ListRoles(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.WithContext(ctx).Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("permissions.name")
+	}).Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// FindByID returns a role with its permissions.
+func (r *RoleRepository) FindByID(ctx context.Context, id uint) (*models.Role, error) {
+	var role models.Role
+	err := r.db.WithContext(ctx).Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("permissions.name")
+	}).First(&role, id).Error
+	return &role, err
+}
+
+func (r *RoleRepository) CreateRole(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Create(role).Error
+}
+
+func (r *RoleRepository) RenameRole(ctx context.Context, id uint, name string) error {
+	return r.db.WithContext(ctx).Model(&models.Role{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// DeleteRole removes a role for good, so its name can be reused; users lose it and
+// its grants go with it.
+func (r *RoleRepository) DeleteRole(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.Role{}, id).Error
+}
+
+// ListPermissions returns every permission by name.
+func (r *RoleRepository) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.db.WithContext(ctx).Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *RoleRepository) FindPermissionByID(ctx context.Context, id uint) (*models.Permission, error) {
+	var permission models.Permission
+	err := r.db.WithContext(ctx).First(&permission, id).Error
+	return &permission, err
+}
+
+func (r *RoleRepository) FindPermissionByName(ctx context.Context, name string) (*models.Permission, error) {
+	var permission models.Permission
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&permission).Error
+	return &permission, err
+}
+
+func (r *RoleRepository) CreatePermission(ctx context.Context, permission *models.Permission) error {
+	return r.db.WithContext(ctx).Create(permission).Error
+}
+
+func (r *RoleRepository) RenamePermission(ctx context.Context, id uint, name string) error {
+	return r.db.WithContext(ctx).Model(&models.Permission{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// DeletePermission removes a permission and every grant of it.
+func (r *RoleRepository) DeletePermission(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Permission{}, id).Error
+}
+
+// EnsurePermissions creates whichever of the named permissions don't exist yet.
+func (r *RoleRepository) EnsurePermissions(ctx context.Context, names []string) error {
+	permissions := make([]models.Permission, len(names))
+	for i, name := range names {
+		permissions[i] = models.Permission{Name: name}
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&permissions).Error
+}
+
+// AttachPermission grants a permission to a role; granting it twice is not an error.
+func (r *RoleRepository) AttachPermission(ctx context.Context, roleID, permissionID uint) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RolePermission{RoleID: roleID, PermissionID: permissionID}).Error
+}
+
+// DetachPermission takes a permission away from a role, reporting whether it had it.
+func (r *RoleRepository) DetachPermission(ctx context.Context, roleID, permissionID uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("role_id = ? AND permission_id = ?", roleID, permissionID).
+		Delete(&models.RolePermission{})
+	return result.RowsAffected > 0, result.Error
+}
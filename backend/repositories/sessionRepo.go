@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// FindByID finds a session in any state.
+func (r *SessionRepository) FindByID(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error
+	return &session, err
+}
+
+// ListActive returns a user's sessions that are neither revoked nor expired, most
+// recently used first.
+func (r *SessionRepository) ListActive(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch records a refresh: where it came from and how long the session now lasts.
+func (r *SessionRepository) Touch(ctx context.Context, id, userAgent, ipAddress string, expiresAt, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"user_agent":   userAgent,
+		"ip_address":   ipAddress,
+		"last_used_at": now,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// Revoke ends sessions together with every refresh token issued to them.
+func (r *SessionRepository) Revoke(ctx context.Context, ids []string, now time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("id IN ? AND revoked_at IS NULL", ids).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("family_id IN ? AND revoked_at IS NULL", ids).
+			Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// UnexpiredSigningKeys returns every key that still verifies tokens.
+func (r *SigningKeyRepository) UnexpiredSigningKeys(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	err := r.db.WithContext(ctx).Where("expires_at > ?", now).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *SigningKeyRepository) CreateSigningKey(ctx context.Context, key *models.SigningKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+// DeleteExpiredSigningKeys removes keys no token can be verified with any more.
+func (r *SigningKeyRepository) DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.SigningKey{}).Error
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaxonomyRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxonomyRepository(db *gorm.DB) *TaxonomyRepository {
+	return &TaxonomyRepository{db: db}
+}
+
+// categorySubtreeSQL selects the IDs of the category with the given slug and all of its descendants.
+const categorySubtreeSQL = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE slug = ?
+	UNION ALL
+	SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+) SELECT id FROM subtree`
+
+// CategoryCount is the number of published posts filed under a category or any of its descendants.
+type CategoryCount struct {
+	CategoryID uint
+	PostCount  int64
+}
+
+// TagCount is a tag with the number of published posts carrying it.
+type TagCount struct {
+	models.Tag
+	PostCount int64 `json:"post_count"`
+}
+
+// GetCategories returns every category with its names. The tree is small enough to load whole.
+func (r *TaxonomyRepository) GetCategories(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.WithContext(ctx).Preload("Names").Order("id").Find(&categories).Error
+	return categories, err
+}
+
+// FindCategoryByID finds a category with its names.
+func (r *TaxonomyRepository) FindCategoryByID(ctx context.Context, id uint) (*models.Category, error) {
+	var category models.Category
+	err := r.db.WithContext(ctx).Preload("Names").First(&category, id).Error
+	return &category, err
+}
+
+// FindCategoryBySlug finds a category with its names.
+func (r *TaxonomyRepository) FindCategoryBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	var category models.Category
+	err := r.db.WithContext(ctx).Preload("Names").Where("slug = ?", slug).First(&category).Error
+	return &category, err
+}
+
+// FindCategoriesByIDs returns the categories among ids that exist.
+func (r *TaxonomyRepository) FindCategoriesByIDs(ctx context.Context, ids []uint) ([]models.Category, error) {
+	var categories []models.Category
+	if len(ids) == 0 {
+		return categories, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&categories).Error
+	return categories, err
+}
+
+// CreateCategory inserts a category together with its names.
+func (r *TaxonomyRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Create(category).Error
+}
+
+// UpdateCategory saves the slug and parent of a category and, when names is not nil,
+// replaces its names.
+func (r *TaxonomyRepository) UpdateCategory(ctx context.Context, category *models.Category, names []models.CategoryName) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(category).
+			Select("slug", "parent_id", "updated_at").
+			Updates(category).Error; err != nil {
+			return err
+		}
+		if names == nil {
+			return nil
+		}
+		if err := tx.Where("category_id = ?", category.ID).Delete(&models.CategoryName{}).Error; err != nil {
+			return err
+		}
+		for i := range names {
+			names[i].CategoryID = category.ID
+		}
+		if len(names) > 0 {
+			if err := tx.Create(&names).Error; err != nil {
+				return err
+			}
+		}
+		category.Names = names
+		return nil
+	})
+}
+
+// DeleteCategory removes a category; its names and post links are removed by cascade.
+func (r *TaxonomyRepository) DeleteCategory(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Category{}, id).Error
+}
+
+// CountChildren returns how many categories sit directly beneath a category.
+func (r *TaxonomyRepository) CountChildren(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// CountCategoryPosts counts published posts per category, including posts filed under
+// descendants. An empty lang counts posts in every language.
+func (r *TaxonomyRepository) CountCategoryPosts(ctx context.Context, lang string) ([]CategoryCount, error) {
+	var counts []CategoryCount
+	err := r.db.WithContext(ctx).Raw(`WITH RECURSIVE tree AS (
+			SELECT id AS root_id, id FROM categories
+			UNION ALL
+			SELECT t.root_id, c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT tree.root_id AS category_id, COUNT(DISTINCT p.id) AS post_count
+		FROM tree
+		JOIN post_categories pc ON pc.category_id = tree.id
+		JOIN posts p ON p.id = pc.post_id
+		WHERE p.status = ? AND p.deleted_at IS NULL AND (? = '' OR p.lang = ?)
+		GROUP BY tree.root_id`, models.PostStatusPublished, lang, lang).
+		Scan(&counts).Error
+	return counts, err
+}
+
+// GetTags lists tags with their published post counts, most used first. A non-empty
+// prefix limits the result to tags whose slug starts with it.
+func (r *TaxonomyRepository) GetTags(ctx context.Context, prefix string, limit int) ([]TagCount, error) {
+	var tags []TagCount
+	query := r.db.WithContext(ctx).Model(&models.Tag{}).
+		Select("tags.*, COUNT(p.id) AS post_count").
+		Joins("LEFT JOIN post_tags pt ON pt.tag_id = tags.id").
+		Joins("LEFT JOIN posts p ON p.id = pt.post_id AND p.status = ? AND p.deleted_at IS NULL", models.PostStatusPublished).
+		Group("tags.id")
+	if prefix != "" {
+		query = query.Where("tags.slug LIKE ?", escapeLike(prefix)+"%")
+	}
+	err := query.Order("post_count DESC, tags.name").Limit(limit).Scan(&tags).Error
+	return tags, err
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// FindTagByID finds a tag.
+func (r *TaxonomyRepository) FindTagByID(ctx context.Context, id uint) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).First(&tag, id).Error
+	return &tag, err
+}
+
+// FindTagBySlug finds a tag by its normalized name.
+func (r *TaxonomyRepository) FindTagBySlug(ctx context.Context, slug string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&tag).Error
+	return &tag, err
+}
+
+// FindOrCreateTags returns the tags for the given slugs, creating the missing ones.
+// tags must already carry their cleaned name and slug.
+func (r *TaxonomyRepository) FindOrCreateTags(ctx context.Context, tags []models.Tag) ([]models.Tag, error) {
+	if len(tags) == 0 {
+		return []models.Tag{}, nil
+	}
+	// Concurrent writers may create the same tag; the first one wins and keeps its name
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "slug"}}, DoNothing: true}).
+		Create(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	slugs := make([]string, len(tags))
+	for i, tag := range tags {
+		slugs[i] = tag.Slug
+	}
+	var stored []models.Tag
+	err := r.db.WithContext(ctx).Where("slug IN ?", slugs).Find(&stored).Error
+	return stored, err
+}
+
+// CreateTag inserts a tag.
+func (r *TaxonomyRepository) CreateTag(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+// UpdateTag saves a tag's name and slug.
+func (r *TaxonomyRepository) UpdateTag(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Model(tag).Select("name", "slug").Updates(tag).Error
+}
+
+// DeleteTag removes a tag; its post links are removed by cascade.
+func (r *TaxonomyRepository) DeleteTag(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Tag{}, id).Error
+}
@@ -2,8 +2,11 @@ package repositories
 
 import (
 	"context"
+	"strings"
+	"time"
 	"github.com/alimosavifard/zyros-backend/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -30,23 +33,148 @@ func (r *UserRepository) FindByID(ctx context.Context, id uint) (*models.User, e
 	return &user, err
 }
 
+// FindByEmail finds a user by their lowercased email address.
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := r.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	return &user, err
+}
 
-// HasPermission checks if a user has a specific permission.
-func (r *UserRepository) HasPermission(ctx context.Context, userID uint, permissionName string) (bool, error) {
-	var count int64
+// UpdatePassword stores a new password hash.
+func (r *UserRepository) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	return r.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+// UpdateProfile saves the given columns of user.
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *models.User, columns ...string) error {
+	return r.DB.WithContext(ctx).Model(user).Select(columns).Updates(user).Error
+}
+
+// MarkEmailVerified records that the user proved they own email. Nothing changes if
+// the user's address has changed since the verification was sent.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id uint, email string, now time.Time) (bool, error) {
+	result := r.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND email = ?", id, email).
+		Update("email_verified_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+
+// Permissions returns the names of the permissions the user's roles grant.
+func (r *UserRepository) Permissions(ctx context.Context, userID uint) ([]string, error) {
+	var names []string
+	err := r.DB.WithContext(ctx).
+		Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id AND roles.deleted_at IS NULL").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id AND user_roles.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = user_roles.user_id AND users.deleted_at IS NULL").
+		Where("users.id = ?", userID).
+		Distinct().
+		Order("permissions.name").
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+// suspendedSQL matches users whose suspension or ban is in force at the bound time.
+const suspendedSQL = "users.suspended_at IS NOT NULL AND (users.suspended_until IS NULL OR users.suspended_until > ?)"
+
+// Statuses a user listing can be filtered by.
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDeleted   = "deleted"
+)
+
+// UserQuery filters and orders a user listing. Empty fields are ignored; OrderBy
+// must be a column the caller has checked.
+type UserQuery struct {
+	Search  string // matches username, display name or email
+	Role    string // role name
+	Status  string
+	OrderBy string
+	Desc    bool
+	Now     time.Time
+}
 
-	// Join with user_roles and role_permissions tables to check for permission
-	err := r.db.WithContext(ctx).
-		Table("users").
-		Joins("JOIN user_roles ON users.id = user_roles.user_id").
-		Joins("JOIN role_permissions ON user_roles.role_id = role_permissions.role_id").
-		Joins("JOIN permissions ON role_permissions.permission_id = permissions.id").
-		Where("users.id = ? AND permissions.name = ? AND users.deleted_at IS NULL", userID, permissionName).
-		Count(&count).Error
-
-	if err != nil {
-		return false, err
+// Search lists users matching q with their roles, and counts every match.
+func (r *UserRepository) Search(ctx context.Context, q UserQuery, page, limit int) ([]models.User, int64, error) {
+	query := r.DB.WithContext(ctx).Model(&models.User{})
+	switch q.Status {
+	case UserStatusActive:
+		query = query.Where("NOT ("+suspendedSQL+")", q.Now)
+	case UserStatusSuspended:
+		query = query.Where(suspendedSQL, q.Now)
+	case UserStatusDeleted:
+		query = query.Unscoped().Where("users.deleted_at IS NOT NULL")
 	}
+	if q.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(q.Search)) + "%"
+		query = query.Where("(LOWER(users.username) LIKE ? OR LOWER(users.display_name) LIKE ? OR users.email LIKE ?)", pattern, pattern, pattern)
+	}
+	if q.Role != "" {
+		query = query.Where("users.id IN (SELECT ur.user_id FROM user_roles ur JOIN roles ON roles.id = ur.role_id "+
+			"WHERE roles.name = ? AND roles.deleted_at IS NULL AND ur.deleted_at IS NULL)", q.Role)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	err := query.Preload("Roles").
+		Order(clause.OrderByColumn{Column: clause.Column{Table: "users", Name: q.OrderBy}, Desc: q.Desc}).
+		Order("users.id").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&users).Error
+	return users, total, err
+}
+
+// FindByIDWithTrashed finds a user even if they were deleted.
+func (r *UserRepository) FindByIDWithTrashed(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := r.DB.WithContext(ctx).Unscoped().Preload("Roles").First(&user, id).Error
+	return &user, err
+}
 
-	return count > 0, nil
-}
\ No newline at end of file
+// Suspend suspends the user until the given time, or for good when until is nil.
+func (r *UserRepository) Suspend(ctx context.Context, id uint, reason string, now time.Time, until *time.Time) error {
+	return r.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"suspended_at":      now,
+		"suspended_until":   until,
+		"suspension_reason": reason,
+	}).Error
+}
+
+// Unsuspend lifts the user's suspension or ban.
+func (r *UserRepository) Unsuspend(ctx context.Context, id uint) error {
+	return r.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"suspended_at":      nil,
+		"suspended_until":   nil,
+		"suspension_reason": "",
+	}).Error
+}
+
+// CountPosts counts the user's posts, trashed ones included.
+func (r *UserRepository) CountPosts(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.DB.WithContext(ctx).Unscoped().Model(&models.Post{}).Where("user_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// DeleteAndReassign soft-deletes the user, first handing their posts, trashed ones
+// included, to reassignTo unless it is 0. It returns how many posts moved.
+func (r *UserRepository) DeleteAndReassign(ctx context.Context, id, reassignTo uint) (int64, error) {
+	var moved int64
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if reassignTo != 0 {
+			result := tx.Unscoped().Model(&models.Post{}).Where("user_id = ?", id).Update("user_id", reassignTo)
+			if result.Error != nil {
+				return result.Error
+			}
+			moved = result.RowsAffected
+		}
+		return tx.Delete(&models.User{}, id).Error
+	})
+	return moved, err
+}
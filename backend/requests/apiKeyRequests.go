@@ -0,0 +1,15 @@
+package requests
+
+import "time"
+
+// APIKeyRequest creates an API key. Scopes are permission names the user holds;
+// without expiresAt the key lasts until it is revoked.
+type APIKeyRequest struct {
+    Name      string     `json:"name" validate:"required,max=100"`
+    Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required"`
+    ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func (r *APIKeyRequest) Validate() error {
+	return ValidateStruct(r)
+}
@@ -1,10 +1,12 @@
 package requests
 
 type ArticleRequest struct {
-    Title    string `json:"title" validate:"required,min=3"`
-    Content  string `json:"content" validate:"required,min=10"`
-    Lang     string `json:"lang" validate:"required,oneof=fa en"`
-    ImageUrl string `json:"imageUrl" validate:"omitempty,url"`
+    Title       string   `json:"title" validate:"required,min=3"`
+    Content     string   `json:"content" validate:"required,min=10"`
+    Lang        string   `json:"lang" validate:"required,oneof=fa en"`
+    ImageUrl    string   `json:"imageUrl" validate:"omitempty,url"`
+    CategoryIDs []uint   `json:"categoryIds" validate:"omitempty,max=10"`
+    Tags        []string `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
 }
 
 func (r *ArticleRequest) Validate() error {
@@ -6,6 +6,7 @@ import (
 type RegisterRequest struct {
     Username string `json:"username" validate:"required,min=3"`
     Password string `json:"password" validate:"required,min=6"`
+    Email    string `json:"email" validate:"omitempty,email,max=254"` // اختیاری، برای بازیابی رمز عبور
 }
 
 type LoginRequest struct {
@@ -19,4 +20,87 @@ func (r *RegisterRequest) Validate() error {
 
 func (r *LoginRequest) Validate() error {
 	return ValidateStruct(r)
-}
\ No newline at end of file
+}
+
+// RefreshRequest optionally carries the refresh token in the body for clients that
+// don't keep cookies.
+type RefreshRequest struct {
+    RefreshToken string `json:"refreshToken"`
+}
+
+// EmailRequest asks for a password reset or verification link to be mailed.
+type EmailRequest struct {
+    Email string `json:"email" validate:"required,email,max=254"`
+}
+
+func (r *EmailRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+type ResetPasswordRequest struct {
+    Token    string `json:"token" validate:"required"`
+    Password string `json:"password" validate:"required,min=6"`
+}
+
+func (r *ResetPasswordRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+type VerifyEmailRequest struct {
+    Token string `json:"token" validate:"required"`
+}
+
+func (r *VerifyEmailRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+// MFACodeRequest carries a TOTP code or a recovery code.
+type MFACodeRequest struct {
+    Code string `json:"code" validate:"required,max=32"`
+}
+
+func (r *MFACodeRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+// MFAChallengeRequest answers the challenge returned by login.
+type MFAChallengeRequest struct {
+    MFAToken string `json:"mfa_token" validate:"required"`
+    Code     string `json:"code" validate:"required,max=32"`
+}
+
+func (r *MFAChallengeRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+// MFAEnrollRequest starts enrollment for an account that must have two-factor login.
+type MFAEnrollRequest struct {
+    MFAToken string `json:"mfa_token" validate:"required"`
+}
+
+func (r *MFAEnrollRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+// UpdateProfileRequest carries the fields of a PATCH /me. A nil field is left
+// unchanged; an empty string clears it.
+type UpdateProfileRequest struct {
+    DisplayName *string            `json:"display_name" validate:"omitempty,max=50"`
+    Bio         *string            `json:"bio" validate:"omitempty,max=500"`
+    AvatarURL   *string            `json:"avatar_url" validate:"omitempty,max=500,http_url|len=0"`
+    Locale      *string            `json:"locale" validate:"omitempty,oneof=fa en|len=0"`
+    SocialLinks *map[string]string `json:"social_links" validate:"omitempty,max=7,dive,keys,oneof=website github x linkedin instagram telegram youtube,endkeys,max=200,http_url|len=0"` // کل فهرست را جایگزین می‌کند
+}
+
+func (r *UpdateProfileRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+type ChangePasswordRequest struct {
+    CurrentPassword string `json:"current_password" validate:"required"`
+    NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+func (r *ChangePasswordRequest) Validate() error {
+	return ValidateStruct(r)
+}
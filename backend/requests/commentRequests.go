@@ -0,0 +1,30 @@
+package requests
+
+// CommentRequest posts a comment, or a reply when parentId is set.
+type CommentRequest struct {
+    Body     string `json:"body" validate:"required,max=5000"`
+    ParentID *uint  `json:"parentId"`
+}
+
+func (r *CommentRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+type UpdateCommentRequest struct {
+    Body string `json:"body" validate:"required,max=5000"`
+}
+
+func (r *UpdateCommentRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+// ModerateCommentRequest moves a comment to another moderation state. The note is
+// kept for moderators only.
+type ModerateCommentRequest struct {
+    Status string `json:"status" validate:"required,oneof=pending approved spam rejected"`
+    Note   string `json:"note" validate:"omitempty,max=500"`
+}
+
+func (r *ModerateCommentRequest) Validate() error {
+	return ValidateStruct(r)
+}
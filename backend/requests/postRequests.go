@@ -1,12 +1,48 @@
 package requests
 
 import (
+    "time"
 )
 
 type PostRequest struct {
-    Title    string `json:"title" validate:"required,min=3"`
-    Content  string `json:"content" validate:"required,min=10"`
-    Type     string `json:"type" validate:"required,oneof=post article"`
-    Lang     string `json:"lang" validate:"required,oneof=fa en"`
-    ImageUrl string `json:"imageUrl" validate:"omitempty,url"` // اختیاری
-}
\ No newline at end of file
+    Title       string   `json:"title" validate:"required,min=3"`
+    Content     string   `json:"content" validate:"required,min=10"`
+    Type        string   `json:"type" validate:"required,oneof=post article"`
+    Lang        string   `json:"lang" validate:"required,oneof=fa en"`
+    Slug        string   `json:"slug" validate:"omitempty,max=100"` // خالی: از عنوان ساخته می‌شود
+    ImageUrl    string   `json:"imageUrl" validate:"omitempty,url"` // اختیاری
+    CategoryIDs []uint   `json:"categoryIds" validate:"omitempty,max=10"`
+    Tags        []string `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+}
+
+// UpdatePostRequest carries the fields of a PUT/PATCH. A nil field is left unchanged.
+type UpdatePostRequest struct {
+    Title       *string   `json:"title" validate:"omitempty,min=3"`
+    Content     *string   `json:"content" validate:"omitempty,min=10"`
+    Type        *string   `json:"type" validate:"omitempty,oneof=post article"`
+    Lang        *string   `json:"lang" validate:"omitempty,oneof=fa en"`
+    Slug        *string   `json:"slug" validate:"omitempty,min=1,max=100"` // بدون slug، تغییر عنوان slug را از نو می‌سازد
+    ImageUrl    *string   `json:"imageUrl" validate:"omitempty,url|len=0"` // رشته خالی تصویر را حذف می‌کند
+    CategoryIDs *[]uint   `json:"categoryIds" validate:"omitempty,max=10"` // آرایه خالی همه دسته‌ها را برمی‌دارد
+    Tags        *[]string `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+}
+
+func (r *UpdatePostRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+// IsComplete reports whether every required field is present, as a PUT must replace the whole post.
+func (r *UpdatePostRequest) IsComplete() bool {
+    return r.Title != nil && r.Content != nil && r.Type != nil && r.Lang != nil
+}
+
+type TransitionRequest struct {
+    Transition  string     `json:"transition" validate:"required,oneof=submit withdraw approve reject publish schedule unschedule archive reopen"`
+    Note        string     `json:"note" validate:"omitempty,max=1000"`
+    PublishAt   *time.Time `json:"publishAt"`   // RFC 3339، برای schedule الزامی است
+    UnpublishAt *time.Time `json:"unpublishAt"` // RFC 3339، اختیاری
+}
+
+func (r *TransitionRequest) Validate() error {
+	return ValidateStruct(r)
+}
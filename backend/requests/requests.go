@@ -1,6 +1,7 @@
 package requests
 
 import (
+	"github.com/go-playground/validator/v10"
 )
 
 type Validatable interface {
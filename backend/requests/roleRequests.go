@@ -0,0 +1,19 @@
+package requests
+
+// RoleRequest creates or renames a role.
+type RoleRequest struct {
+    Name string `json:"name" validate:"required,max=50"`
+}
+
+func (r *RoleRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+// PermissionRequest creates or renames a permission.
+type PermissionRequest struct {
+    Name string `json:"name" validate:"required,max=50"`
+}
+
+func (r *PermissionRequest) Validate() error {
+	return ValidateStruct(r)
+}
@@ -0,0 +1,33 @@
+package requests
+
+// CategoryRequest creates a category. Names maps a language ("fa" or "en") to the
+// category's name in it; an empty slug is derived from the names.
+type CategoryRequest struct {
+    Slug     string            `json:"slug" validate:"omitempty,max=100"`
+    ParentID *uint             `json:"parentId"`
+    Names    map[string]string `json:"names" validate:"required,min=1,dive,keys,oneof=fa en,endkeys,required,max=100"`
+}
+
+func (r *CategoryRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+// UpdateCategoryRequest changes a category. A nil field is left unchanged, parentId 0
+// moves the category to the root and names replaces every name.
+type UpdateCategoryRequest struct {
+    Slug     *string           `json:"slug" validate:"omitempty,min=1,max=100"`
+    ParentID *uint             `json:"parentId"`
+    Names    map[string]string `json:"names" validate:"omitempty,min=1,dive,keys,oneof=fa en,endkeys,required,max=100"`
+}
+
+func (r *UpdateCategoryRequest) Validate() error {
+	return ValidateStruct(r)
+}
+
+type TagRequest struct {
+    Name string `json:"name" validate:"required,max=50"`
+}
+
+func (r *TagRequest) Validate() error {
+	return ValidateStruct(r)
+}
@@ -0,0 +1,13 @@
+package requests
+
+import "time"
+
+// SuspendUserRequest suspends a user until Until, or bans them without it.
+type SuspendUserRequest struct {
+    Reason string     `json:"reason" validate:"required,max=500"`
+    Until  *time.Time `json:"until"` // RFC 3339
+}
+
+func (r *SuspendUserRequest) Validate() error {
+	return ValidateStruct(r)
+}
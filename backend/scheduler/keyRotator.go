@@ -0,0 +1,192 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/keyring"
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+const keyRotationLockKey = "zyros:scheduler:key-rotation"
+
+// SigningKeyStore is the part of SigningKeyRepository the rotator needs.
+type SigningKeyStore interface {
+	UnexpiredSigningKeys(ctx context.Context, now time.Time) ([]models.SigningKey, error)
+	CreateSigningKey(ctx context.Context, key *models.SigningKey) error
+	DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error
+}
+
+// RotationPolicy says which keys to create and for how long they are used. Overlap
+// is how long a key keeps verifying after it stops signing; it must be at least
+// the access token lifetime.
+type RotationPolicy struct {
+	Algorithm string
+	SignFor   time.Duration
+	Overlap   time.Duration
+}
+
+// KeyRotator keeps a keyring in step with the stored signing keys. Every replica
+// reloads the keys on each tick; only one at a time creates a successor when the
+// signing key is about to retire.
+type KeyRotator struct {
+	store    SigningKeyStore
+	locker   Locker
+	ring     *keyring.Keyring
+	box      *utils.SecretBox
+	policy   RotationPolicy
+	clock    utils.Clock
+	interval time.Duration
+}
+
+func NewKeyRotator(store SigningKeyStore, locker Locker, ring *keyring.Keyring, box *utils.SecretBox, policy RotationPolicy, clock utils.Clock, interval time.Duration) *KeyRotator {
+	return &KeyRotator{store: store, locker: locker, ring: ring, box: box, policy: policy, clock: clock, interval: interval}
+}
+
+// Ready ticks until the keyring has a signing key. It is meant for startup, before
+// any token is issued; another replica may be creating the first key meanwhile.
+func (r *KeyRotator) Ready(ctx context.Context, attempts int) error {
+	for i := 0; i < attempts; i++ {
+		if err := r.Tick(ctx); err != nil {
+			return err
+		}
+		if _, ok := r.ring.Signing(r.clock.Now()); ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return errors.New("no signing key became available")
+}
+
+// Run ticks every interval until ctx is cancelled.
+func (r *KeyRotator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.Tick(ctx); err != nil {
+			utils.InitLogger().Error().Err(err).Msg("Signing key rotation run failed")
+		}
+	}
+}
+
+// Reload replaces the keyring with the stored keys without rotating. It lets a
+// verifier pick up a key another replica created since the last tick.
+func (r *KeyRotator) Reload(ctx context.Context) error {
+	return r.reload(ctx, r.clock.Now())
+}
+
+// Tick reloads the keyring and, if the signing key retires within two intervals,
+// creates its successor. Creating it early means there is never a moment without
+// a signing key, at the cost of retiring the old one slightly ahead of schedule.
+// The successor only starts signing an interval later, once every replica has
+// reloaded it; the first key signs straight away, since nothing signs before it.
+func (r *KeyRotator) Tick(ctx context.Context) error {
+	now := r.clock.Now()
+	if err := r.reload(ctx, now); err != nil {
+		return err
+	}
+	if r.hasSuccessor(now) {
+		return nil
+	}
+
+	release, ok, err := r.locker.TryLock(ctx, keyRotationLockKey, time.Minute)
+	if err != nil || !ok {
+		return err
+	}
+	defer release()
+
+	// Another replica may have rotated between our reload and taking the lock
+	if err := r.reload(ctx, now); err != nil {
+		return err
+	}
+	if r.hasSuccessor(now) {
+		return nil
+	}
+
+	delay := r.interval
+	if _, ok := r.ring.Signing(now); !ok {
+		delay = 0
+	}
+	key, err := keyring.Generate(r.policy.Algorithm, now, delay, r.policy.SignFor, r.policy.Overlap)
+	if err != nil {
+		return err
+	}
+	encoded, err := key.MarshalPrivate()
+	if err != nil {
+		return err
+	}
+	sealed, err := r.box.Seal(encoded)
+	if err != nil {
+		return err
+	}
+	if err := r.store.CreateSigningKey(ctx, &models.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: sealed,
+		CreatedAt:  key.CreatedAt,
+		NotBefore:  key.NotBefore,
+		RetiresAt:  key.RetiresAt,
+		ExpiresAt:  key.ExpiresAt,
+	}); err != nil {
+		return err
+	}
+	utils.InitLogger().Info().Str("kid", key.ID).Str("alg", key.Algorithm).Msg("Created signing key")
+
+	if err := r.store.DeleteExpiredSigningKeys(ctx, now); err != nil {
+		return err
+	}
+	return r.reload(ctx, now)
+}
+
+// hasSuccessor reports whether a key of the configured algorithm will still be
+// signing two intervals from now.
+func (r *KeyRotator) hasSuccessor(now time.Time) bool {
+	key, ok := r.ring.Signing(now.Add(2 * r.interval))
+	return ok && key.Algorithm == r.policy.Algorithm
+}
+
+// reload replaces the keyring with the stored keys. Keys of another algorithm stay
+// in it, so switching algorithms doesn't invalidate tokens already issued.
+func (r *KeyRotator) reload(ctx context.Context, now time.Time) error {
+	stored, err := r.store.UnexpiredSigningKeys(ctx, now)
+	if err != nil {
+		return err
+	}
+	keys := make([]*keyring.Key, 0, len(stored))
+	for _, s := range stored {
+		encoded, err := r.box.Open(s.PrivateKey)
+		if err != nil {
+			utils.InitLogger().Error().Err(err).Str("kid", s.ID).Msg("Failed to decrypt signing key")
+			continue
+		}
+		private, err := keyring.ParsePrivate(s.Algorithm, encoded)
+		if err != nil {
+			utils.InitLogger().Error().Err(err).Str("kid", s.ID).Msg("Failed to parse signing key")
+			continue
+		}
+		keys = append(keys, &keyring.Key{
+			ID:        s.ID,
+			Algorithm: s.Algorithm,
+			Private:   private,
+			CreatedAt: s.CreatedAt,
+			NotBefore: s.NotBefore,
+			RetiresAt: s.RetiresAt,
+			ExpiresAt: s.ExpiresAt,
+		})
+	}
+	r.ring.Set(keys)
+	return nil
+}
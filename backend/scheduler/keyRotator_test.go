@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/keyring"
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+type fakeKeyStore struct {
+	keys []models.SigningKey
+}
+
+func (s *fakeKeyStore) UnexpiredSigningKeys(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	for _, k := range s.keys {
+		if k.ExpiresAt.After(now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (s *fakeKeyStore) CreateSigningKey(ctx context.Context, key *models.SigningKey) error {
+	s.keys = append(s.keys, *key)
+	return nil
+}
+
+func (s *fakeKeyStore) DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error {
+	keys, _ := s.UnexpiredSigningKeys(ctx, now)
+	s.keys = keys
+	return nil
+}
+
+func TestKeyRotatorTick(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	clock := utils.NewFakeClock(start)
+	box, err := utils.NewSecretBox("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeKeyStore{}
+	ring := keyring.New()
+	policy := RotationPolicy{Algorithm: keyring.EdDSA, SignFor: 24 * time.Hour, Overlap: time.Hour}
+	rotator := NewKeyRotator(store, &fakeLocker{}, ring, box, policy, clock, time.Minute)
+	ctx := context.Background()
+
+	if err := rotator.Ready(ctx, 1); err != nil {
+		t.Fatalf("Ready: %v", err)
+	}
+	first, ok := ring.Signing(clock.Now())
+	if !ok || len(store.keys) != 1 || first.ID != store.keys[0].ID {
+		t.Fatalf("first key not created and loaded: %d stored", len(store.keys))
+	}
+
+	clock.Advance(12 * time.Hour)
+	if err := rotator.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.keys) != 1 {
+		t.Fatalf("rotated %d keys before the signing key was due to retire", len(store.keys)-1)
+	}
+
+	// Within two intervals of retiring, the successor is created but only takes
+	// over signing an interval later, once every replica has loaded it
+	clock.Set(start.Add(24*time.Hour - time.Minute))
+	if err := rotator.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.keys) != 2 {
+		t.Fatalf("successor not created: %d stored", len(store.keys))
+	}
+	if signing, _ := ring.Signing(clock.Now()); signing.ID != first.ID {
+		t.Error("successor signs before its not-before")
+	}
+	if _, ok := ring.Find(store.keys[1].ID, clock.Now()); !ok {
+		t.Error("successor not loaded for verification")
+	}
+
+	clock.Advance(time.Minute)
+	second, _ := ring.Signing(clock.Now())
+	if second == nil || second.ID != store.keys[1].ID {
+		t.Fatal("successor not signing after its not-before")
+	}
+	if _, ok := ring.Find(first.ID, clock.Now()); !ok {
+		t.Error("retiring key no longer verifies")
+	}
+
+	// After the overlap the old key is gone from the store and the keyring
+	clock.Set(start.Add(25*time.Hour + time.Minute))
+	if err := rotator.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ring.Find(first.ID, clock.Now()); ok {
+		t.Error("expired key still verifies")
+	}
+	if got := len(ring.JWKS(clock.Now()).Keys); got != 1 {
+		t.Errorf("JWKS has %d keys, want 1", got)
+	}
+}
+
+func TestKeyRotatorLocked(t *testing.T) {
+	clock := utils.NewFakeClock(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	box, _ := utils.NewSecretBox("test")
+	store := &fakeKeyStore{}
+	policy := RotationPolicy{Algorithm: keyring.EdDSA, SignFor: time.Hour, Overlap: time.Hour}
+	rotator := NewKeyRotator(store, &fakeLocker{held: true}, keyring.New(), box, policy, clock, time.Minute)
+
+	if err := rotator.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.keys) != 0 {
+		t.Errorf("created a key while another replica held the lock")
+	}
+}
@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/alimosavifard/zyros-backend/workflow"
+)
+
+const (
+	publishLockKey = "zyros:scheduler:publish"
+	batchSize      = 100
+)
+
+// PostStore is the part of PostRepository the worker needs.
+type PostStore interface {
+	DuePublications(ctx context.Context, now time.Time, limit int) ([]models.Post, error)
+	DueUnpublications(ctx context.Context, now time.Time, limit int) ([]models.Post, error)
+	Transition(ctx context.Context, post *models.Post, transition *models.PostTransition) error
+}
+
+// CacheInvalidator clears cached listings and copies of the given posts.
+type CacheInvalidator func(ctx context.Context, posts ...*models.Post) error
+
+// PublishWorker flips scheduled posts live at publish_at and archives published
+// posts at unpublish_at. Only one replica does the work on each tick.
+type PublishWorker struct {
+	store      PostStore
+	locker     Locker
+	invalidate CacheInvalidator
+	clock      utils.Clock
+	interval   time.Duration
+}
+
+func NewPublishWorker(store PostStore, locker Locker, invalidate CacheInvalidator, clock utils.Clock, interval time.Duration) *PublishWorker {
+	return &PublishWorker{store: store, locker: locker, invalidate: invalidate, clock: clock, interval: interval}
+}
+
+// Run ticks every interval until ctx is cancelled.
+func (w *PublishWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Tick(ctx); err != nil {
+			utils.InitLogger().Error().Err(err).Msg("Scheduled publishing run failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick performs a single pass and returns the posts it changed. It does nothing
+// if another replica currently holds the lock.
+func (w *PublishWorker) Tick(ctx context.Context) ([]*models.Post, error) {
+	release, ok, err := w.locker.TryLock(ctx, publishLockKey, 2*w.interval)
+	if err != nil || !ok {
+		return nil, err
+	}
+	defer release()
+
+	now := w.clock.Now()
+	var changed []*models.Post
+
+	due, err := w.store.DuePublications(ctx, now, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	for i := range due {
+		post := &due[i]
+		if err := w.move(ctx, post, workflow.Publish, models.PostStatusPublished, now); err != nil {
+			utils.InitLogger().Error().Err(err).Msgf("Failed to publish scheduled post %d", post.ID)
+			continue
+		}
+		changed = append(changed, post)
+	}
+
+	expired, err := w.store.DueUnpublications(ctx, now, batchSize)
+	if err != nil {
+		return changed, err
+	}
+	for i := range expired {
+		post := &expired[i]
+		if err := w.move(ctx, post, workflow.Archive, models.PostStatusArchived, now); err != nil {
+			utils.InitLogger().Error().Err(err).Msgf("Failed to unpublish post %d", post.ID)
+			continue
+		}
+		changed = append(changed, post)
+	}
+
+	if len(changed) > 0 {
+		if err := w.invalidate(ctx, changed...); err != nil {
+			return changed, err
+		}
+		utils.InitLogger().Info().Msgf("Scheduled publishing changed %d posts", len(changed))
+	}
+	return changed, nil
+}
+
+// move records a system-driven transition; the actor is left empty.
+func (w *PublishWorker) move(ctx context.Context, post *models.Post, transition workflow.Transition, to string, now time.Time) error {
+	from := post.Status
+	post.Status = to
+	post.StatusChangedAt = &now
+	post.StatusChangedBy = nil
+	if to == models.PostStatusPublished {
+		// Date the post by its scheduled time rather than by when the worker noticed it
+		publishedAt := now
+		if post.PublishAt != nil {
+			publishedAt = *post.PublishAt
+		}
+		post.PublishedAt = &publishedAt
+	} else {
+		// Archiving ends the schedule
+		post.UnpublishAt = nil
+	}
+	post.PublishAt = nil
+
+	return w.store.Transition(ctx, post, &models.PostTransition{
+		PostID:     post.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		Transition: string(transition),
+		Note:       "scheduled",
+		CreatedAt:  now,
+	})
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+type fakeStore struct {
+	posts       map[uint]*models.Post
+	transitions []models.PostTransition
+}
+
+func (s *fakeStore) DuePublications(ctx context.Context, now time.Time, limit int) ([]models.Post, error) {
+	var due []models.Post
+	for _, p := range s.posts {
+		if p.Status == models.PostStatusScheduled && p.PublishAt != nil && !p.PublishAt.After(now) {
+			due = append(due, *p)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeStore) DueUnpublications(ctx context.Context, now time.Time, limit int) ([]models.Post, error) {
+	var due []models.Post
+	for _, p := range s.posts {
+		if p.Status == models.PostStatusPublished && p.UnpublishAt != nil && !p.UnpublishAt.After(now) {
+			due = append(due, *p)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeStore) Transition(ctx context.Context, post *models.Post, transition *models.PostTransition) error {
+	stored := *post
+	s.posts[post.ID] = &stored
+	s.transitions = append(s.transitions, *transition)
+	return nil
+}
+
+type fakeLocker struct {
+	held bool
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	l.held = true
+	return func() { l.held = false }, true, nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestPublishWorkerTick(t *testing.T) {
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	clock := utils.NewFakeClock(start)
+	store := &fakeStore{posts: map[uint]*models.Post{
+		1: {ID: 1, Lang: "fa", Type: "post", Status: models.PostStatusScheduled, PublishAt: timePtr(start.Add(time.Hour)), UnpublishAt: timePtr(start.Add(3 * time.Hour))},
+		2: {ID: 2, Lang: "en", Type: "article", Status: models.PostStatusScheduled, PublishAt: timePtr(start.Add(2 * time.Hour))},
+		3: {ID: 3, Lang: "fa", Type: "post", Status: models.PostStatusApproved, PublishAt: timePtr(start)},
+	}}
+	var invalidated []uint
+	invalidate := func(ctx context.Context, posts ...*models.Post) error {
+		for _, p := range posts {
+			invalidated = append(invalidated, p.ID)
+		}
+		return nil
+	}
+	worker := NewPublishWorker(store, &fakeLocker{}, invalidate, clock, time.Minute)
+	ctx := context.Background()
+
+	steps := []struct {
+		advance    time.Duration
+		wantStatus map[uint]string
+		wantMoved  int
+	}{
+		{0, map[uint]string{1: models.PostStatusScheduled, 2: models.PostStatusScheduled, 3: models.PostStatusApproved}, 0},
+		{time.Hour, map[uint]string{1: models.PostStatusPublished, 2: models.PostStatusScheduled, 3: models.PostStatusApproved}, 1},
+		{time.Hour, map[uint]string{1: models.PostStatusPublished, 2: models.PostStatusPublished, 3: models.PostStatusApproved}, 1},
+		{time.Hour, map[uint]string{1: models.PostStatusArchived, 2: models.PostStatusPublished, 3: models.PostStatusApproved}, 1},
+		{time.Hour, map[uint]string{1: models.PostStatusArchived, 2: models.PostStatusPublished, 3: models.PostStatusApproved}, 0},
+	}
+
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		changed, err := worker.Tick(ctx)
+		if err != nil {
+			t.Fatalf("step %d: Tick returned %v", i, err)
+		}
+		if len(changed) != step.wantMoved {
+			t.Errorf("step %d: changed %d posts, want %d", i, len(changed), step.wantMoved)
+		}
+		for id, want := range step.wantStatus {
+			if got := store.posts[id].Status; got != want {
+				t.Errorf("step %d: post %d status = %q, want %q", i, id, got, want)
+			}
+		}
+	}
+
+	if got := store.posts[1].PublishedAt; got == nil || !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("post 1 published_at = %v, want its publish_at", got)
+	}
+	if p := store.posts[1]; p.PublishAt != nil || p.UnpublishAt != nil {
+		t.Errorf("archived post 1 kept its schedule: %v, %v", p.PublishAt, p.UnpublishAt)
+	}
+	if p := store.posts[2]; p.PublishAt != nil {
+		t.Errorf("published post 2 kept publish_at %v", p.PublishAt)
+	}
+	if len(store.transitions) != 3 {
+		t.Fatalf("recorded %d transitions, want 3", len(store.transitions))
+	}
+	for _, tr := range store.transitions {
+		if tr.ActorID != nil {
+			t.Errorf("system transition for post %d has actor %d", tr.PostID, *tr.ActorID)
+		}
+	}
+	if len(invalidated) != 3 {
+		t.Errorf("invalidated caches for %v, want three posts", invalidated)
+	}
+}
+
+func TestPublishWorkerSkipsWhenLocked(t *testing.T) {
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	store := &fakeStore{posts: map[uint]*models.Post{
+		1: {ID: 1, Status: models.PostStatusScheduled, PublishAt: timePtr(now.Add(-time.Minute))},
+	}}
+	invalidate := func(ctx context.Context, posts ...*models.Post) error { return nil }
+	worker := NewPublishWorker(store, &fakeLocker{held: true}, invalidate, utils.NewFakeClock(now), time.Minute)
+
+	changed, err := worker.Tick(context.Background())
+	if err != nil {
+		t.Fatalf("Tick returned %v", err)
+	}
+	if len(changed) != 0 || store.posts[1].Status != models.PostStatusScheduled {
+		t.Errorf("worker changed posts while another replica held the lock")
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/mailer"
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+// AccountService runs the mailed-token flows: password reset and email verification.
+// Request methods never report whether an account exists; they succeed either way.
+type AccountService struct {
+	userRepo    *repositories.UserRepository
+	tokenRepo   *repositories.AccountTokenRepository
+	authService *AuthService
+	mailer      mailer.Mailer
+	// appURL is the frontend base URL the mailed links point at
+	appURL string
+}
+
+func NewAccountService(userRepo *repositories.UserRepository, tokenRepo *repositories.AccountTokenRepository, authService *AuthService, m mailer.Mailer, appURL string) *AccountService {
+	return &AccountService{userRepo: userRepo, tokenRepo: tokenRepo, authService: authService, mailer: m, appURL: strings.TrimRight(appURL, "/")}
+}
+
+// NormalizeEmail trims and lowercases an address for storage and lookup.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// RequestPasswordReset mails a reset link if a user has verified the address. An
+// unverified address may belong to someone else, so it never receives one.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, NormalizeEmail(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.SendPasswordReset(ctx, user, false)
+	return err
+}
+
+// SendPasswordReset mails the user a reset link, reporting whether they have a
+// verified address to send it to. forced says an admin cleared their password, so
+// the link is the only way back in.
+func (s *AccountService) SendPasswordReset(ctx context.Context, user *models.User, forced bool) (bool, error) {
+	if user.Email == nil || user.EmailVerifiedAt == nil {
+		return false, nil
+	}
+
+	token, err := s.issue(ctx, user, models.AccountTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return false, err
+	}
+	body := fmt.Sprintf("Hi %s,\n\nUse the link below within an hour to choose a new password:\n\n%s\n\n"+
+		"If you didn't ask for this, you can ignore this email.\n",
+		user.Username, s.link("/reset-password", token))
+	if forced {
+		body = fmt.Sprintf("Hi %s,\n\nAn administrator has reset your password and signed you out everywhere. "+
+			"Use the link below within an hour to choose a new one:\n\n%s\n\n"+
+			"If the link expires, you can ask for a new one at %s\n",
+			user.Username, s.link("/reset-password", token), s.appURL+"/forgot-password")
+	}
+	s.send(mailer.Message{To: *user.Email, Subject: "Reset your Zyros password", Body: body})
+	return true, nil
+}
+
+// ResetPassword sets a new password with a mailed token and signs the user out of
+// every session.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	stored, err := s.tokenRepo.Consume(ctx, models.AccountTokenPasswordReset, utils.HashToken(token), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.ErrInvalidAccountToken
+	}
+	if err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, stored.UserID, string(hash)); err != nil {
+		return err
+	}
+	_, err = s.authService.RevokeOtherSessions(ctx, stored.UserID, "")
+	return err
+}
+
+// RequestEmailVerification mails a new verification link to an address that is on
+// file but not yet verified.
+func (s *AccountService) RequestEmailVerification(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, NormalizeEmail(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return s.SendEmailVerification(ctx, user)
+}
+
+// SendEmailVerification mails a verification link for the user's current address.
+func (s *AccountService) SendEmailVerification(ctx context.Context, user *models.User) error {
+	if user.Email == nil {
+		return nil
+	}
+	token, err := s.issue(ctx, user, models.AccountTokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	s.send(mailer.Message{
+		To:      *user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm that this address belongs to your Zyros account:\n\n%s\n",
+			user.Username, s.link("/verify-email", token)),
+	})
+	return nil
+}
+
+// VerifyEmail confirms the address a verification token was mailed to.
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	now := time.Now()
+	stored, err := s.tokenRepo.Consume(ctx, models.AccountTokenEmailVerification, utils.HashToken(token), now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.ErrInvalidAccountToken
+	}
+	if err != nil {
+		return err
+	}
+
+	verified, err := s.userRepo.MarkEmailVerified(ctx, stored.UserID, stored.Email, now)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return utils.ErrInvalidAccountToken
+	}
+	return nil
+}
+
+// NotifyLockout warns the owner of a locked-out username by email, if they have a
+// verified address. IP lockouts concern no one account and are not mailed.
+func (s *AccountService) NotifyLockout(ctx context.Context, lockout Lockout) {
+	if lockout.Scope != LockoutScopeUsername {
+		return
+	}
+	user, err := s.userRepo.FindByUsername(ctx, lockout.Username)
+	if err != nil || user.Email == nil || user.EmailVerifiedAt == nil {
+		return
+	}
+	s.send(mailer.Message{
+		To:      *user.Email,
+		Subject: "Sign-in to your Zyros account was paused",
+		Body: fmt.Sprintf("Hi %s,\n\nAfter %d failed password attempts, sign-in to your account is paused until %s.\n\n"+
+			"If this wasn't you, someone may be guessing your password. You can choose a new one at:\n\n%s\n",
+			user.Username, lockout.Failures, lockout.Until.UTC().Format("2006-01-02 15:04 MST"), s.appURL+"/forgot-password"),
+	})
+}
+
+// issue stores a fresh token for the user's current address and returns it in clear.
+func (s *AccountService) issue(ctx context.Context, user *models.User, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	stored := &models.AccountToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		Email:     *user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokenRepo.Replace(ctx, stored); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// send delivers in the background: the response must not take longer when an
+// account exists, and a slow relay must not hold up the request.
+func (s *AccountService) send(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			utils.InitLogger().Error().Err(err).Str("subject", msg.Subject).Msg("Failed to send account email")
+		}
+	}()
+}
+
+func (s *AccountService) link(path, token string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every API key, which is how a bearer token is told apart
+// from a JWT.
+const APIKeyPrefix = "zyk_"
+
+// IsAPIKey reports whether a bearer token is an API key rather than a JWT.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// APIKeyService manages personal API keys for scripts and integrations.
+type APIKeyService struct {
+	repo     *repositories.APIKeyRepository
+	roleRepo *repositories.RoleRepository
+}
+
+func NewAPIKeyService(repo *repositories.APIKeyRepository, roleRepo *repositories.RoleRepository) *APIKeyService {
+	return &APIKeyService{repo: repo, roleRepo: roleRepo}
+}
+
+// APIKeyInfo is a key as listed to its owner, with its scopes by name.
+type APIKeyInfo struct {
+	models.APIKey
+	Scopes []string `json:"scopes"`
+}
+
+// NewAPIKey is a freshly created key. Key is the secret itself and is never shown
+// again.
+type NewAPIKey struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
+
+// Create issues a key limited to scopes, each of which the user must hold now.
+func (s *APIKeyService) Create(ctx context.Context, user *models.User, name string, scopes []string, expiresAt *time.Time) (*NewAPIKey, error) {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, utils.ErrInvalidExpiry
+	}
+	subject := policy.NewSubject(user)
+	for _, scope := range scopes {
+		if !subject.Can(scope) {
+			return nil, utils.ErrInvalidScope
+		}
+	}
+	permissions, err := s.roleRepo.FindPermissions(ctx, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	key := APIKeyPrefix + secret
+	stored := &models.APIKey{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    key[:len(APIKeyPrefix)+6],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    permissions,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+	return &NewAPIKey{APIKeyInfo: newAPIKeyInfo(*stored), Key: key}, nil
+}
+
+// List returns a user's usable keys.
+func (s *APIKeyService) List(ctx context.Context, userID uint) ([]APIKeyInfo, error) {
+	keys, err := s.repo.ListActive(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]APIKeyInfo, len(keys))
+	for i, key := range keys {
+		infos[i] = newAPIKeyInfo(key)
+	}
+	return infos, nil
+}
+
+// Revoke disables one of a user's keys for good.
+func (s *APIKeyService) Revoke(ctx context.Context, userID, id uint) error {
+	revoked, err := s.repo.Revoke(ctx, userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return utils.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RevokeAll disables every key the user has.
+func (s *APIKeyService) RevokeAll(ctx context.Context, userID uint) error {
+	return s.repo.RevokeAll(ctx, userID, time.Now())
+}
+
+// Authenticate looks up a usable key and records its use.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*APIKeyInfo, error) {
+	now := time.Now()
+	stored, err := s.repo.FindActiveByHash(ctx, utils.HashToken(key), now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Touch(ctx, stored.ID, now); err != nil {
+		return nil, err
+	}
+	info := newAPIKeyInfo(*stored)
+	return &info, nil
+}
+
+func newAPIKeyInfo(key models.APIKey) APIKeyInfo {
+	scopes := make([]string, len(key.Scopes))
+	for i, permission := range key.Scopes {
+		scopes[i] = permission.Name
+	}
+	sort.Strings(scopes)
+	return APIKeyInfo{APIKey: key, Scopes: scopes}
+}
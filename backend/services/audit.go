@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+// AuditEvent is a security-relevant or editorial action worth keeping a record of.
+type AuditEvent struct {
+	Action string
+	// ActorID is who did it; 0 for anonymous requests and the system itself
+	ActorID    uint
+	TargetType string // "user", "post", "role", ...
+	TargetID   uint
+	// Before and After are the target's state around a change; either may be nil
+	Before    interface{}
+	After     interface{}
+	IPAddress string
+	// RequestID ties the event to the request's log lines; it is taken from the
+	// context when empty
+	RequestID string
+	Details   map[string]interface{}
+}
+
+// Auditor records audit events. Recording must not fail the action being audited,
+// so implementations report their own errors.
+type Auditor interface {
+	Record(ctx context.Context, event AuditEvent)
+}
+
+// LogAuditor writes audit events to the application log.
+type LogAuditor struct{}
+
+func (LogAuditor) Record(ctx context.Context, event AuditEvent) {
+	utils.InitLogger().Info().
+		Str("audit", event.Action).
+		Uint("actorID", event.ActorID).
+		Str("targetType", event.TargetType).
+		Uint("targetID", event.TargetID).
+		Str("ip", event.IPAddress).
+		Str("requestID", requestMetadata(ctx, event.RequestID, "requestID")).
+		Fields(event.Details).
+		Msg("Audit event")
+}
+
+// requestMetadata returns value, or when it is empty, what the request middleware
+// stored under key. Handlers pass their *gin.Context down as the context.Context,
+// whose Value looks up those keys.
+func requestMetadata(ctx context.Context, value, key string) string {
+	if value != "" {
+		return value
+	}
+	if v, ok := ctx.Value(key).(string); ok {
+		return v
+	}
+	return ""
+}
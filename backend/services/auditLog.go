@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+const (
+	auditBufferSize = 1024
+	auditBatchSize  = 100
+)
+
+// AuditLog is the Auditor that keeps events in the audit_log table. Record only
+// queues the event; Run writes the queue in batches. Should the queue fill up or a
+// write fail, events go to the application log instead of being lost.
+//
+// Entries are chained with an HMAC under key, which only the server holds, so
+// someone with write access to the database can't rewrite the chain to match.
+type AuditLog struct {
+	repo     *repositories.AuditRepository
+	clock    utils.Clock
+	key      []byte
+	queue    chan models.AuditLogEntry
+	fallback Auditor
+}
+
+func NewAuditLog(repo *repositories.AuditRepository, clock utils.Clock, key []byte) *AuditLog {
+	return &AuditLog{repo: repo, clock: clock, key: key, queue: make(chan models.AuditLogEntry, auditBufferSize), fallback: LogAuditor{}}
+}
+
+func (l *AuditLog) Record(ctx context.Context, event AuditEvent) {
+	event.IPAddress = requestMetadata(ctx, event.IPAddress, "clientIP")
+	event.RequestID = requestMetadata(ctx, event.RequestID, "requestID")
+	entry, err := newAuditEntry(event, l.clock.Now())
+	if err != nil {
+		utils.InitLogger().Error().Err(err).Str("audit", event.Action).Msg("Failed to encode audit event")
+		l.fallback.Record(ctx, event)
+		return
+	}
+
+	select {
+	case l.queue <- *entry:
+	default:
+		utils.InitLogger().Error().Str("audit", event.Action).Msg("Audit queue full, logging event instead")
+		l.fallback.Record(ctx, event)
+	}
+}
+
+// Run writes queued events until ctx is done, then writes what is left.
+func (l *AuditLog) Run(ctx context.Context) {
+	for {
+		select {
+		case entry := <-l.queue:
+			l.write(ctx, l.drain(entry))
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-l.queue:
+					l.write(context.Background(), l.drain(entry))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// drain collects first and whatever else is already queued, up to a batch.
+func (l *AuditLog) drain(first models.AuditLogEntry) []models.AuditLogEntry {
+	batch := []models.AuditLogEntry{first}
+	for len(batch) < auditBatchSize {
+		select {
+		case entry := <-l.queue:
+			batch = append(batch, entry)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+func (l *AuditLog) write(ctx context.Context, batch []models.AuditLogEntry) {
+	err := l.repo.Append(ctx, batch, func(prevHash string, entry *models.AuditLogEntry) {
+		entry.PrevHash = prevHash
+		entry.Hash = auditHash(l.key, entry)
+	})
+	if err == nil {
+		return
+	}
+	utils.InitLogger().Error().Err(err).Int("events", len(batch)).Msg("Failed to write audit log, logging events instead")
+	for _, entry := range batch {
+		utils.InitLogger().Info().
+			Str("audit", entry.Action).
+			Str("targetType", entry.TargetType).
+			RawJSON("details", orNull(entry.Details)).
+			Str("ip", entry.IPAddress).
+			Str("requestID", entry.RequestID).
+			Time("at", entry.CreatedAt).
+			Msg("Audit event")
+	}
+}
+
+// Query lists matching entries newest first, with the number of matches.
+func (l *AuditLog) Query(ctx context.Context, q repositories.AuditQuery, page, limit int) ([]models.AuditLogEntry, int64, error) {
+	return l.repo.Query(ctx, q, page, limit)
+}
+
+// Each passes matching entries to fn in log order, a batch at a time.
+func (l *AuditLog) Each(ctx context.Context, q repositories.AuditQuery, fn func([]models.AuditLogEntry) error) error {
+	return l.repo.Each(ctx, q, 500, fn)
+}
+
+// AuditVerification is the outcome of checking the hash chain.
+type AuditVerification struct {
+	Valid   bool  `json:"valid"`
+	Checked int64 `json:"checked"`
+	// BrokenAt is the first entry that doesn't match its hash or doesn't follow
+	// the entry before it
+	BrokenAt *uint64 `json:"broken_at,omitempty"`
+}
+
+// Verify recomputes the whole chain.
+func (l *AuditLog) Verify(ctx context.Context) (*AuditVerification, error) {
+	result := &AuditVerification{Valid: true}
+	prevHash := ""
+	err := l.repo.Each(ctx, repositories.AuditQuery{}, 1000, func(entries []models.AuditLogEntry) error {
+		if !result.Valid {
+			return nil
+		}
+		if i, ok := verifyAuditChain(l.key, prevHash, entries); !ok {
+			result.Valid = false
+			result.Checked += int64(i)
+			result.BrokenAt = &entries[i].ID
+			return nil
+		}
+		result.Checked += int64(len(entries))
+		prevHash = entries[len(entries)-1].Hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// verifyAuditChain checks that entries follow prevHash and each other, returning
+// the index of the first that doesn't.
+func verifyAuditChain(key []byte, prevHash string, entries []models.AuditLogEntry) (int, bool) {
+	for i := range entries {
+		if entries[i].PrevHash != prevHash || !hmac.Equal([]byte(auditHash(key, &entries[i])), []byte(entries[i].Hash)) {
+			return i, false
+		}
+		prevHash = entries[i].Hash
+	}
+	return len(entries), true
+}
+
+func newAuditEntry(event AuditEvent, now time.Time) (*models.AuditLogEntry, error) {
+	// Postgres keeps microseconds; hashing more would break the chain on read
+	entry := &models.AuditLogEntry{
+		CreatedAt:  now.UTC().Truncate(time.Microsecond),
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		IPAddress:  event.IPAddress,
+		RequestID:  event.RequestID,
+	}
+	if event.ActorID != 0 {
+		entry.ActorID = &event.ActorID
+	}
+	if event.TargetID != 0 {
+		entry.TargetID = &event.TargetID
+	}
+
+	var err error
+	if entry.Before, err = auditJSON(event.Before); err != nil {
+		return nil, err
+	}
+	if entry.After, err = auditJSON(event.After); err != nil {
+		return nil, err
+	}
+	if len(event.Details) > 0 {
+		if entry.Details, err = auditJSON(event.Details); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// auditJSON encodes v in canonical form, or returns nil for a nil v.
+func auditJSON(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(raw)
+}
+
+// canonicalJSON re-encodes raw with sorted keys and no insignificant whitespace.
+// JSONB reorders keys and reformats what it stores, so an entry is hashed over the
+// canonical form of its JSON both when it is written and when it is verified.
+func canonicalJSON(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// auditHash is the HMAC-SHA-256 under key of the entry's fields and the previous
+// entry's hash. The ID isn't covered: it is only assigned on insert, and the chain
+// already fixes each entry's position.
+func auditHash(key []byte, entry *models.AuditLogEntry) string {
+	h := hmac.New(sha256.New, key)
+	field := func(s string) {
+		// Length-prefixed, so no two different entries hash the same input
+		h.Write([]byte(strconv.Itoa(len(s))))
+		h.Write([]byte{':'})
+		h.Write([]byte(s))
+	}
+	jsonField := func(raw json.RawMessage) {
+		canonical, err := canonicalJSON(raw)
+		if err != nil {
+			canonical = raw
+		}
+		field(string(canonical))
+	}
+	optionalID := func(id *uint) {
+		if id == nil {
+			field("")
+			return
+		}
+		field(strconv.FormatUint(uint64(*id), 10))
+	}
+
+	field(entry.PrevHash)
+	field(entry.CreatedAt.UTC().Format(time.RFC3339Nano))
+	optionalID(entry.ActorID)
+	field(entry.Action)
+	field(entry.TargetType)
+	optionalID(entry.TargetID)
+	jsonField(entry.Before)
+	jsonField(entry.After)
+	jsonField(entry.Details)
+	field(entry.IPAddress)
+	field(entry.RequestID)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func orNull(raw json.RawMessage) []byte {
+	if len(raw) == 0 {
+		return []byte("null")
+	}
+	return raw
+}
+
+// AuditCSVHeader names the columns of AuditCSVRow.
+var AuditCSVHeader = []string{"id", "created_at", "actor_id", "action", "target_type", "target_id", "before", "after", "details", "ip_address", "request_id", "prev_hash", "hash"}
+
+// AuditCSVRow formats an entry for a CSV export.
+func AuditCSVRow(entry *models.AuditLogEntry) []string {
+	optionalID := func(id *uint) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*id), 10)
+	}
+	return []string{
+		strconv.FormatUint(entry.ID, 10),
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		optionalID(entry.ActorID),
+		csvSafe(entry.Action),
+		csvSafe(entry.TargetType),
+		optionalID(entry.TargetID),
+		csvSafe(string(entry.Before)),
+		csvSafe(string(entry.After)),
+		csvSafe(string(entry.Details)),
+		csvSafe(entry.IPAddress),
+		csvSafe(entry.RequestID),
+		entry.PrevHash,
+		entry.Hash,
+	}
+}
+
+// csvSafe stops spreadsheets from running a cell as a formula. Usernames and
+// other user input end up in the details, so a cell may start with anything.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
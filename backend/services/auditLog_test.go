@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+)
+
+var testAuditKey = []byte("audit-key")
+
+func sealedChain(t *testing.T, events ...AuditEvent) []models.AuditLogEntry {
+	t.Helper()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	entries := make([]models.AuditLogEntry, len(events))
+	prevHash := ""
+	for i, event := range events {
+		entry, err := newAuditEntry(event, now.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.ID = uint64(i + 1)
+		entry.PrevHash = prevHash
+		entry.Hash = auditHash(testAuditKey, entry)
+		prevHash = entry.Hash
+		entries[i] = *entry
+	}
+	return entries
+}
+
+func TestAuditChain(t *testing.T) {
+	entries := sealedChain(t,
+		AuditEvent{Action: "auth.login", ActorID: 1, TargetType: "user", TargetID: 1, IPAddress: "10.0.0.1", RequestID: "req-1"},
+		AuditEvent{Action: "post.update", ActorID: 1, TargetType: "post", TargetID: 7,
+			Before: map[string]interface{}{"title": "Old", "status": "draft"},
+			After:  map[string]interface{}{"title": "New", "status": "draft"}},
+		AuditEvent{Action: "user.ban", ActorID: 1, TargetType: "user", TargetID: 2, Details: map[string]interface{}{"reason": "spam"}},
+	)
+	if i, ok := verifyAuditChain(testAuditKey, "", entries); !ok {
+		t.Fatalf("intact chain broken at %d", i)
+	}
+
+	// Postgres hands JSONB back with its own key order and spacing
+	stored := make([]models.AuditLogEntry, len(entries))
+	copy(stored, entries)
+	stored[1].Before = json.RawMessage(`{"status": "draft", "title": "Old"}`)
+	if _, ok := verifyAuditChain(testAuditKey, "", stored); !ok {
+		t.Error("reformatted JSON broke the chain")
+	}
+
+	tampered := func(name string, i int, change func(*models.AuditLogEntry)) {
+		t.Helper()
+		chain := make([]models.AuditLogEntry, len(entries))
+		copy(chain, entries)
+		change(&chain[i])
+		if at, ok := verifyAuditChain(testAuditKey, "", chain); ok || at != i {
+			t.Errorf("%s: verify = %d, %v, want break at %d", name, at, ok, i)
+		}
+	}
+	tampered("edited details", 2, func(e *models.AuditLogEntry) { e.Details = json.RawMessage(`{"reason":"abuse"}`) })
+	tampered("edited after", 1, func(e *models.AuditLogEntry) { e.After = json.RawMessage(`{"status":"draft","title":"Other"}`) })
+	tampered("cleared actor", 0, func(e *models.AuditLogEntry) { e.ActorID = nil })
+	tampered("moved in time", 1, func(e *models.AuditLogEntry) { e.CreatedAt = e.CreatedAt.Add(-time.Hour) })
+
+	// Removing an entry breaks the link of the one after it, even with hashes
+	// recomputed for the entry itself
+	withoutFirst := entries[1:]
+	if at, ok := verifyAuditChain(testAuditKey, "", withoutFirst); ok || at != 0 {
+		t.Errorf("deleted entry not detected: %d, %v", at, ok)
+	}
+
+	// A chain rebuilt without the server's key doesn't verify either
+	forged := make([]models.AuditLogEntry, len(entries))
+	copy(forged, entries)
+	prevHash := ""
+	for i := range forged {
+		forged[i].PrevHash = prevHash
+		forged[i].Hash = auditHash([]byte("guessed"), &forged[i])
+		prevHash = forged[i].Hash
+	}
+	if at, ok := verifyAuditChain(testAuditKey, "", forged); ok || at != 0 {
+		t.Errorf("chain rebuilt with another key verified: %d, %v", at, ok)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	a, err := canonicalJSON(json.RawMessage(`{"b": 1, "a": {"y": [1, 2.50], "x": "<b>"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := canonicalJSON(json.RawMessage(`{"a":{"x":"<b>","y":[1,2.50]},"b":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("canonical forms differ: %s and %s", a, b)
+	}
+	if raw, _ := canonicalJSON(nil); raw != nil {
+		t.Errorf("empty JSON = %s, want nil", raw)
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	tests := map[string]string{
+		"post.create":       "post.create",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-2+3":              "'-2+3",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"":                  "",
+		`{"username":"=1"}`: `{"username":"=1"}`,
+	}
+	for in, want := range tests {
+		if got := csvSafe(in); got != want {
+			t.Errorf("csvSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
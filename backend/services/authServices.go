@@ -2,146 +2,828 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 	"strconv"
-	"net/http"
+	"sync"
 	
 	"github.com/alimosavifard/zyros-backend/config"
+	"github.com/alimosavifard/zyros-backend/keyring"
 	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
 	"github.com/alimosavifard/zyros-backend/repositories"
 	"github.com/alimosavifard/zyros-backend/utils"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AuthService struct {
 	userRepo    *repositories.UserRepository
 	roleRepo    *repositories.RoleRepository
+	sessionRepo *repositories.SessionRepository
+	refreshRepo *repositories.RefreshTokenRepository
+	mfa         *MFAService
+	guard       *LoginGuard
+	apiKeys     *APIKeyService
+	permissions *PermissionCache
+	auditor     Auditor
 	redisClient *redis.Client
 	jwtSecret   string
 	jwtExp      time.Duration
+	refreshExp  time.Duration
+	// keys signs access tokens when set; otherwise they are signed HS256 with jwtSecret
+	keys *keyring.Keyring
+	// acceptHS256 keeps HS256 tokens valid while moving to asymmetric keys
+	acceptHS256 bool
+	// reloadKeys refreshes keys from the store when a token names an unknown kid
+	reloadKeys     KeyReloader
+	reloadMu       sync.Mutex
+	keysReloadedAt time.Time
 }
 
-func NewAuthService(userRepo *repositories.UserRepository, roleRepo *repositories.RoleRepository, redisClient *redis.Client, cfg *config.Config) *AuthService {
+// KeyReloader refreshes the keyring from the stored signing keys.
+type KeyReloader func(ctx context.Context) error
+
+// keyReloadInterval is the least time between reloads for unknown kids, so tokens
+// with made-up kids can't send every request to the database.
+const keyReloadInterval = 5 * time.Second
+
+func NewAuthService(userRepo *repositories.UserRepository, roleRepo *repositories.RoleRepository, sessionRepo *repositories.SessionRepository, refreshRepo *repositories.RefreshTokenRepository, mfa *MFAService, guard *LoginGuard, apiKeys *APIKeyService, keys *keyring.Keyring, redisClient *redis.Client, auditor Auditor, cfg *config.Config) *AuthService {
 	jwtExp, err := time.ParseDuration(cfg.JWT_EXPIRATION)
 	if err != nil {
 		utils.InitLogger().Fatal().Err(err).Msg("Invalid JWT_EXPIRATION format")
 	}
 
+	refreshExp := 30 * 24 * time.Hour
+	if cfg.REFRESH_TOKEN_EXPIRATION != "" {
+		if refreshExp, err = time.ParseDuration(cfg.REFRESH_TOKEN_EXPIRATION); err != nil {
+			utils.InitLogger().Fatal().Err(err).Msg("Invalid REFRESH_TOKEN_EXPIRATION format")
+		}
+	}
+
 	return &AuthService{
 		userRepo:    userRepo,
 		roleRepo:    roleRepo,
+		sessionRepo: sessionRepo,
+		refreshRepo: refreshRepo,
+		mfa:         mfa,
+		guard:       guard,
+		apiKeys:     apiKeys,
+		permissions: NewPermissionCache(NewRedisPermissionStore(redisClient), userRepo.Permissions, utils.SystemClock{}, permissionCacheSize, permissionLocalTTL),
+		auditor:     auditor,
 		redisClient: redisClient,
 		jwtSecret:   cfg.JWT_SECRET,
 		jwtExp:      jwtExp,
+		refreshExp:  refreshExp,
+		keys:        keys,
+		acceptHS256: keys == nil || (cfg.JWT_ACCEPT_HS256 == "true" && cfg.JWT_SECRET != ""),
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, user *models.User) (string, error) {
-	if s.jwtSecret == "" {
-		return "", errors.New("JWT_SECRET is not set")
+// OnUnknownKey sets how signing keys are reloaded when a token's kid isn't in the
+// keyring, e.g. because another replica just created it. It is a setter because
+// the rotator is only started when asymmetric keys are configured.
+func (s *AuthService) OnUnknownKey(reload KeyReloader) {
+	s.reloadKeys = reload
+}
+
+// ClientInfo describes the device a request comes from, as recorded on its session.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// TokenPair is what a client receives on login: a short-lived access token and the
+// refresh token that obtains the next one.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+func (s *AuthService) Register(ctx context.Context, user *models.User, client ClientInfo) (*TokenPair, error) {
+	if s.keys == nil && s.jwtSecret == "" {
+		return nil, errors.New("JWT_SECRET is not set")
+	}
+
+	if err := s.prepareUser(ctx, user); err != nil {
+		return nil, err
 	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if repositories.IsUniqueViolation(err, "") {
+			return nil, utils.ErrAccountExists
+		}
+		return nil, err
+	}
+	s.audit(ctx, "auth.register", user.ID, client, map[string]interface{}{"username": user.Username})
+	return s.startSession(ctx, user, client)
+}
 
+// prepareUser hashes a new user's password and gives them the default role, ready
+// to be created.
+func (s *AuthService) prepareUser(ctx context.Context, user *models.User) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return "", err
+		return err
 	}
 	user.Password = string(hashedPassword)
 
 	defaultRole, err := s.roleRepo.FindByName(ctx, "user")
 	if err != nil {
-		return "", errors.New("default role not found")
+		return errors.New("default role not found")
 	}
 	user.Roles = append(user.Roles, *defaultRole)
+	return nil
+}
 
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		return "", err
-	}
-	return s.generateToken(user.ID, user.Username)
+// LoginResult is the outcome of a correct password or external login: either a
+// session, or a challenge to answer with a second factor before one is started.
+type LoginResult struct {
+	Tokens    *TokenPair
+	Challenge *MFAChallenge
+}
+
+// MFAChallenge stands in for the session between the password and the second factor.
+// EnrollmentRequired means the account must set up an authenticator before it can
+// answer.
+type MFAChallenge struct {
+	Token              string    `json:"mfa_token"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	EnrollmentRequired bool      `json:"enrollment_required"`
 }
 
-func (s *AuthService) Login(ctx context.Context, username, password string) (string, error) {
-	if s.jwtSecret == "" {
-		return "", errors.New("JWT_SECRET is not set")
+const (
+	mfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+)
+
+// dummyPasswordHash is checked when the username doesn't exist, so that a login for
+// an unknown user takes as long as one with a wrong password.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("zyros-no-such-user"), bcrypt.DefaultCost)
+
+// Login checks a password. Every failure looks the same whether or not the username
+// exists, and a locked-out login is refused even with the right password, so
+// neither reveals which accounts exist.
+func (s *AuthService) Login(ctx context.Context, username, password string, client ClientInfo) (*LoginResult, error) {
+	if s.keys == nil && s.jwtSecret == "" {
+		return nil, errors.New("JWT_SECRET is not set")
 	}
 	
+	if err := s.checkGuard(ctx, username, client); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.FindByUsername(ctx, username)
 	if err != nil {
-		utils.SendError(ctx, http.StatusInternalServerError, "invalid credentials", err)
-		return ""        
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, s.loginFailed(ctx, username, client)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		utils.SendError(ctx, http.StatusInternalServerError, "invalid credentials", err)
-		return ""
+		return nil, s.loginFailed(ctx, username, client)
+	}
+	result, err := s.finishLogin(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	// With a second factor still to answer, the failures stay counted until it passes
+	if result.Tokens != nil {
+		if err := s.guard.Succeed(ctx, username); err != nil {
+			return nil, err
+		}
 	}
+	return result, nil
+}
+
+// checkGuard refuses a login step while the username or IP is locked out.
+func (s *AuthService) checkGuard(ctx context.Context, username string, client ClientInfo) error {
+	wait, err := s.guard.Check(ctx, username, client.IPAddress)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// finishLogin starts the session for a user who has proven who they are, or the
+// second-factor challenge when their account needs one.
+func (s *AuthService) finishLogin(ctx context.Context, user *models.User, client ClientInfo) (*LoginResult, error) {
+	if user.IsSuspended(time.Now()) {
+		return nil, utils.ErrAccountSuspended
+	}
+	enabled, err := s.mfa.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled || s.mfa.Required(user) {
+		challenge, err := s.newChallenge(ctx, user.ID, !enabled)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{Challenge: challenge}, nil
+	}
+
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokens}, nil
+}
 
-	return s.generateToken(user.ID, user.Username)
+// mfaFailed spends an attempt of the challenge and counts the wrong code towards
+// lockout like a wrong password, so fresh challenges don't give guessing a way round it.
+func (s *AuthService) mfaFailed(ctx context.Context, challenge string, user *models.User, client ClientInfo) {
+	s.failChallenge(ctx, challenge)
+	s.audit(ctx, "auth.mfa_failed", user.ID, client, map[string]interface{}{"username": user.Username})
+	if err := s.guard.Fail(ctx, user.Username, client.IPAddress); err != nil {
+		utils.InitLogger().Error().Err(err).Msg("Failed to record MFA failure")
+	}
+}
+
+// loginFailed records a failed attempt and counts it towards lockout.
+func (s *AuthService) loginFailed(ctx context.Context, username string, client ClientInfo) error {
+	s.audit(ctx, "auth.login_failed", 0, client, map[string]interface{}{"username": username})
+	if err := s.guard.Fail(ctx, username, client.IPAddress); err != nil {
+		return err
+	}
+	return utils.ErrInvalidCredentials
+}
+
+// UnlockLogin lifts a lockout on a user's username on behalf of an admin.
+func (s *AuthService) UnlockLogin(ctx context.Context, actorID, userID uint, client ClientInfo) error {
+	user, err := s.FindUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.guard.Unlock(ctx, user, actorID, client.IPAddress)
 }
 
-func (s *AuthService) generateToken(userID uint, username string) (string, error) {
+// VerifyMFA answers a login challenge with a TOTP or recovery code and starts the
+// session. A challenge allows a few wrong codes before it is discarded.
+func (s *AuthService) VerifyMFA(ctx context.Context, challenge, code string, client ClientInfo) (*TokenPair, error) {
+	userID, enroll, err := s.loadChallenge(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if enroll {
+		return nil, utils.ErrMFANotEnabled
+	}
+	user, err := s.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGuard(ctx, user.Username, client); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfa.Verify(ctx, userID, code); err != nil {
+		if errors.Is(err, utils.ErrInvalidMFACode) {
+			s.mfaFailed(ctx, challenge, user, client)
+		}
+		return nil, err
+	}
+	tokens, err := s.completeChallenge(ctx, challenge, userID, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guard.Succeed(ctx, user.Username); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// BeginMFAEnrollment starts authenticator setup for an account that has to enroll
+// before its login can finish.
+func (s *AuthService) BeginMFAEnrollment(ctx context.Context, challenge string) (*MFASetup, error) {
+	userID, enroll, err := s.loadChallenge(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if !enroll {
+		return nil, utils.ErrMFAAlreadyEnabled
+	}
+	user, err := s.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.mfa.Setup(ctx, user)
+}
+
+// CompleteMFAEnrollment confirms the setup begun with BeginMFAEnrollment and starts
+// the session. It also returns the new recovery codes.
+func (s *AuthService) CompleteMFAEnrollment(ctx context.Context, challenge, code string, client ClientInfo) (*TokenPair, []string, error) {
+	userID, enroll, err := s.loadChallenge(ctx, challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !enroll {
+		return nil, nil, utils.ErrMFAAlreadyEnabled
+	}
+	user, err := s.FindUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkGuard(ctx, user.Username, client); err != nil {
+		return nil, nil, err
+	}
+
+	codes, err := s.mfa.Enable(ctx, userID, code)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidMFACode) {
+			s.mfaFailed(ctx, challenge, user, client)
+		}
+		return nil, nil, err
+	}
+	tokens, err := s.completeChallenge(ctx, challenge, userID, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.guard.Succeed(ctx, user.Username); err != nil {
+		return nil, nil, err
+	}
+	return tokens, codes, nil
+}
+
+// newChallenge stores a login challenge in Redis under the hash of its token.
+func (s *AuthService) newChallenge(ctx context.Context, userID uint, enroll bool) (*MFAChallenge, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	key := mfaChallengeKey(token)
+	_, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "userID", userID, "enroll", enroll, "attempts", 0)
+		pipe.Expire(ctx, key, mfaChallengeTTL)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{Token: token, ExpiresAt: time.Now().Add(mfaChallengeTTL), EnrollmentRequired: enroll}, nil
+}
+
+func (s *AuthService) loadChallenge(ctx context.Context, token string) (uint, bool, error) {
+	fields, err := s.redisClient.HGetAll(ctx, mfaChallengeKey(token)).Result()
+	if err != nil {
+		return 0, false, err
+	}
+	userID, err := strconv.ParseUint(fields["userID"], 10, 32)
+	if err != nil {
+		return 0, false, utils.ErrInvalidMFAChallenge
+	}
+	return uint(userID), fields["enroll"] == "1", nil
+}
+
+// failChallenge counts a wrong code and discards the challenge after too many.
+func (s *AuthService) failChallenge(ctx context.Context, token string) {
+	key := mfaChallengeKey(token)
+	var attempts *redis.IntCmd
+	var ttl *redis.DurationCmd
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		attempts = pipe.HIncrBy(ctx, key, "attempts", 1)
+		ttl = pipe.TTL(ctx, key)
+		return nil
+	})
+	// No TTL means the challenge expired just before the increment recreated it
+	if err == nil && (attempts.Val() >= mfaChallengeMaxAttempts || ttl.Val() < 0) {
+		err = s.redisClient.Del(ctx, key).Err()
+	}
+	if err != nil {
+		utils.InitLogger().Error().Err(err).Msg("Failed to record MFA attempt")
+	}
+}
+
+// completeChallenge spends the challenge and starts the session. Deleting the key is
+// what claims it, so a challenge answered twice at once yields one session.
+func (s *AuthService) completeChallenge(ctx context.Context, token string, userID uint, client ClientInfo) (*TokenPair, error) {
+	deleted, err := s.redisClient.Del(ctx, mfaChallengeKey(token)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, utils.ErrInvalidMFAChallenge
+	}
+	user, err := s.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, user, client)
+}
+
+// Refresh exchanges a refresh token for a new pair. Each refresh token works once:
+// presenting a used one means it was stolen or replayed, so the whole family is
+// revoked and the user has to log in again.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	now := time.Now()
+	hash := utils.HashToken(refreshToken)
+
+	current, err := s.refreshRepo.Consume(ctx, hash, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		stored, findErr := s.refreshRepo.FindByHash(ctx, hash)
+		if findErr == nil && stored.UsedAt != nil {
+			if err := s.revokeSessions(ctx, now, stored.FamilyID); err != nil {
+				return nil, err
+			}
+			utils.InitLogger().Warn().Uint("userID", stored.UserID).Str("family", stored.FamilyID).
+				Msg("Refresh token reuse detected, session revoked")
+			return nil, utils.ErrRefreshTokenReused
+		}
+		return nil, utils.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, current.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.IsSuspended(now) {
+		return nil, utils.ErrAccountSuspended
+	}
+
+	tokens, err := s.issueTokens(ctx, user, current.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.Touch(ctx, current.FamilyID, client.UserAgent, client.IPAddress, tokens.RefreshExpiresAt, now); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Logout revokes the session behind the given tokens; either may be empty. The access
+// token stops working at once rather than at its expiry.
+func (s *AuthService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	now := time.Now()
+	if refreshToken != "" {
+		stored, err := s.refreshRepo.FindByHash(ctx, utils.HashToken(refreshToken))
+		if err == nil {
+			if err := s.revokeSessions(ctx, now, stored.FamilyID); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	if accessToken == "" {
+		return nil
+	}
+	claims, err := s.parseToken(ctx, accessToken)
+	if err != nil {
+		return nil // an invalid access token grants nothing anyway
+	}
+	if sid, _ := claims["sid"].(string); sid != "" {
+		if err := s.revokeSessions(ctx, now, sid); err != nil {
+			return err
+		}
+	}
+	if jti, _ := claims["jti"].(string); jti != "" {
+		exp, _ := claims["exp"].(float64)
+		if ttl := time.Until(time.Unix(int64(exp), 0)); ttl > 0 {
+			return s.redisClient.Set(ctx, revokedTokenKey(jti), 1, ttl).Err()
+		}
+	}
+	return nil
+}
+
+// startSession records a new login and opens its refresh token family.
+func (s *AuthService) startSession(ctx context.Context, user *models.User, client ClientInfo) (*TokenPair, error) {
+	// Checked again here for logins that went through a second-factor challenge
+	if user.IsSuspended(time.Now()) {
+		return nil, utils.ErrAccountSuspended
+	}
+	sessionID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.refreshExp),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "auth.login", user.ID, client, map[string]interface{}{"user_agent": client.UserAgent})
+	return s.issueTokens(ctx, user, sessionID)
+}
+
+// audit records an authentication event about userID, who is also its actor.
+func (s *AuthService) audit(ctx context.Context, action string, userID uint, client ClientInfo, details map[string]interface{}) {
+	event := AuditEvent{Action: action, ActorID: userID, IPAddress: client.IPAddress, Details: details}
+	if userID != 0 {
+		event.TargetType, event.TargetID = "user", userID
+	}
+	s.auditor.Record(ctx, event)
+}
+
+// issueTokens stores a new refresh token in the family and signs a matching access token.
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, familyID string) (*TokenPair, error) {
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	stored := &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: now.Add(s.refreshExp),
+	}
+	if err := s.refreshRepo.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.generateToken(user.ID, user.Username, familyID, now)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  now.Add(s.jwtExp),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// revokeSessions ends sessions and their refresh tokens and, for the lifetime of the
+// access tokens already handed out, denylists every access token bound to them.
+func (s *AuthService) revokeSessions(ctx context.Context, now time.Time, ids ...string) error {
+	if err := s.sessionRepo.Revoke(ctx, ids, now); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.redisClient.Set(ctx, revokedSessionKey(id), 1, s.jwtExp).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AuthService) generateToken(userID uint, username, sessionID string, now time.Time) (string, error) {
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
 	claims := jwt.MapClaims{
 		"userID":   userID,
 		"username": username,
-		"exp":      time.Now().Add(s.jwtExp).Unix(),
+		"sid":      sessionID,
+		"jti":      jti,
+		"iat":      now.Unix(),
+		"exp":      now.Add(s.jwtExp).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
-	if err != nil {
-		return "", err
+	if s.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.jwtSecret))
+	}
+
+	key, ok := s.keys.Signing(now)
+	if !ok {
+		return "", errors.New("no signing key available")
+	}
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// JWKS returns the public keys other services verify access tokens with. It is
+// empty while tokens are signed HS256.
+func (s *AuthService) JWKS() keyring.JWKSet {
+	if s.keys == nil {
+		return keyring.JWKSet{Keys: []keyring.JWK{}}
 	}
-	return tokenString, nil
+	return s.keys.JWKS(time.Now())
+}
+
+// AccessClaims identifies who an access token was issued to. A request made with an
+// API key has no session; it carries the key's ID and scopes instead.
+type AccessClaims struct {
+	UserID    uint
+	SessionID string
+	APIKeyID  uint
+	Scopes    []string
 }
 
+// ValidateToken verifies an access token and returns its user.
 func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (uint, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+	claims, err := s.Authenticate(ctx, tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// Authenticate verifies an access token or API key. Tokens revoked by logout, or
+// belonging to a revoked session, are rejected. Suspending a user revokes their
+// sessions, and their API keys stop working at once.
+func (s *AuthService) Authenticate(ctx context.Context, tokenString string) (*AccessClaims, error) {
+	if IsAPIKey(tokenString) {
+		key, err := s.apiKeys.Authenticate(ctx, tokenString)
+		if err != nil {
+			return nil, err
 		}
-		return []byte(s.jwtSecret), nil
-	})
+		return &AccessClaims{UserID: key.UserID, APIKeyID: key.ID, Scopes: key.Scopes}, nil
+	}
+
+	claims, err := s.parseToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := claims["userID"].(float64)
+	if !ok {
+		return nil, errors.New("invalid userID")
+	}
+	jti, _ := claims["jti"].(string)
+	sid, _ := claims["sid"].(string)
+	if jti == "" || sid == "" {
+		return nil, errors.New("token is not bound to a session")
+	}
+
+	revoked, err := s.redisClient.Exists(ctx, revokedTokenKey(jti), revokedSessionKey(sid)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked > 0 {
+		return nil, utils.ErrTokenRevoked
+	}
+	return &AccessClaims{UserID: uint(userID), SessionID: sid}, nil
+}
+
+// FindUser loads a user with their roles.
+func (s *AuthService) FindUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrUserNotFound
+	}
+	return user, err
+}
 
+// SessionInfo is an active session as shown to its owner.
+type SessionInfo struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// GetSessions lists a user's active sessions, flagging currentID as the one the
+// request was made from.
+func (s *AuthService) GetSessions(ctx context.Context, userID uint, currentID string) ([]SessionInfo, error) {
+	sessions, err := s.sessionRepo.ListActive(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]SessionInfo, len(sessions))
+	for i, session := range sessions {
+		infos[i] = SessionInfo{Session: session, Current: session.ID == currentID}
+	}
+	return infos, nil
+}
+
+// RevokeSession signs a user out of one of their sessions.
+func (s *AuthService) RevokeSession(ctx context.Context, userID uint, sessionID string) error {
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && session.UserID != userID) {
+		return utils.ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.revokeSessions(ctx, time.Now(), session.ID)
+}
+
+// RevokeOtherSessions signs a user out everywhere except keepID, which may be empty
+// to end every session. It returns how many sessions were ended.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID uint, keepID string) (int, error) {
+	now := time.Now()
+	sessions, err := s.sessionRepo.ListActive(ctx, userID, now)
 	if err != nil {
 		return 0, err
 	}
+	var ids []string
+	for _, session := range sessions {
+		if session.ID != keepID {
+			ids = append(ids, session.ID)
+		}
+	}
+	return len(ids), s.revokeSessions(ctx, now, ids...)
+}
+
+func (s *AuthService) parseToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return s.verificationKey(ctx, token)
+	})
+
+	if err != nil {
+		return nil, err
+	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, ok := claims["userID"].(float64)
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+// reloadUnknownKey reloads the signing keys unless that was done within the last
+// keyReloadInterval, and reports whether the keyring may have changed. Callers
+// waiting on a reload in progress look again once it is done.
+func (s *AuthService) reloadUnknownKey(ctx context.Context) bool {
+	if s.reloadKeys == nil {
+		return false
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if time.Since(s.keysReloadedAt) < keyReloadInterval {
+		return true
+	}
+	s.keysReloadedAt = time.Now()
+	if err := s.reloadKeys(ctx); err != nil {
+		utils.InitLogger().Warn().Err(err).Msg("Failed to reload signing keys")
+		return false
+	}
+	return true
+}
+
+// verificationKey picks the key a token is checked with: the asymmetric key named
+// by its kid, or the shared secret for HS256 while that is still accepted. An
+// unknown kid reloads the keys once before the token is rejected.
+func (s *AuthService) verificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if !s.acceptHS256 {
+			return nil, errors.New("HS256 tokens are no longer accepted")
+		}
+		return []byte(s.jwtSecret), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodEd25519:
+		if s.keys == nil {
+			return nil, errors.New("unexpected signing method")
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys.Find(kid, time.Now())
+		if !ok && s.reloadUnknownKey(ctx) {
+			key, ok = s.keys.Find(kid, time.Now())
+		}
 		if !ok {
-			return 0, errors.New("invalid userID")
+			return nil, errors.New("unknown signing key")
 		}
-		return uint(userID), nil
+		if key.Method().Alg() != token.Method.Alg() {
+			return nil, errors.New("signing method does not match key")
+		}
+		return key.Public(), nil
 	}
-	return 0, errors.New("invalid token")
+	return nil, errors.New("unexpected signing method")
+}
+
+func revokedTokenKey(jti string) string {
+	return "revoked_jti:" + jti
+}
+
+func revokedSessionKey(familyID string) string {
+	return "revoked_session:" + familyID
+}
+
+func mfaChallengeKey(token string) string {
+	return "mfa_challenge:" + utils.HashToken(token)
 }
 
+// HasPermission reports whether any of the user's roles grants permission.
 func (s *AuthService) HasPermission(ctx context.Context, userID uint, permission string) (bool, error) {
-	cacheKey := s.getPermissionsCacheKey(userID)
-	cachedData, err := s.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var data map[string]interface{}
-		if json.Unmarshal([]byte(cachedData), &data) == nil {
-			if perms, ok := data["permissions"].([]interface{}); ok {
-				for _, perm := range perms {
-					if p, ok := perm.(string); ok && p == permission {
-						return true, nil
-					}
-				}
-			}
-		}
-	}
+	return s.permissions.Has(ctx, userID, permission)
+}
 
-	hasPermission, err := s.userRepo.HasPermission(ctx, userID, permission)
+// Subject loads the user with their roles and permissions for policy checks.
+func (s *AuthService) Subject(ctx context.Context, userID uint) (*policy.Subject, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return hasPermission, nil
+	return policy.NewSubject(user), nil
+}
+
+// InvalidatePermissions makes the cached permissions of the given users stale,
+// after their roles change.
+func (s *AuthService) InvalidatePermissions(ctx context.Context, userIDs ...uint) error {
+	return s.permissions.InvalidateUsers(ctx, userIDs...)
 }
 
-func (s *AuthService) getPermissionsCacheKey(userID uint) string {
-	return "user_permissions:" + strconv.FormatUint(uint64(userID), 10)
-}
\ No newline at end of file
+// InvalidateAllPermissions makes every user's cached permissions stale, after a
+// role's permissions change.
+func (s *AuthService) InvalidateAllPermissions(ctx context.Context) error {
+	return s.permissions.InvalidateAll(ctx)
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/microcosm-cc/bluemonday"
+	"gorm.io/gorm"
+)
+
+// commentPolicy is stricter than the UGC policy posts are cleaned with: comments keep
+// inline emphasis, code, quotes and nofollow links, and nothing else.
+var commentPolicy = newCommentPolicy()
+
+func newCommentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "b", "strong", "i", "em", "code", "pre", "blockquote")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowStandardURLs()
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// CommentAuthor is the public face of a commenter.
+type CommentAuthor struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// CommentResponse is a comment with its replies. Deleted or hidden comments that
+// still have visible replies are kept as tombstones without author, body or status.
+type CommentResponse struct {
+	ID        uint               `json:"id"`
+	PostID    uint               `json:"post_id"`
+	ParentID  *uint              `json:"parent_id"`
+	Author    *CommentAuthor     `json:"author,omitempty"`
+	Body      string             `json:"body"`
+	Status    string             `json:"status,omitempty"`
+	Deleted   bool               `json:"deleted,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	Replies   []*CommentResponse `json:"replies"`
+}
+
+// ModerationItem is a comment waiting in the moderation queue.
+type ModerationItem struct {
+	models.Comment
+	Author    CommentAuthor `json:"author"`
+	PostTitle string        `json:"post_title"`
+	PostSlug  string        `json:"post_slug"`
+	PostLang  string        `json:"post_lang"`
+}
+
+type CommentService struct {
+	repo   *repositories.CommentRepository
+	posts  *PostService
+	policy *policy.Policy
+}
+
+func NewCommentService(repo *repositories.CommentRepository, posts *PostService, commentPolicy *policy.Policy) *CommentService {
+	return &CommentService{repo: repo, posts: posts, policy: commentPolicy}
+}
+
+// GetComments returns the thread of a post the viewer may see, with top-level
+// comments paginated oldest first. total counts the top-level comments.
+func (s *CommentService) GetComments(ctx context.Context, postID uint, page, limit int, viewer *policy.Subject) ([]*CommentResponse, int, error) {
+	if _, err := s.posts.FindVisiblePost(ctx, postID, viewer); err != nil {
+		return nil, 0, err
+	}
+
+	var viewerID uint
+	if viewer != nil {
+		viewerID = viewer.UserID
+	}
+	roots, total, err := s.repo.ListRootsForPost(ctx, postID, viewerID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	rootIDs := make([]uint, len(roots))
+	for i := range roots {
+		rootIDs[i] = roots[i].ID
+	}
+	replies, err := s.repo.ListReplies(ctx, rootIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return buildCommentTree(append(roots, replies...), viewerID), int(total), nil
+}
+
+// buildCommentTree nests comments under their parents. Readers see approved comments
+// and their own; deleted comments and those hidden from viewerID become tombstones
+// when they have visible replies and are dropped otherwise.
+func buildCommentTree(comments []models.Comment, viewerID uint) []*CommentResponse {
+	nodes := make(map[uint]*CommentResponse, len(comments))
+	for i := range comments {
+		comment := &comments[i]
+		visible := comment.Status == models.CommentStatusApproved || (viewerID != 0 && comment.UserID == viewerID)
+		if comment.DeletedAt.Valid || !visible {
+			nodes[comment.ID] = newTombstone(comment)
+		} else {
+			nodes[comment.ID] = newCommentResponse(comment)
+		}
+	}
+
+	var roots []*CommentResponse
+	for i := range comments {
+		node := nodes[comments[i].ID]
+		if node.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*node.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+	return pruneTombstones(roots)
+}
+
+// pruneTombstones removes tombstones that have no remaining replies.
+func pruneTombstones(nodes []*CommentResponse) []*CommentResponse {
+	kept := make([]*CommentResponse, 0, len(nodes))
+	for _, node := range nodes {
+		node.Replies = pruneTombstones(node.Replies)
+		if node.Deleted && len(node.Replies) == 0 {
+			continue
+		}
+		kept = append(kept, node)
+	}
+	return kept
+}
+
+func newCommentResponse(comment *models.Comment) *CommentResponse {
+	return &CommentResponse{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		ParentID:  comment.ParentID,
+		Author:    &CommentAuthor{ID: comment.User.ID, Username: comment.User.Username},
+		Body:      comment.Body,
+		Status:    comment.Status,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		Replies:   []*CommentResponse{},
+	}
+}
+
+// newTombstone keeps a comment's place in the thread without revealing it.
+func newTombstone(comment *models.Comment) *CommentResponse {
+	return &CommentResponse{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		ParentID:  comment.ParentID,
+		Deleted:   true,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		Replies:   []*CommentResponse{},
+	}
+}
+
+// CreateComment posts a comment on a published post. Comments start in the moderation
+// queue unless their author is a moderator.
+func (s *CommentService) CreateComment(ctx context.Context, postID uint, actor *policy.Subject, body string, parentID *uint) (*CommentResponse, error) {
+	post, err := s.posts.FindVisiblePost(ctx, postID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status != models.PostStatusPublished {
+		return nil, utils.ErrCommentsClosed
+	}
+
+	body, err = sanitizeComment(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var rootID *uint
+	if parentID != nil {
+		// Replies only attach to comments readers can see on the same post
+		parent, err := s.repo.FindByID(ctx, *parentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrInvalidCommentParent
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parent.PostID != post.ID || parent.Status != models.CommentStatusApproved {
+			return nil, utils.ErrInvalidCommentParent
+		}
+		rootID = parent.RootID
+		if rootID == nil {
+			rootID = &parent.ID
+		}
+	}
+
+	comment := &models.Comment{
+		PostID:   post.ID,
+		ParentID: parentID,
+		RootID:   rootID,
+		UserID:   actor.UserID,
+		Body:     body,
+		Status:   models.CommentStatusPending,
+	}
+	if s.policy.CanAny(actor, policy.ActionCommentModerate) {
+		now := time.Now()
+		comment.Status = models.CommentStatusApproved
+		comment.ModeratedBy = &actor.UserID
+		comment.ModeratedAt = &now
+	}
+
+	if err := s.repo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+	if err := s.afterChange(ctx, comment, ""); err != nil {
+		return nil, err
+	}
+	return s.response(ctx, comment.ID)
+}
+
+// UpdateComment lets the author rewrite their comment. An edited comment goes back
+// to the moderation queue unless its author is a moderator.
+func (s *CommentService) UpdateComment(ctx context.Context, id uint, actor *policy.Subject, body string) (*CommentResponse, error) {
+	comment, err := s.findComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionCommentUpdate, policy.CommentResource(comment)).Err(); err != nil {
+		return nil, err
+	}
+
+	if comment.Body, err = sanitizeComment(body); err != nil {
+		return nil, err
+	}
+
+	previous := comment.Status
+	if !s.policy.CanAny(actor, policy.ActionCommentModerate) {
+		comment.Status = models.CommentStatusPending
+		comment.ModeratedBy = nil
+		comment.ModeratedAt = nil
+		comment.ModerationNote = ""
+	}
+
+	if err := s.repo.Update(ctx, comment); err != nil {
+		return nil, err
+	}
+	if err := s.afterChange(ctx, comment, previous); err != nil {
+		return nil, err
+	}
+	return s.response(ctx, comment.ID)
+}
+
+// DeleteComment removes a comment. Authors remove their own; moderators any.
+func (s *CommentService) DeleteComment(ctx context.Context, id uint, actor *policy.Subject) error {
+	comment, err := s.findComment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionCommentDelete, policy.CommentResource(comment)).Err(); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, comment); err != nil {
+		return err
+	}
+	return s.afterChange(ctx, comment, comment.Status)
+}
+
+// GetModerationQueue lists comments in the given moderation state, oldest first.
+func (s *CommentService) GetModerationQueue(ctx context.Context, status string, page, limit int, actor *policy.Subject) ([]ModerationItem, int64, error) {
+	if err := s.policy.Authorize(actor, policy.ActionCommentModerate, policy.Resource{Type: policy.ResourceComment}).Err(); err != nil {
+		return nil, 0, err
+	}
+
+	comments, total, err := s.repo.ModerationQueue(ctx, status, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]ModerationItem, len(comments))
+	for i, comment := range comments {
+		items[i] = ModerationItem{
+			Comment: comment,
+			Author:  CommentAuthor{ID: comment.User.ID, Username: comment.User.Username},
+		}
+		if comment.Post != nil {
+			items[i].PostTitle = comment.Post.Title
+			items[i].PostSlug = comment.Post.Slug
+			items[i].PostLang = comment.Post.Lang
+		}
+	}
+	return items, total, nil
+}
+
+// ModerateComment moves a comment to another moderation state.
+func (s *CommentService) ModerateComment(ctx context.Context, id uint, actor *policy.Subject, status, note string) (*models.Comment, error) {
+	comment, err := s.findComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionCommentModerate, policy.CommentResource(comment)).Err(); err != nil {
+		return nil, err
+	}
+
+	previous := comment.Status
+	now := time.Now()
+	comment.Status = status
+	comment.ModeratedBy = &actor.UserID
+	comment.ModeratedAt = &now
+	comment.ModerationNote = strings.TrimSpace(note)
+
+	if err := s.repo.Update(ctx, comment); err != nil {
+		return nil, err
+	}
+	if err := s.afterChange(ctx, comment, previous); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// afterChange drops the cached copies of the comment's post when its approved count
+// may have changed.
+func (s *CommentService) afterChange(ctx context.Context, comment *models.Comment, previousStatus string) error {
+	if comment.Status != models.CommentStatusApproved && previousStatus != models.CommentStatusApproved {
+		return nil
+	}
+	post, err := s.posts.findPost(ctx, comment.PostID, true)
+	if err != nil {
+		return err
+	}
+	return s.posts.InvalidatePostCaches(ctx, post)
+}
+
+func (s *CommentService) response(ctx context.Context, id uint) (*CommentResponse, error) {
+	comment, err := s.findComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return newCommentResponse(comment), nil
+}
+
+func (s *CommentService) findComment(ctx context.Context, id uint) (*models.Comment, error) {
+	comment, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrCommentNotFound
+	}
+	return comment, err
+}
+
+// sanitizeComment cleans a comment body and rejects one left with no visible text.
+func sanitizeComment(body string) (string, error) {
+	body = strings.TrimSpace(commentPolicy.Sanitize(body))
+	if strings.TrimSpace(bluemonday.StrictPolicy().Sanitize(body)) == "" {
+		return "", utils.ErrEmptyComment
+	}
+	return body, nil
+}
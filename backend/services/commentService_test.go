@@ -0,0 +1,149 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"gorm.io/gorm"
+)
+
+func testComment(id, parentID, userID uint, status string, deleted bool) models.Comment {
+	c := models.Comment{
+		ID:        id,
+		PostID:    1,
+		UserID:    userID,
+		Body:      "comment",
+		Status:    status,
+		CreatedAt: time.Unix(int64(id), 0),
+		User:      models.User{Username: "user"},
+	}
+	c.User.ID = userID
+	if parentID != 0 {
+		c.ParentID = &parentID
+	}
+	if deleted {
+		c.DeletedAt = gorm.DeletedAt{Time: time.Unix(int64(id), 0), Valid: true}
+	}
+	return c
+}
+
+// shape renders a tree as "id(children)" with tombstones marked by a '~'.
+func shape(nodes []*CommentResponse) string {
+	s := ""
+	for i, n := range nodes {
+		if i > 0 {
+			s += " "
+		}
+		if n.Deleted {
+			s += "~"
+		}
+		s += string(rune('0' + n.ID))
+		if len(n.Replies) > 0 {
+			s += "(" + shape(n.Replies) + ")"
+		}
+	}
+	return s
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	const approved, pending, rejected = models.CommentStatusApproved, models.CommentStatusPending, models.CommentStatusRejected
+	tests := []struct {
+		name     string
+		comments []models.Comment
+		viewerID uint
+		want     string
+	}{
+		{"flat", []models.Comment{
+			testComment(1, 0, 10, approved, false),
+			testComment(2, 0, 20, approved, false),
+		}, 0, "1 2"},
+		{"nested replies keep their order", []models.Comment{
+			testComment(1, 0, 10, approved, false),
+			testComment(2, 1, 20, approved, false),
+			testComment(3, 2, 10, approved, false),
+			testComment(4, 1, 30, approved, false),
+		}, 0, "1(2(3) 4)"},
+		{"pending comments are hidden from others", []models.Comment{
+			testComment(1, 0, 10, approved, false),
+			testComment(2, 1, 20, pending, false),
+		}, 30, "1"},
+		{"authors see their own pending comments", []models.Comment{
+			testComment(1, 0, 10, approved, false),
+			testComment(2, 1, 20, pending, false),
+		}, 20, "1(2)"},
+		{"deleted comment with replies is a tombstone", []models.Comment{
+			testComment(1, 0, 10, approved, true),
+			testComment(2, 1, 20, approved, false),
+		}, 0, "~1(2)"},
+		{"rejected comment with replies is a tombstone", []models.Comment{
+			testComment(1, 0, 10, rejected, false),
+			testComment(2, 1, 20, approved, false),
+		}, 0, "~1(2)"},
+		{"chain of tombstones over a visible reply", []models.Comment{
+			testComment(1, 0, 10, approved, true),
+			testComment(2, 1, 20, approved, true),
+			testComment(3, 2, 30, approved, false),
+		}, 0, "~1(~2(3))"},
+		{"tombstones without visible replies are dropped", []models.Comment{
+			testComment(1, 0, 10, approved, true),
+			testComment(2, 1, 20, pending, false),
+			testComment(3, 0, 30, approved, false),
+		}, 0, "3"},
+		{"replies to missing parents are dropped", []models.Comment{
+			testComment(2, 9, 20, approved, false),
+		}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shape(buildCommentTree(tt.comments, tt.viewerID)); got != tt.want {
+				t.Errorf("tree = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTombstonesHideContent(t *testing.T) {
+	comments := []models.Comment{
+		testComment(1, 0, 10, models.CommentStatusApproved, true),
+		testComment(2, 1, 20, models.CommentStatusApproved, false),
+	}
+	tree := buildCommentTree(comments, 0)
+	if len(tree) != 1 {
+		t.Fatalf("tree has %d roots", len(tree))
+	}
+	if root := tree[0]; root.Author != nil || root.Body != "" || root.Status != "" {
+		t.Errorf("tombstone reveals %+v", root)
+	}
+}
+
+func TestPruneTombstones(t *testing.T) {
+	tombstone := func(id uint, replies ...*CommentResponse) *CommentResponse {
+		return &CommentResponse{ID: id, Deleted: true, Replies: replies}
+	}
+	live := func(id uint, replies ...*CommentResponse) *CommentResponse {
+		return &CommentResponse{ID: id, Replies: replies}
+	}
+
+	tests := []struct {
+		name  string
+		nodes []*CommentResponse
+		want  string
+	}{
+		{"empty", nil, ""},
+		{"live comments stay", []*CommentResponse{live(1), live(2, live(3))}, "1 2(3)"},
+		{"lone tombstone goes", []*CommentResponse{tombstone(1), live(2)}, "2"},
+		{"tombstone of tombstones goes", []*CommentResponse{tombstone(1, tombstone(2, tombstone(3)))}, ""},
+		{"tombstone over a live reply stays", []*CommentResponse{tombstone(1, tombstone(2), tombstone(3, live(4)))}, "~1(~3(4))"},
+		{"live comment loses dead replies", []*CommentResponse{live(1, tombstone(2))}, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shape(pruneTombstones(tt.nodes)); got != tt.want {
+				t.Errorf("pruned = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
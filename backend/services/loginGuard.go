@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginLimits configures LoginGuard. Failures are counted per username and per IP;
+// once a counter reaches its threshold every further failure locks that username
+// or IP out for twice as long as the last, from BaseLockout up to MaxLockout.
+type LoginLimits struct {
+	MaxUserFailures int64
+	MaxIPFailures   int64
+	BaseLockout     time.Duration
+	MaxLockout      time.Duration
+}
+
+// DefaultLoginLimits tolerate a few typos but hold guessing against one account to
+// about one try an hour.
+var DefaultLoginLimits = LoginLimits{
+	MaxUserFailures: 5,
+	MaxIPFailures:   20,
+	BaseLockout:     time.Minute,
+	MaxLockout:      time.Hour,
+}
+
+const (
+	LockoutScopeUsername = "username"
+	LockoutScopeIP       = "ip"
+
+	// loginFailureWindow is how long a counter survives without new failures
+	loginFailureWindow = 24 * time.Hour
+)
+
+// Lockout describes a username or IP that was just locked out.
+type Lockout struct {
+	Scope     string
+	Username  string
+	IPAddress string
+	Failures  int64
+	Until     time.Time
+}
+
+// LockoutNotifier is told when a failure starts a lockout.
+type LockoutNotifier func(ctx context.Context, lockout Lockout)
+
+// LoginLockedError refuses a login while its username or IP is locked out. It
+// matches utils.ErrLoginLocked with errors.Is.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return utils.ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return utils.ErrLoginLocked
+}
+
+// LoginGuard throttles password guessing. The global rate limit only counts
+// requests per IP, which does nothing against many IPs trying one username.
+type LoginGuard struct {
+	redisClient *redis.Client
+	limits      LoginLimits
+	auditor     Auditor
+	notify      LockoutNotifier
+}
+
+func NewLoginGuard(redisClient *redis.Client, limits LoginLimits, auditor Auditor) *LoginGuard {
+	return &LoginGuard{redisClient: redisClient, limits: limits, auditor: auditor}
+}
+
+// OnLockout sets the hook told about new lockouts. It is a setter because the
+// notifier usually depends on services built after the guard.
+func (g *LoginGuard) OnLockout(notify LockoutNotifier) {
+	g.notify = notify
+}
+
+// Check returns how long the username or IP is still locked out, or zero.
+func (g *LoginGuard) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range []string{loginLockKey(LockoutScopeUsername, username), loginLockKey(LockoutScopeIP, ip)} {
+		ttl, err := g.redisClient.PTTL(ctx, key).Result()
+		if err != nil {
+			return 0, err
+		}
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait, nil
+}
+
+// Fail counts a failed login against the username and the IP and starts a lockout
+// for any counter past its threshold.
+func (g *LoginGuard) Fail(ctx context.Context, username, ip string) error {
+	now := time.Now()
+	for _, scope := range []struct {
+		name, value string
+		max         int64
+	}{
+		{LockoutScopeUsername, username, g.limits.MaxUserFailures},
+		{LockoutScopeIP, ip, g.limits.MaxIPFailures},
+	} {
+		failures, err := g.count(ctx, loginFailuresKey(scope.name, scope.value))
+		if err != nil {
+			return err
+		}
+		lockout := lockoutDuration(failures, scope.max, g.limits.BaseLockout, g.limits.MaxLockout)
+		if lockout == 0 {
+			continue
+		}
+		if err := g.redisClient.Set(ctx, loginLockKey(scope.name, scope.value), 1, lockout).Err(); err != nil {
+			return err
+		}
+
+		event := Lockout{Scope: scope.name, Username: username, IPAddress: ip, Failures: failures, Until: now.Add(lockout)}
+		g.auditor.Record(ctx, AuditEvent{
+			Action:    "login.lockout",
+			IPAddress: ip,
+			Details: map[string]interface{}{
+				"scope":    scope.name,
+				"username": username,
+				"failures": failures,
+				"until":    event.Until,
+			},
+		})
+		// Only the first lockout is worth a notification; later ones just extend it
+		if failures == scope.max && g.notify != nil {
+			g.notify(ctx, event)
+		}
+	}
+	return nil
+}
+
+// Succeed clears the username's failures. The IP's are kept: one valid account
+// must not let an attacker reset the count for every other username.
+func (g *LoginGuard) Succeed(ctx context.Context, username string) error {
+	return g.redisClient.Del(ctx, loginFailuresKey(LockoutScopeUsername, username)).Err()
+}
+
+// Unlock lifts the lockout on a user's username and forgets its failures. IP
+// lockouts are left to expire.
+func (g *LoginGuard) Unlock(ctx context.Context, user *models.User, actorID uint, ip string) error {
+	if err := g.redisClient.Del(ctx,
+		loginFailuresKey(LockoutScopeUsername, user.Username),
+		loginLockKey(LockoutScopeUsername, user.Username),
+	).Err(); err != nil {
+		return err
+	}
+	g.auditor.Record(ctx, AuditEvent{
+		Action:     "login.unlock",
+		ActorID:    actorID,
+		TargetType: "user",
+		TargetID:   user.ID,
+		IPAddress:  ip,
+		Details:    map[string]interface{}{"username": user.Username},
+	})
+	return nil
+}
+
+// count adds a failure and returns the new total.
+func (g *LoginGuard) count(ctx context.Context, key string) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := g.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, loginFailureWindow)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// lockoutDuration is zero below max failures, then base doubled for each failure
+// past it, capped at limit.
+func lockoutDuration(failures, max int64, base, limit time.Duration) time.Duration {
+	if max <= 0 || failures < max {
+		return 0
+	}
+	d := base
+	for i := max; i < failures && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+	return d
+}
+
+// Usernames are matched case-insensitively so changing case doesn't reset the count.
+func loginFailuresKey(scope, value string) string {
+	return "login_failures:" + scope + ":" + strings.ToLower(value)
+}
+
+func loginLockKey(scope, value string) string {
+	return "login_lock:" + scope + ":" + strings.ToLower(value)
+}
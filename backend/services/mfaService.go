@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/totp"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaIssuer         = "Zyros"
+	recoveryCodeCount = 10
+	// Recovery codes avoid characters that are easy to misread: 0/O, 1/I/L
+	recoveryAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+)
+
+// MFAService manages TOTP enrollment, recovery codes and second-factor checks.
+type MFAService struct {
+	repo *repositories.MFARepository
+	box  *utils.SecretBox
+	// enforce makes two-factor login mandatory for privileged accounts
+	enforce bool
+}
+
+func NewMFAService(repo *repositories.MFARepository, box *utils.SecretBox, enforce bool) *MFAService {
+	return &MFAService{repo: repo, box: box, enforce: enforce}
+}
+
+// MFASetup is what an authenticator app needs to enroll: the secret, and the same
+// secret as an otpauth:// URI for rendering as a QR code.
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFAStatus describes a user's two-factor settings.
+type MFAStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	Required          bool       `json:"required"`
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+}
+
+// Required reports whether the user may only log in with a second factor.
+func (s *MFAService) Required(user *models.User) bool {
+	return s.enforce && policy.NewSubject(user).IsPrivileged()
+}
+
+// Enabled reports whether the user has confirmed an enrollment.
+func (s *MFAService) Enabled(ctx context.Context, userID uint) (bool, error) {
+	mfa, err := s.repo.Find(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return mfa.EnabledAt != nil, nil
+}
+
+func (s *MFAService) Status(ctx context.Context, user *models.User) (*MFAStatus, error) {
+	status := &MFAStatus{Required: s.Required(user)}
+	mfa, err := s.repo.Find(ctx, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && mfa.EnabledAt == nil) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Enabled, status.EnabledAt = true, mfa.EnabledAt
+	if status.RecoveryCodesLeft, err = s.repo.CountRecoveryCodes(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Setup starts an enrollment with a fresh secret. Until Enable confirms a code from
+// it, the secret is not used for login and Setup may be called again.
+func (s *MFAService) Setup(ctx context.Context, user *models.User) (*MFASetup, error) {
+	enabled, err := s.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, utils.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.box.Seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePending(ctx, &models.UserMFA{UserID: user.ID, Secret: sealed}); err != nil {
+		return nil, err
+	}
+	return &MFASetup{Secret: secret, ProvisioningURI: totp.ProvisioningURI(mfaIssuer, user.Username, secret)}, nil
+}
+
+// Enable confirms a pending enrollment with a code from the authenticator and returns
+// the recovery codes. They are shown this once; only their hashes are kept.
+func (s *MFAService) Enable(ctx context.Context, userID uint, code string) ([]string, error) {
+	mfa, err := s.repo.Find(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mfa.EnabledAt != nil {
+		return nil, utils.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.box.Open(mfa.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), mfa.LastStep)
+	if !ok {
+		return nil, utils.ErrInvalidMFACode
+	}
+
+	codes, stored, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Enable(ctx, userID, step, stored, time.Now()); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a second factor: a current TOTP code or an unused recovery code.
+// Either works only once.
+func (s *MFAService) Verify(ctx context.Context, userID uint, code string) error {
+	mfa, err := s.repo.Find(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && mfa.EnabledAt == nil) {
+		return utils.ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		secret, err := s.box.Open(mfa.Secret)
+		if err != nil {
+			return err
+		}
+		step, ok := totp.Validate(secret, code, time.Now(), mfa.LastStep)
+		if !ok {
+			return utils.ErrInvalidMFACode
+		}
+		// A concurrent request may have used the same code since Find
+		used, err := s.repo.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return utils.ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return utils.ErrInvalidMFACode
+	}
+	return nil
+}
+
+// Disable removes the enrollment after checking a second factor. Accounts that must
+// use two-factor login can't turn it off.
+func (s *MFAService) Disable(ctx context.Context, user *models.User, code string) error {
+	if s.Required(user) {
+		return utils.ErrMFARequired
+	}
+	if err := s.Verify(ctx, user.ID, code); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after checking a second factor.
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, stored, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, stored); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCodes returns a set of codes in clear, formatted xxxxx-xxxxx, and the
+// rows storing their hashes.
+func newRecoveryCodes() ([]string, []models.MFARecoveryCode, error) {
+	codes := make([]string, recoveryCodeCount)
+	stored := make([]models.MFARecoveryCode, recoveryCodeCount)
+	buf := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		var b strings.Builder
+		for j, c := range buf {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+		}
+		codes[i] = b.String()
+		stored[i] = models.MFARecoveryCode{CodeHash: hashRecoveryCode(codes[i])}
+	}
+	return codes, stored, nil
+}
+
+// hashRecoveryCode hashes a code as typed, ignoring case and the dash.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return utils.HashToken(code)
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/oidc"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// oidcLoginTTL is how long a user has to log in at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// maxUsernameLength keeps usernames derived from provider claims readable.
+const maxUsernameLength = 30
+
+// OIDCService logs users in with external OpenID Connect providers, creating an
+// account the first time someone arrives from one.
+type OIDCService struct {
+	providers    map[string]*oidc.Provider
+	identityRepo *repositories.IdentityRepository
+	userRepo     *repositories.UserRepository
+	authService  *AuthService
+	redisClient  *redis.Client
+}
+
+func NewOIDCService(providers map[string]*oidc.Provider, identityRepo *repositories.IdentityRepository, userRepo *repositories.UserRepository, authService *AuthService, redisClient *redis.Client) *OIDCService {
+	return &OIDCService{
+		providers:    providers,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		authService:  authService,
+		redisClient:  redisClient,
+	}
+}
+
+// oidcLogin is what Begin remembers about a login until the provider redirects back.
+type oidcLogin struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// Providers returns the names of the configured providers, for a login page.
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Begin starts a login with provider. It returns the URL to send the user to and the
+// state that comes back with them, which the caller should also bind to the browser.
+func (s *OIDCService) Begin(ctx context.Context, provider string) (string, string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", utils.ErrUnknownProvider
+	}
+
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	login := oidcLogin{Provider: provider}
+	if login.Nonce, err = utils.RandomToken(32); err != nil {
+		return "", "", err
+	}
+	if login.Verifier, err = utils.RandomToken(32); err != nil {
+		return "", "", err
+	}
+	data, err := json.Marshal(login)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.redisClient.Set(ctx, oidcLoginKey(state), data, oidcLoginTTL).Err(); err != nil {
+		return "", "", err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, login.Nonce, oidc.Challenge(login.Verifier))
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Complete finishes a login when the provider redirects back with code. Each state
+// works once. The result is a session, or a second-factor challenge for accounts
+// that have one, exactly as for a password login.
+func (s *OIDCService) Complete(ctx context.Context, provider, state, code string, client ClientInfo) (*LoginResult, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, utils.ErrUnknownProvider
+	}
+
+	data, err := s.redisClient.GetDel(ctx, oidcLoginKey(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, utils.ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+	var login oidcLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, err
+	}
+	if login.Provider != provider {
+		return nil, utils.ErrInvalidOIDCState
+	}
+
+	claims, err := p.Exchange(ctx, code, login.Verifier, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.findOrCreateUser(ctx, provider, claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.authService.finishLogin(ctx, user, client)
+}
+
+// findOrCreateUser returns the user linked to the identity, registering one on its
+// first login. A verified email is copied onto the new account only if no other
+// account has it: linking to an existing account by email would let anyone who
+// controls a provider account with that address take it over.
+func (s *OIDCService) findOrCreateUser(ctx context.Context, provider string, claims *oidc.Claims) (*models.User, error) {
+	now := time.Now()
+	identity, err := s.identityRepo.Find(ctx, provider, claims.Subject)
+	if err == nil {
+		if err := s.identityRepo.Touch(ctx, identity.ID, now); err != nil {
+			return nil, err
+		}
+		return s.authService.FindUser(ctx, identity.UserID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	username, err := s.availableUsername(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	// Nobody knows this password; the user can set one with a password reset
+	password, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{Username: username, Password: password}
+	identity = &models.UserIdentity{Provider: provider, Subject: claims.Subject, LastLoginAt: &now}
+	if claims.Email != "" {
+		email := NormalizeEmail(claims.Email)
+		identity.Email = &email
+		if claims.EmailVerified {
+			_, err := s.userRepo.FindByEmail(ctx, email)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				user.Email = &email
+				user.EmailVerifiedAt = &now
+			} else if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.authService.prepareUser(ctx, user); err != nil {
+		return nil, err
+	}
+	if err := s.identityRepo.CreateWithUser(ctx, user, identity); err != nil {
+		return nil, err
+	}
+	s.authService.audit(ctx, "auth.register", user.ID, ClientInfo{}, map[string]interface{}{"username": user.Username, "provider": provider})
+	return user, nil
+}
+
+// availableUsername derives a username from the identity's claims, adding a number
+// when it is taken.
+func (s *OIDCService) availableUsername(ctx context.Context, claims *oidc.Claims) (string, error) {
+	base := usernameFromClaims(claims)
+	candidate := base
+	for attempt := 0; attempt < 10; attempt++ {
+		_, err := s.userRepo.FindByUsername(ctx, candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%04d", base, n)
+	}
+	return "", errors.New("no available username")
+}
+
+// usernameFromClaims picks the preferred username, the email's local part or the
+// name, whichever comes first, and keeps only letters, digits, dots, dashes and
+// underscores.
+func usernameFromClaims(claims *oidc.Claims) string {
+	for _, source := range []string{claims.PreferredUsername, strings.SplitN(claims.Email, "@", 2)[0], claims.Name} {
+		var b strings.Builder
+		for _, r := range strings.ToLower(source) {
+			switch {
+			case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+				b.WriteRune(r)
+			case r == ' ':
+				b.WriteRune('_')
+			}
+		}
+		name := strings.Trim(b.String(), "._-")
+		if len(name) > maxUsernameLength {
+			name = name[:maxUsernameLength]
+		}
+		if len(name) >= 3 {
+			return name
+		}
+	}
+	return "user"
+}
+
+func oidcLoginKey(state string) string {
+	return "oidc_login:" + utils.HashToken(state)
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alimosavifard/zyros-backend/oidc"
+)
+
+func TestUsernameFromClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims oidc.Claims
+		want   string
+	}{
+		{"preferred username", oidc.Claims{PreferredUsername: "Reader_1", Email: "other@example.com"}, "reader_1"},
+		{"email local part", oidc.Claims{Email: "jane.doe@example.com"}, "jane.doe"},
+		{"name with spaces", oidc.Claims{Name: "Jane Q Doe"}, "jane_q_doe"},
+		{"unusable characters dropped", oidc.Claims{PreferredUsername: "@@", Email: "علی@example.com", Name: "-Ali-"}, "ali"},
+		{"too short everywhere", oidc.Claims{PreferredUsername: "ab"}, "user"},
+		{"long names cut", oidc.Claims{PreferredUsername: "abcdefghijklmnopqrstuvwxyz0123456789"}, "abcdefghijklmnopqrstuvwxyz0123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := usernameFromClaims(&tt.claims); got != tt.want {
+				t.Errorf("usernameFromClaims() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	permissionCacheSize = 4096
+	// permissionLocalTTL bounds how long a replica trusts its own copy should the
+	// version stamps be lost, e.g. when Redis is flushed
+	permissionLocalTTL = 30 * time.Second
+	permissionEntryTTL = time.Hour
+)
+
+// PermissionEntry is a user's effective permissions, stamped with the global and
+// per-user versions current when they were loaded.
+type PermissionEntry struct {
+	Global      int64    `json:"global"`
+	User        int64    `json:"user"`
+	Permissions []string `json:"permissions"`
+}
+
+// PermissionStore is the cache shared between replicas. Bumping a version makes
+// every entry stamped with the old one stale.
+type PermissionStore interface {
+	// Versions returns the global version and the user's version.
+	Versions(ctx context.Context, userID uint) (int64, int64, error)
+	// Get returns the stored entry, or nil if there is none.
+	Get(ctx context.Context, userID uint) (*PermissionEntry, error)
+	Set(ctx context.Context, userID uint, entry *PermissionEntry) error
+	BumpUsers(ctx context.Context, userIDs ...uint) error
+	BumpAll(ctx context.Context) error
+}
+
+// PermissionCache resolves a user's permissions with a single query and keeps them
+// in the shared store, with a small LRU in front. Every lookup reads the version
+// stamps, so a bump on any replica takes effect on the next request everywhere.
+type PermissionCache struct {
+	store PermissionStore
+	load  func(ctx context.Context, userID uint) ([]string, error)
+	clock utils.Clock
+
+	mu    sync.Mutex
+	local *lru
+	ttl   time.Duration
+}
+
+func NewPermissionCache(store PermissionStore, load func(ctx context.Context, userID uint) ([]string, error), clock utils.Clock, size int, localTTL time.Duration) *PermissionCache {
+	return &PermissionCache{store: store, load: load, clock: clock, local: newLRU(size), ttl: localTTL}
+}
+
+// localEntry is a PermissionEntry held in process until expiresAt.
+type localEntry struct {
+	entry     *PermissionEntry
+	expiresAt time.Time
+}
+
+// Permissions returns the names of every permission the user's roles grant.
+func (c *PermissionCache) Permissions(ctx context.Context, userID uint) ([]string, error) {
+	global, user, err := c.store.Versions(ctx, userID)
+	if err != nil {
+		// Without the versions no cached copy can be trusted
+		utils.InitLogger().Warn().Err(err).Msg("Permission cache unavailable, loading from the database")
+		return c.load(ctx, userID)
+	}
+
+	if entry, ok := c.localGet(userID, global, user); ok {
+		return entry.Permissions, nil
+	}
+	entry, err := c.store.Get(ctx, userID)
+	if err != nil {
+		utils.InitLogger().Warn().Err(err).Uint("userID", userID).Msg("Failed to read cached permissions")
+	}
+	if entry != nil && entry.Global == global && entry.User == user {
+		c.localPut(userID, entry)
+		return entry.Permissions, nil
+	}
+
+	// Stamped with the versions read before loading: a bump while loading leaves
+	// the entry stale rather than caching grants that were just revoked
+	permissions, err := c.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	entry = &PermissionEntry{Global: global, User: user, Permissions: permissions}
+	if err := c.store.Set(ctx, userID, entry); err != nil {
+		utils.InitLogger().Warn().Err(err).Uint("userID", userID).Msg("Failed to cache permissions")
+	}
+	c.localPut(userID, entry)
+	return permissions, nil
+}
+
+// Has reports whether the user holds permission.
+func (c *PermissionCache) Has(ctx context.Context, userID uint, permission string) (bool, error) {
+	permissions, err := c.Permissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// InvalidateUsers makes the users' cached permissions stale, after their roles change.
+func (c *PermissionCache) InvalidateUsers(ctx context.Context, userIDs ...uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	for _, id := range userIDs {
+		c.local.remove(id)
+	}
+	c.mu.Unlock()
+	return c.store.BumpUsers(ctx, userIDs...)
+}
+
+// InvalidateAll makes every cached permission stale, after a role's grants change.
+func (c *PermissionCache) InvalidateAll(ctx context.Context) error {
+	c.mu.Lock()
+	c.local.purge()
+	c.mu.Unlock()
+	return c.store.BumpAll(ctx)
+}
+
+func (c *PermissionCache) localGet(userID uint, global, user int64) (*PermissionEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.local.get(userID)
+	if !ok {
+		return nil, false
+	}
+	if cached.entry.Global != global || cached.entry.User != user || !c.clock.Now().Before(cached.expiresAt) {
+		c.local.remove(userID)
+		return nil, false
+	}
+	return cached.entry, true
+}
+
+func (c *PermissionCache) localPut(userID uint, entry *PermissionEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.local.add(userID, localEntry{entry: entry, expiresAt: c.clock.Now().Add(c.ttl)})
+}
+
+// lru is a fixed-size map that drops the least recently used entry when full. It
+// is not safe for concurrent use.
+type lru struct {
+	size  int
+	order *list.List
+	items map[uint]*list.Element
+}
+
+type lruItem struct {
+	key   uint
+	value localEntry
+}
+
+func newLRU(size int) *lru {
+	return &lru{size: size, order: list.New(), items: make(map[uint]*list.Element)}
+}
+
+func (l *lru) get(key uint) (localEntry, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		return localEntry{}, false
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruItem).value, true
+}
+
+func (l *lru) add(key uint, value localEntry) {
+	if elem, ok := l.items[key]; ok {
+		elem.Value.(*lruItem).value = value
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.items[key] = l.order.PushFront(&lruItem{key: key, value: value})
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (l *lru) remove(key uint) {
+	if elem, ok := l.items[key]; ok {
+		l.order.Remove(elem)
+		delete(l.items, key)
+	}
+}
+
+func (l *lru) purge() {
+	l.order.Init()
+	l.items = make(map[uint]*list.Element)
+}
+
+// RedisPermissionStore keeps permission entries and their version stamps in Redis.
+type RedisPermissionStore struct {
+	redisClient *redis.Client
+}
+
+func NewRedisPermissionStore(redisClient *redis.Client) *RedisPermissionStore {
+	return &RedisPermissionStore{redisClient: redisClient}
+}
+
+const globalPermissionVersionKey = "user_permissions_version"
+
+func (s *RedisPermissionStore) Versions(ctx context.Context, userID uint) (int64, int64, error) {
+	values, err := s.redisClient.MGet(ctx, globalPermissionVersionKey, userPermissionVersionKey(userID)).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	global, err := parseVersion(values[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	user, err := parseVersion(values[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return global, user, nil
+}
+
+func (s *RedisPermissionStore) Get(ctx context.Context, userID uint) (*PermissionEntry, error) {
+	data, err := s.redisClient.Get(ctx, userPermissionsKey(userID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry PermissionEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *RedisPermissionStore) Set(ctx context.Context, userID uint, entry *PermissionEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.redisClient.Set(ctx, userPermissionsKey(userID), data, permissionEntryTTL).Err()
+}
+
+func (s *RedisPermissionStore) BumpUsers(ctx context.Context, userIDs ...uint) error {
+	_, err := s.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range userIDs {
+			pipe.Incr(ctx, userPermissionVersionKey(id))
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisPermissionStore) BumpAll(ctx context.Context) error {
+	return s.redisClient.Incr(ctx, globalPermissionVersionKey).Err()
+}
+
+// parseVersion reads a version stamp; one that was never bumped is 0.
+func parseVersion(value interface{}) (int64, error) {
+	if value == nil {
+		return 0, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return 0, errors.New("unexpected permission version type")
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func userPermissionsKey(userID uint) string {
+	return "user_permissions:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func userPermissionVersionKey(userID uint) string {
+	return "user_permissions_version:" + strconv.FormatUint(uint64(userID), 10)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+// fakePermissionStore is the shared store, as several replicas would see it.
+type fakePermissionStore struct {
+	mu      sync.Mutex
+	global  int64
+	users   map[uint]int64
+	entries map[uint]*PermissionEntry
+	down    bool
+}
+
+func newFakePermissionStore() *fakePermissionStore {
+	return &fakePermissionStore{users: map[uint]int64{}, entries: map[uint]*PermissionEntry{}}
+}
+
+func (s *fakePermissionStore) Versions(ctx context.Context, userID uint) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return 0, 0, errors.New("store down")
+	}
+	return s.global, s.users[userID], nil
+}
+
+func (s *fakePermissionStore) Get(ctx context.Context, userID uint) (*PermissionEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[userID], nil
+}
+
+func (s *fakePermissionStore) Set(ctx context.Context, userID uint, entry *PermissionEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[userID] = entry
+	return nil
+}
+
+func (s *fakePermissionStore) BumpUsers(ctx context.Context, userIDs ...uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range userIDs {
+		s.users[id]++
+	}
+	return nil
+}
+
+func (s *fakePermissionStore) BumpAll(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.global++
+	return nil
+}
+
+// fakeGrants stands in for the database, counting loads.
+type fakeGrants struct {
+	grants map[uint][]string
+	loads  int
+}
+
+func (g *fakeGrants) load(ctx context.Context, userID uint) ([]string, error) {
+	g.loads++
+	return g.grants[userID], nil
+}
+
+func TestPermissionCache(t *testing.T) {
+	ctx := context.Background()
+	clock := utils.NewFakeClock(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	store := newFakePermissionStore()
+	db := &fakeGrants{grants: map[uint][]string{1: {"create_post"}, 2: {"create_post"}}}
+	replicaA := NewPermissionCache(store, db.load, clock, 10, time.Minute)
+	replicaB := NewPermissionCache(store, db.load, clock, 10, time.Minute)
+
+	has := func(c *PermissionCache, userID uint, permission string) bool {
+		t.Helper()
+		ok, err := c.Has(ctx, userID, permission)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if !has(replicaA, 1, "create_post") || has(replicaA, 1, "manage_users") {
+		t.Fatal("wrong grants on first load")
+	}
+	has(replicaA, 1, "create_post")
+	has(replicaB, 1, "create_post")
+	if db.loads != 1 {
+		t.Fatalf("loaded %d times, want 1: the second replica should use the shared entry", db.loads)
+	}
+
+	// A role change on one replica is seen by the other on its next lookup
+	db.grants[1] = []string{"create_post", "manage_users"}
+	if err := replicaA.InvalidateUsers(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !has(replicaB, 1, "manage_users") {
+		t.Error("user version bump not seen by the other replica")
+	}
+	has(replicaB, 2, "create_post")
+	loads := db.loads
+
+	db.grants[2] = nil
+	if err := replicaB.InvalidateAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if has(replicaA, 2, "create_post") {
+		t.Error("global version bump not seen by the other replica")
+	}
+	if !has(replicaA, 1, "manage_users") || db.loads != loads+2 {
+		t.Errorf("global bump reloaded %d users, want 2", db.loads-loads)
+	}
+}
+
+func TestPermissionCacheLocalExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := utils.NewFakeClock(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	store := newFakePermissionStore()
+	db := &fakeGrants{grants: map[uint][]string{1: {"create_post"}}}
+	cache := NewPermissionCache(store, db.load, clock, 10, time.Minute)
+
+	cache.Has(ctx, 1, "create_post")
+	// Losing the shared entry (e.g. a Redis flush) only matters once the local
+	// copy expires
+	delete(store.entries, 1)
+	cache.Has(ctx, 1, "create_post")
+	if db.loads != 1 {
+		t.Fatalf("loaded %d times before the local copy expired", db.loads)
+	}
+	clock.Advance(2 * time.Minute)
+	cache.Has(ctx, 1, "create_post")
+	if db.loads != 2 {
+		t.Errorf("expired local copy still used")
+	}
+
+	// Without the store every lookup goes to the database
+	store.down = true
+	if ok, err := cache.Has(ctx, 1, "create_post"); !ok || err != nil {
+		t.Errorf("Has = %v, %v with the store down", ok, err)
+	}
+	if db.loads != 3 {
+		t.Errorf("cached copy trusted without version stamps")
+	}
+}
+
+func TestLRU(t *testing.T) {
+	l := newLRU(2)
+	l.add(1, localEntry{})
+	l.add(2, localEntry{})
+	l.get(1)
+	l.add(3, localEntry{})
+
+	if _, ok := l.get(2); ok {
+		t.Error("least recently used entry not evicted")
+	}
+	if _, ok := l.get(1); !ok {
+		t.Error("recently used entry evicted")
+	}
+	l.purge()
+	if _, ok := l.get(3); ok || l.order.Len() != 0 {
+		t.Error("purge left entries behind")
+	}
+}
@@ -6,43 +6,173 @@ import (
 	"errors"
 	"fmt"
 	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
 	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/requests"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/redis/go-redis/v9"
 	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/alimosavifard/zyros-backend/workflow"
+	"gorm.io/gorm"
+	"strings"
 	"time"
 )
 
 // PostResponse: struct واسط برای پاسخ، بدون تغییر مدل Post
 type PostResponse struct {
-	ID            uint   `json:"id"`
-	Title         string `json:"title"`
-	Content       string `json:"content"`
-	Type          string `json:"type"`
-	Lang          string `json:"lang"`
-	ImageUrl      string `json:"imageUrl,omitempty"`
-	UserID        uint   `json:"user_id"`
-	User          models.User `json:"user,omitempty"`
-	CreatedAt     time.Time `json:"created_at,omitempty"` // اگر نیاز باشد
-	LikesCount    int64  `json:"likesCount"`
-	IsLikedByUser bool   `json:"isLikedByUser"`
+	ID            uint              `json:"id"`
+	Title         string            `json:"title"`
+	Slug          string            `json:"slug"`
+	Content       string            `json:"content"`
+	Type          string            `json:"type"`
+	Lang          string            `json:"lang"`
+	ImageUrl      string            `json:"imageUrl,omitempty"`
+	UserID        uint              `json:"user_id"`
+	User          PostAuthor        `json:"user"`
+	Categories    []models.Category `json:"categories"`
+	Tags          []models.Tag      `json:"tags"`
+	Version       uint              `json:"version"`
+	Status        string            `json:"status"`
+	PublishedAt   *time.Time        `json:"published_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at,omitempty"` // اگر نیاز باشد
+	UpdatedAt     time.Time         `json:"updated_at,omitempty"`
+	DeletedAt     *time.Time        `json:"deleted_at,omitempty"`
+	LikesCount    int64             `json:"likesCount"`
+	CommentsCount int64             `json:"commentsCount"`
+	IsLikedByUser bool              `json:"isLikedByUser"`
+}
+
+// PostAuthor is the public part of a post's author.
+type PostAuthor struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+func newPostAuthor(user models.User) PostAuthor {
+	return PostAuthor{ID: user.ID, Username: user.Username, DisplayName: user.DisplayName, AvatarURL: user.AvatarURL}
+}
+
+// newPostResponse maps a post with its author, categories and tags loaded. The
+// counters are left for the caller.
+func newPostResponse(post *models.Post) *PostResponse {
+	return &PostResponse{
+		ID:          post.ID,
+		Title:       post.Title,
+		Slug:        post.Slug,
+		Content:     post.Content,
+		Type:        post.Type,
+		Lang:        post.Lang,
+		ImageUrl:    post.ImageUrl,
+		UserID:      post.UserID,
+		User:        newPostAuthor(post.User),
+		Categories:  post.Categories,
+		Tags:        post.Tags,
+		Version:     post.Version,
+		Status:      post.Status,
+		PublishedAt: post.PublishedAt,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+	}
+}
+
+// optionalPostAuthor is newPostAuthor for an association that may be missing.
+func optionalPostAuthor(user *models.User) *PostAuthor {
+	if user == nil {
+		return nil
+	}
+	author := newPostAuthor(*user)
+	return &author
+}
+
+// PostTransitionResponse is one entry of a post's workflow history.
+type PostTransitionResponse struct {
+	ID         uint        `json:"id"`
+	FromStatus string      `json:"from_status"`
+	ToStatus   string      `json:"to_status"`
+	Transition string      `json:"transition"`
+	ActorID    *uint       `json:"actor_id"`
+	Actor      *PostAuthor `json:"actor,omitempty"`
+	Note       string      `json:"note,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// PostRevisionResponse describes a saved revision without its content.
+type PostRevisionResponse struct {
+	ID        uint        `json:"id"`
+	Revision  int         `json:"revision"`
+	Title     string      `json:"title"`
+	Type      string      `json:"type"`
+	Lang      string      `json:"lang"`
+	ImageUrl  string      `json:"imageUrl"`
+	AuthorID  *uint       `json:"author_id"`
+	Author    *PostAuthor `json:"author,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
 }
 
 type PostService struct {
 	repo        *repositories.PostRepository
+	taxonomy    *TaxonomyService
 	redisClient *redis.Client
 	likeService *LikeService
+	policy      *policy.Policy
+	auditor     Auditor
+	// revisionLimit is how many revisions are kept per post; 0 keeps them all
+	revisionLimit int
 }
 
-func NewPostService(repo *repositories.PostRepository, redisClient *redis.Client, likeService *LikeService) *PostService {
-	return &PostService{repo: repo, redisClient: redisClient, likeService: likeService}
+func NewPostService(repo *repositories.PostRepository, taxonomy *TaxonomyService, redisClient *redis.Client, likeService *LikeService, postPolicy *policy.Policy, auditor Auditor, revisionLimit int) *PostService {
+	return &PostService{repo: repo, taxonomy: taxonomy, redisClient: redisClient, likeService: likeService, policy: postPolicy, auditor: auditor, revisionLimit: revisionLimit}
 }
 
-func (s *PostService) CreatePost(ctx context.Context, post *models.Post) error {
+// CreatePost saves a new draft filed under the given categories and tags. Its slug is
+// derived from the title unless post.Slug is already set.
+func (s *PostService) CreatePost(ctx context.Context, post *models.Post, categoryIDs []uint, tags []string) (*PostResponse, error) {
 	p := bluemonday.UGCPolicy()
 	post.Content = p.Sanitize(post.Content)
 
-	tx := s.repo.db.Begin()
+	derivedSlug := post.Slug == ""
+	if err := s.assignSlug(ctx, post, post.Slug); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if post.Categories, err = s.taxonomy.ResolveCategories(ctx, categoryIDs); err != nil {
+		return nil, err
+	}
+	if post.Tags, err = s.taxonomy.ResolveTags(ctx, tags); err != nil {
+		return nil, err
+	}
+
+	// Every post starts as a draft and goes through the editorial workflow
+	now := time.Now()
+	post.Status = models.PostStatusDraft
+	post.StatusChangedAt = &now
+	post.StatusChangedBy = &post.UserID
+
+	// Posts created at the same time can derive the same free slug; whichever
+	// loses the race moves on to the next suffix
+	err = s.insertPost(ctx, post)
+	for attempt := 1; derivedSlug && errors.Is(err, utils.ErrSlugTaken) && attempt < slugAttempts; attempt++ {
+		if err := s.assignSlug(ctx, post, ""); err != nil {
+			return nil, err
+		}
+		err = s.insertPost(ctx, post)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "post.create", post.UserID, post.ID, nil, postSnapshot(post), nil)
+	return s.writtenPost(ctx, post.ID, post.UserID)
+}
+
+// slugAttempts is how many slugs CreatePost tries when another post takes the one it derived.
+const slugAttempts = 3
+
+// insertPost saves a new post and its first revision in one transaction.
+func (s *PostService) insertPost(ctx context.Context, post *models.Post) error {
+	tx := s.repo.GetDB().Begin()
 	if tx.Error != nil {
 		return tx.Error
 	}
@@ -52,24 +182,28 @@ func (s *PostService) CreatePost(ctx context.Context, post *models.Post) error {
 		return err
 	}
 
-	cacheKeyPattern := fmt.Sprintf("posts:lang:%s:type:%s:*", post.Lang, post.Type)
-	keys, err := s.redisClient.Keys(ctx, cacheKeyPattern).Result()
-	if err != nil {
+	if err := s.repo.CreateRevisionWithTx(tx, post, post.UserID, s.revisionLimit); err != nil {
 		tx.Rollback()
 		return err
 	}
-	if len(keys) > 0 {
-		if err := s.redisClient.Del(ctx, keys...).Err(); err != nil {
-			tx.Rollback()
-			return err
-		}
+
+	if err := s.InvalidatePostCaches(ctx, post); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	return tx.Commit().Error
 }
 
-func (s *PostService) GetPosts(ctx context.Context, lang string, postType string, page, limit int, userID uint) ([]PostResponse, error) {
-	cacheKey := fmt.Sprintf("posts:lang:%s:type:%s:page:%d:limit:%d:user:%d", lang, postType, page, limit, userID)
+// GetPosts lists posts visible to viewer, which is nil for anonymous readers.
+// Category and tag filters may be given as names or slugs.
+func (s *PostService) GetPosts(ctx context.Context, filter repositories.PostFilter, page, limit int, viewer *policy.Subject) ([]PostResponse, error) {
+	visibility := s.visibility(viewer)
+	userID := visibility.ViewerID
+	filter.Category = utils.Slugify(filter.Category)
+	filter.Tag = utils.Slugify(utils.CleanTagName(filter.Tag))
+	cacheKey := fmt.Sprintf("posts:lang:%s:type:%s:status:%s:category:%s:tag:%s:author:%d:page:%d:limit:%d:user:%d",
+		filter.Lang, filter.Type, filter.Status, filter.Category, filter.Tag, filter.AuthorID, page, limit, userID)
 
 	cachedPosts, err := s.redisClient.Get(ctx, cacheKey).Result()
 	if err == nil && cachedPosts != "" {
@@ -79,7 +213,7 @@ func (s *PostService) GetPosts(ctx context.Context, lang string, postType string
 		}
 	}
 
-    posts, err := s.repo.GetByLang(ctx, lang, postType, page, limit)
+    posts, err := s.repo.GetByLang(ctx, filter, visibility, page, limit)
     if err != nil {
         utils.InitLogger().Error().Err(err).Msg("Failed to fetch posts from DB")
         return nil, fmt.Errorf("failed to fetch posts from DB: %w", err)
@@ -100,13 +234,22 @@ func (s *PostService) GetPosts(ctx context.Context, lang string, postType string
 		postResponses[i] = PostResponse{
 			ID:            post.ID,
 			Title:         post.Title,
+			Slug:          post.Slug,
 			Content:       post.Content,
 			Type:          post.Type,
 			Lang:          post.Lang,
 			ImageUrl:      post.ImageUrl,
 			UserID:        post.UserID,
-			User:          post.User,
+			User:          newPostAuthor(post.User),
+			Categories:    post.Categories,
+			Tags:          post.Tags,
+			Version:       post.Version,
+			Status:        post.Status,
+			PublishedAt:   post.PublishedAt,
+			CreatedAt:     post.CreatedAt,
+			UpdatedAt:     post.UpdatedAt,
 			LikesCount:    likesCount,
+			CommentsCount: post.CommentsCount,
 			IsLikedByUser: isLiked,
 		}
 	}
@@ -119,7 +262,10 @@ func (s *PostService) GetPosts(ctx context.Context, lang string, postType string
 	return postResponses, nil
 }
 
-func (s *PostService) GetPostByID(ctx context.Context, id uint, userID uint) (*PostResponse, error) {
+// GetPostByID returns a post if viewer, which is nil for anonymous readers, may see it.
+func (s *PostService) GetPostByID(ctx context.Context, id uint, viewer *policy.Subject) (*PostResponse, error) {
+	visibility := s.visibility(viewer)
+	userID := visibility.ViewerID
 	cacheKey := fmt.Sprintf("post:%d:user:%d", id, userID)
 
 	cachedPost, err := s.redisClient.Get(ctx, cacheKey).Result()
@@ -130,26 +276,12 @@ func (s *PostService) GetPostByID(ctx context.Context, id uint, userID uint) (*P
 		}
 	}
 
-	post, err := s.repo.FindByID(ctx, id)
+	post, err := s.repo.FindVisibleByID(ctx, id, visibility)
 	if err != nil {
 		return nil, err
 	}
 
-	likesCount, _ := s.likeService.GetPostLikes(ctx, post.ID)
-	isLiked, _ := s.likeService.likeRepo.IsLiked(ctx, userID, post.ID)
-
-	postResp := &PostResponse{
-		ID:            post.ID,
-		Title:         post.Title,
-		Content:       post.Content,
-		Type:          post.Type,
-		Lang:          post.Lang,
-		ImageUrl:      post.ImageUrl,
-		UserID:        post.UserID,
-		User:          post.User,
-		LikesCount:    likesCount,
-		IsLikedByUser: isLiked,
-	}
+	postResp := s.withCounts(ctx, post, userID)
 
 	postJSON, err := json.Marshal(postResp)
 	if err == nil {
@@ -157,4 +289,639 @@ func (s *PostService) GetPostByID(ctx context.Context, id uint, userID uint) (*P
 	}
 
 	return postResp, nil
-}
\ No newline at end of file
+}
+
+// withCounts is the response for post with the like and comment counts viewerID sees.
+func (s *PostService) withCounts(ctx context.Context, post *models.Post, viewerID uint) *PostResponse {
+	postResp := newPostResponse(post)
+	postResp.LikesCount, _ = s.likeService.GetPostLikes(ctx, post.ID)
+	postResp.CommentsCount = post.CommentsCount
+	postResp.IsLikedByUser, _ = s.likeService.likeRepo.IsLiked(ctx, viewerID, post.ID)
+	return postResp
+}
+
+// writtenPost reads a post back after a write, so its author, categories and
+// tags come back in the same shape as GetPostByID returns them.
+func (s *PostService) writtenPost(ctx context.Context, id, actorID uint) (*PostResponse, error) {
+	post, err := s.repo.FindVisibleByID(ctx, id, repositories.PostVisibility{All: true})
+	if err != nil {
+		return nil, err
+	}
+	return s.withCounts(ctx, post, actorID), nil
+}
+
+// UpdatePost applies the given changes to a post once the policy allows the actor to edit it.
+// expectedVersion is the version the editor started from; a stale one yields a
+// *repositories.VersionConflictError. 0 applies the changes to whatever version is current.
+func (s *PostService) UpdatePost(ctx context.Context, id uint, actor *policy.Subject, req *requests.UpdatePostRequest, expectedVersion uint) (*PostResponse, error) {
+	post, err := s.findPost(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionPostUpdate, policy.PostResource(post)).Err(); err != nil {
+		return nil, err
+	}
+	if expectedVersion == 0 {
+		expectedVersion = post.Version
+	}
+	if post.Version != expectedVersion {
+		return nil, &repositories.VersionConflictError{Current: post.Version}
+	}
+
+	// Keep a copy of the old values so both the old and new listing caches are cleared
+	before := *post
+
+	if req.Title != nil {
+		post.Title = *req.Title
+	}
+	if req.Content != nil {
+		post.Content = bluemonday.UGCPolicy().Sanitize(*req.Content)
+	}
+	if req.Type != nil {
+		post.Type = *req.Type
+	}
+	if req.Lang != nil {
+		post.Lang = *req.Lang
+	}
+	if req.ImageUrl != nil {
+		post.ImageUrl = *req.ImageUrl
+	}
+	if req.Slug != nil {
+		if err := s.assignSlug(ctx, post, *req.Slug); err != nil {
+			return nil, err
+		}
+	} else if post.Title != before.Title || post.Lang != before.Lang {
+		if err := s.assignSlug(ctx, post, ""); err != nil {
+			return nil, err
+		}
+	}
+	if req.CategoryIDs != nil {
+		if post.Categories, err = s.taxonomy.ResolveCategories(ctx, *req.CategoryIDs); err != nil {
+			return nil, err
+		}
+	}
+	if req.Tags != nil {
+		if post.Tags, err = s.taxonomy.ResolveTags(ctx, *req.Tags); err != nil {
+			return nil, err
+		}
+	}
+	post.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateWithRevision(ctx, post, expectedVersion, actor.UserID, s.revisionLimit); err != nil {
+		return nil, err
+	}
+	// Categories and tags aren't in the snapshots, so what they were set to is noted
+	details := map[string]interface{}{}
+	if req.CategoryIDs != nil {
+		details["category_ids"] = *req.CategoryIDs
+	}
+	if req.Tags != nil {
+		details["tags"] = *req.Tags
+	}
+	s.audit(ctx, "post.update", actor.UserID, post.ID, postSnapshot(&before), postSnapshot(post), details)
+	if err := s.InvalidatePostCaches(ctx, &before, post); err != nil {
+		return nil, err
+	}
+	return s.writtenPost(ctx, post.ID, actor.UserID)
+}
+
+// DeletePost moves a post into the trash.
+func (s *PostService) DeletePost(ctx context.Context, id uint, actor *policy.Subject) error {
+	post, err := s.findPost(ctx, id, false)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionPostDelete, policy.PostResource(post)).Err(); err != nil {
+		return err
+	}
+
+	if err := s.repo.SoftDelete(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, "post.delete", actor.UserID, post.ID, postSnapshot(post), nil, nil)
+	return s.InvalidatePostCaches(ctx, post)
+}
+
+// RestorePost brings a trashed post back.
+func (s *PostService) RestorePost(ctx context.Context, id uint, actor *policy.Subject) error {
+	post, err := s.findPost(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	if !post.DeletedAt.Valid {
+		return utils.ErrPostNotFound
+	}
+	if err := s.policy.Authorize(actor, policy.ActionPostRestore, policy.PostResource(post)).Err(); err != nil {
+		return err
+	}
+
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, "post.restore", actor.UserID, post.ID, nil, postSnapshot(post), nil)
+	return s.InvalidatePostCaches(ctx, post)
+}
+
+// PurgePost permanently removes a post, whether or not it is in the trash.
+func (s *PostService) PurgePost(ctx context.Context, id uint, actor *policy.Subject) error {
+	post, err := s.findPost(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionPostPurge, policy.PostResource(post)).Err(); err != nil {
+		return err
+	}
+
+	if err := s.repo.Purge(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, "post.purge", actor.UserID, post.ID, postSnapshot(post), nil, nil)
+	return s.InvalidatePostCaches(ctx, post)
+}
+
+// GetTrashedPosts lists trashed posts: the actor's own, or everyone's when they may restore any post.
+func (s *PostService) GetTrashedPosts(ctx context.Context, actor *policy.Subject, page, limit int) ([]PostResponse, error) {
+	ownerID := actor.UserID
+	if s.policy.CanAny(actor, policy.ActionPostRestore) {
+		ownerID = 0
+	}
+
+	posts, err := s.repo.GetTrashed(ctx, ownerID, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trashed posts from DB: %w", err)
+	}
+
+	postResponses := make([]PostResponse, len(posts))
+	for i, post := range posts {
+		deletedAt := post.DeletedAt.Time
+		postResponses[i] = PostResponse{
+			ID:        post.ID,
+			Title:     post.Title,
+			Slug:      post.Slug,
+			Content:   post.Content,
+			Type:      post.Type,
+			Lang:      post.Lang,
+			ImageUrl:  post.ImageUrl,
+			UserID:    post.UserID,
+			User:      newPostAuthor(post.User),
+			Version:   post.Version,
+			Status:    post.Status,
+			CreatedAt: post.CreatedAt,
+			UpdatedAt: post.UpdatedAt,
+			DeletedAt: &deletedAt,
+		}
+	}
+	return postResponses, nil
+}
+
+// TransitionInput describes a requested workflow transition.
+type TransitionInput struct {
+	Transition  workflow.Transition
+	Note        string
+	PublishAt   *time.Time // required when scheduling
+	UnpublishAt *time.Time // optional when scheduling or publishing
+}
+
+// TransitionPost moves a post through the editorial workflow and records who did it.
+func (s *PostService) TransitionPost(ctx context.Context, id uint, actor *policy.Subject, input TransitionInput) (*PostResponse, error) {
+	post, err := s.findPost(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	step, err := workflow.Next(post.Status, input.Transition)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(actor, step.Action, policy.PostResource(post)).Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := applySchedule(post, input, now); err != nil {
+		return nil, err
+	}
+
+	from := post.Status
+	post.Status = step.To
+	post.StatusChangedAt = &now
+	post.StatusChangedBy = &actor.UserID
+	if step.To == models.PostStatusPublished && post.PublishedAt == nil {
+		post.PublishedAt = &now
+	}
+
+	record := &models.PostTransition{
+		PostID:     post.ID,
+		FromStatus: from,
+		ToStatus:   step.To,
+		Transition: string(input.Transition),
+		ActorID:    &actor.UserID,
+		Note:       input.Note,
+		CreatedAt:  now,
+	}
+	if err := s.repo.Transition(ctx, post, record); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Someone else moved the post first
+			return nil, fmt.Errorf("%w: post is no longer %s", workflow.ErrInvalidTransition, from)
+		}
+		return nil, err
+	}
+	s.audit(ctx, "post.transition", actor.UserID, post.ID, nil, nil, map[string]interface{}{
+		"transition": string(input.Transition),
+		"from":       from,
+		"to":         step.To,
+	})
+
+	if err := s.InvalidatePostCaches(ctx, post); err != nil {
+		return nil, err
+	}
+	return s.writtenPost(ctx, post.ID, actor.UserID)
+}
+
+// applySchedule validates and sets publish_at/unpublish_at for a transition. Only a
+// scheduled or published post keeps a schedule; any other move clears it.
+func applySchedule(post *models.Post, input TransitionInput, now time.Time) error {
+	switch input.Transition {
+	case workflow.Schedule:
+		if input.PublishAt == nil || !input.PublishAt.After(now) {
+			return fmt.Errorf("%w: publish_at must be in the future", workflow.ErrInvalidSchedule)
+		}
+		if input.UnpublishAt != nil && !input.UnpublishAt.After(*input.PublishAt) {
+			return fmt.Errorf("%w: unpublish_at must be after publish_at", workflow.ErrInvalidSchedule)
+		}
+		post.PublishAt = input.PublishAt
+		post.UnpublishAt = input.UnpublishAt
+	case workflow.Publish:
+		if input.PublishAt != nil {
+			return fmt.Errorf("%w: use the schedule transition to publish later", workflow.ErrInvalidSchedule)
+		}
+		if input.UnpublishAt != nil && !input.UnpublishAt.After(now) {
+			return fmt.Errorf("%w: unpublish_at must be in the future", workflow.ErrInvalidSchedule)
+		}
+		// Publishing a scheduled post early replaces its schedule
+		post.PublishAt = nil
+		post.UnpublishAt = input.UnpublishAt
+	default:
+		if input.PublishAt != nil || input.UnpublishAt != nil {
+			return fmt.Errorf("%w: %s does not take a schedule", workflow.ErrInvalidSchedule, input.Transition)
+		}
+		post.PublishAt = nil
+		post.UnpublishAt = nil
+	}
+	return nil
+}
+
+// GetPostTransitions returns the workflow history of a post to its author or a reviewer.
+func (s *PostService) GetPostTransitions(ctx context.Context, id uint, actor *policy.Subject) ([]PostTransitionResponse, error) {
+	post, err := s.findPost(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionPostHistory, policy.PostResource(post)).Err(); err != nil {
+		return nil, err
+	}
+	transitions, err := s.repo.GetTransitions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]PostTransitionResponse, len(transitions))
+	for i, t := range transitions {
+		responses[i] = PostTransitionResponse{
+			ID:         t.ID,
+			FromStatus: t.FromStatus,
+			ToStatus:   t.ToStatus,
+			Transition: t.Transition,
+			ActorID:    t.ActorID,
+			Actor:      optionalPostAuthor(t.Actor),
+			Note:       t.Note,
+			CreatedAt:  t.CreatedAt,
+		}
+	}
+	return responses, nil
+}
+
+// GetPostRevisions lists the saved revisions of a post to its author or a reviewer.
+func (s *PostService) GetPostRevisions(ctx context.Context, id uint, actor *policy.Subject) ([]PostRevisionResponse, error) {
+	post, err := s.findPost(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionPostHistory, policy.PostResource(post)).Err(); err != nil {
+		return nil, err
+	}
+	revisions, err := s.repo.GetRevisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]PostRevisionResponse, len(revisions))
+	for i, r := range revisions {
+		responses[i] = PostRevisionResponse{
+			ID:        r.ID,
+			Revision:  r.Revision,
+			Title:     r.Title,
+			Type:      r.Type,
+			Lang:      r.Lang,
+			ImageUrl:  r.ImageUrl,
+			AuthorID:  r.AuthorID,
+			Author:    optionalPostAuthor(r.Author),
+			CreatedAt: r.CreatedAt,
+		}
+	}
+	return responses, nil
+}
+
+// RevisionDiff is a word-level comparison between two revisions of a post.
+type RevisionDiff struct {
+	From    int               `json:"from"`
+	To      int               `json:"to"`
+	Title   []utils.DiffChunk `json:"title"`
+	Content []utils.DiffChunk `json:"content"`
+}
+
+// DiffPostRevisions compares revision rev against another revision of the same post.
+// When against is 0 the previous revision is used, or an empty post if there is none.
+func (s *PostService) DiffPostRevisions(ctx context.Context, id uint, rev, against int, actor *policy.Subject) (*RevisionDiff, error) {
+	post, err := s.findPost(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionPostHistory, policy.PostResource(post)).Err(); err != nil {
+		return nil, err
+	}
+
+	to, err := s.findRevision(ctx, id, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	from := &models.PostRevision{}
+	if against != 0 {
+		if from, err = s.findRevision(ctx, id, against); err != nil {
+			return nil, err
+		}
+	} else if rev > 1 {
+		from, err = s.findRevision(ctx, id, rev-1)
+		if errors.Is(err, utils.ErrRevisionNotFound) {
+			// The previous revision was pruned, so compare against nothing
+			from, err = &models.PostRevision{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := bluemonday.UGCPolicy()
+	return &RevisionDiff{
+		From:    from.Revision,
+		To:      to.Revision,
+		Title:   utils.WordDiff(from.Title, to.Title),
+		Content: utils.WordDiff(p.Sanitize(from.Content), p.Sanitize(to.Content)),
+	}, nil
+}
+
+// RestorePostRevision copies an old revision back onto the post, which is saved as a new revision.
+// A non-zero expectedVersion makes the restore conditional like UpdatePost.
+func (s *PostService) RestorePostRevision(ctx context.Context, id uint, rev int, actor *policy.Subject, expectedVersion uint) (*PostResponse, error) {
+	post, err := s.findPost(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(actor, policy.ActionPostUpdate, policy.PostResource(post)).Err(); err != nil {
+		return nil, err
+	}
+	if expectedVersion == 0 {
+		expectedVersion = post.Version
+	}
+	if post.Version != expectedVersion {
+		return nil, &repositories.VersionConflictError{Current: post.Version}
+	}
+
+	revision, err := s.findRevision(ctx, id, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *post
+	post.Title = revision.Title
+	post.Content = bluemonday.UGCPolicy().Sanitize(revision.Content)
+	post.Type = revision.Type
+	post.Lang = revision.Lang
+	post.ImageUrl = revision.ImageUrl
+	if post.Title != before.Title || post.Lang != before.Lang {
+		if err := s.assignSlug(ctx, post, ""); err != nil {
+			return nil, err
+		}
+	}
+	post.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateWithRevision(ctx, post, expectedVersion, actor.UserID, s.revisionLimit); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "post.revision.restore", actor.UserID, post.ID, postSnapshot(&before), postSnapshot(post), map[string]interface{}{"revision": rev})
+	if err := s.InvalidatePostCaches(ctx, &before, post); err != nil {
+		return nil, err
+	}
+	return s.writtenPost(ctx, post.ID, actor.UserID)
+}
+
+func (s *PostService) findRevision(ctx context.Context, postID uint, rev int) (*models.PostRevision, error) {
+	revision, err := s.repo.FindRevision(ctx, postID, rev)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrRevisionNotFound
+	}
+	return revision, err
+}
+
+// SearchPosts runs a full-text search over the posts viewer may read and returns one
+// page of hits together with the total number of matches.
+func (s *PostService) SearchPosts(ctx context.Context, q repositories.SearchQuery, page, limit int, viewer *policy.Subject) ([]repositories.SearchHit, int64, error) {
+	q.Text = strings.TrimSpace(utils.NormalizeSearchText(q.Text))
+	if q.Text == "" {
+		return []repositories.SearchHit{}, 0, nil
+	}
+	return s.repo.Search(ctx, q, s.visibility(viewer), page, limit)
+}
+
+// PostLocation is where a post that moved to a new slug can now be found.
+type PostLocation struct {
+	Lang string
+	Slug string
+}
+
+// GetPostBySlug returns the post with the given slug in lang. When the slug is an old
+// one, the post's current location is returned instead so the caller can redirect.
+func (s *PostService) GetPostBySlug(ctx context.Context, lang, slug string, viewer *policy.Subject) (*PostResponse, *PostLocation, error) {
+	slug = utils.Slugify(slug)
+	id, err := s.repo.FindIDBySlug(ctx, lang, slug)
+	if err == nil {
+		post, err := s.GetPostByID(ctx, id, viewer)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, utils.ErrPostNotFound
+		}
+		return post, nil, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	redirect, err := s.repo.FindSlugRedirect(ctx, lang, slug)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, utils.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only reveal the new address of posts the viewer could read there
+	post, err := s.repo.FindVisibleByID(ctx, redirect.PostID, s.visibility(viewer))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, utils.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, &PostLocation{Lang: post.Lang, Slug: post.Slug}, nil
+}
+
+// assignSlug sets post.Slug. A requested slug is normalized and must be free in the
+// post's language; without one the slug is derived from the title and made unique
+// with a numeric suffix.
+func (s *PostService) assignSlug(ctx context.Context, post *models.Post, requested string) error {
+	if requested != "" {
+		slug := utils.Slugify(requested)
+		if slug == "" {
+			return utils.ErrInvalidSlug
+		}
+		taken, err := s.repo.SlugsWithPrefix(ctx, post.Lang, slug, post.ID)
+		if err != nil {
+			return err
+		}
+		for _, t := range taken {
+			if t == slug {
+				return utils.ErrSlugTaken
+			}
+		}
+		post.Slug = slug
+		return nil
+	}
+
+	base := utils.Slugify(post.Title)
+	if base == "" {
+		base = post.Type
+	}
+	taken, err := s.repo.SlugsWithPrefix(ctx, post.Lang, base, post.ID)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool, len(taken))
+	for _, t := range taken {
+		used[t] = true
+	}
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	post.Slug = slug
+	return nil
+}
+
+// visibility works out which workflow states viewer may read.
+func (s *PostService) visibility(viewer *policy.Subject) repositories.PostVisibility {
+	if viewer == nil {
+		return repositories.PostVisibility{}
+	}
+	return repositories.PostVisibility{
+		ViewerID: viewer.UserID,
+		All:      s.policy.CanAny(viewer, policy.ActionPostReview),
+	}
+}
+
+// FindVisiblePost loads a live post if viewer, which is nil for anonymous readers, may see it.
+func (s *PostService) FindVisiblePost(ctx context.Context, id uint, viewer *policy.Subject) (*models.Post, error) {
+	post, err := s.repo.FindVisibleByID(ctx, id, s.visibility(viewer))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrPostNotFound
+	}
+	return post, err
+}
+
+func (s *PostService) findPost(ctx context.Context, id uint, withTrashed bool) (*models.Post, error) {
+	var post *models.Post
+	var err error
+	if withTrashed {
+		post, err = s.repo.FindByIDWithTrashed(ctx, id)
+	} else {
+		post, err = s.repo.FindByID(ctx, id)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrPostNotFound
+	}
+	return post, err
+}
+
+// InvalidatePostCaches clears the listing pages for each post's lang/type and
+// every per-user cached copy of the post itself.
+func (s *PostService) InvalidatePostCaches(ctx context.Context, posts ...*models.Post) error {
+	for _, post := range posts {
+		if err := deleteKeys(ctx, s.redisClient,
+			fmt.Sprintf("posts:lang:%s:type:%s:*", post.Lang, post.Type),
+			fmt.Sprintf("post:%d:user:*", post.ID),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateAllPostCaches drops every cached post and listing, e.g. after posts
+// change author.
+func (s *PostService) InvalidateAllPostCaches(ctx context.Context) error {
+	return deleteKeys(ctx, s.redisClient, "posts:lang:*", "post:*:user:*")
+}
+
+func (s *PostService) audit(ctx context.Context, action string, actorID, postID uint, before, after interface{}, details map[string]interface{}) {
+	s.auditor.Record(ctx, AuditEvent{Action: action, ActorID: actorID, TargetType: "post", TargetID: postID, Before: before, After: after, Details: details})
+}
+
+// postSnapshot is the state of a post as kept in the audit log. The content is
+// left out: revisions keep it, and it would bloat every entry.
+func postSnapshot(post *models.Post) map[string]interface{} {
+	return map[string]interface{}{
+		"title":     post.Title,
+		"slug":      post.Slug,
+		"type":      post.Type,
+		"lang":      post.Lang,
+		"status":    post.Status,
+		"image_url": post.ImageUrl,
+		"author_id": post.UserID,
+	}
+}
+
+// deleteKeysBatch is how many keys deleteKeys asks SCAN for, and deletes, at a time.
+const deleteKeysBatch = 500
+
+// deleteKeys removes every Redis key matching one of the patterns. It walks the
+// keyspace with SCAN rather than KEYS so Redis never blocks on one long command.
+func deleteKeys(ctx context.Context, client *redis.Client, patterns ...string) error {
+	for _, pattern := range patterns {
+		keys := make([]string, 0, deleteKeysBatch)
+		iter := client.Scan(ctx, 0, pattern, deleteKeysBatch).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+			if len(keys) == deleteKeysBatch {
+				if err := client.Del(ctx, keys...).Err(); err != nil {
+					return err
+				}
+				keys = keys[:0]
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
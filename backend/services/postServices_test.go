@@ -0,0 +1,55 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+)
+
+func TestPostResponseHidesAuthorAccount(t *testing.T) {
+	email := "author@example.com"
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	post := &models.Post{Title: "Edited", Lang: "en", Version: 3, UserID: 7}
+	post.ID = 42
+	post.User = models.User{
+		Username:         "author",
+		Password:         "$2a$10$hash",
+		Email:            &email,
+		EmailVerifiedAt:  &now,
+		DisplayName:      "The Author",
+		Roles:            []models.Role{{Name: "editor"}},
+		SuspendedAt:      &now,
+		SuspendedUntil:   &now,
+		SuspensionReason: "spam",
+	}
+	post.User.ID = 7
+
+	raw, err := json.Marshal(newPostResponse(post))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		User map[string]interface{} `json:"user"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatal(err)
+	}
+	for field := range body.User {
+		switch field {
+		case "id", "username", "display_name", "avatar_url":
+		default:
+			t.Errorf("author field %q in post response", field)
+		}
+	}
+	if body.User["display_name"] != "The Author" {
+		t.Errorf("author = %v", body.User)
+	}
+	for _, private := range []string{email, "$2a$10$hash", "spam", "suspended", "editor"} {
+		if strings.Contains(string(raw), private) {
+			t.Errorf("post response contains %q: %s", private, raw)
+		}
+	}
+}
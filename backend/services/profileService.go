@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Profile is what anyone may see of a user.
+type Profile struct {
+	ID          uint              `json:"id"`
+	Username    string            `json:"username"`
+	DisplayName string            `json:"display_name"`
+	Bio         string            `json:"bio"`
+	AvatarURL   string            `json:"avatar_url"`
+	SocialLinks map[string]string `json:"social_links"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// Account is a user's own view of their account.
+type Account struct {
+	Profile
+	Email           *string    `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Locale          string     `json:"locale"`
+	Roles           []string   `json:"roles"`
+}
+
+// ProfileService serves public profiles and the self-service account settings.
+type ProfileService struct {
+	userRepo    *repositories.UserRepository
+	postService *PostService
+	authService *AuthService
+}
+
+func NewProfileService(userRepo *repositories.UserRepository, postService *PostService, authService *AuthService) *ProfileService {
+	return &ProfileService{userRepo: userRepo, postService: postService, authService: authService}
+}
+
+func newProfile(user *models.User) Profile {
+	links := user.SocialLinks
+	if links == nil {
+		links = map[string]string{}
+	}
+	return Profile{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarURL,
+		SocialLinks: links,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+func newAccount(user *models.User) *Account {
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.Name
+	}
+	return &Account{
+		Profile:         newProfile(user),
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Locale:          user.Locale,
+		Roles:           roles,
+	}
+}
+
+// Me returns the user's own account.
+func (s *ProfileService) Me(ctx context.Context, userID uint) (*Account, error) {
+	user, err := s.authService.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return newAccount(user), nil
+}
+
+// UpdateMe changes the fields set in req. Display name and bio are stored as plain
+// text, so any markup in them is dropped.
+func (s *ProfileService) UpdateMe(ctx context.Context, userID uint, req *requests.UpdateProfileRequest) (*Account, error) {
+	user, err := s.authService.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	if req.DisplayName != nil {
+		user.DisplayName = plainText(*req.DisplayName)
+		columns = append(columns, "display_name")
+	}
+	if req.Bio != nil {
+		user.Bio = plainText(*req.Bio)
+		columns = append(columns, "bio")
+	}
+	if req.AvatarURL != nil {
+		user.AvatarURL = strings.TrimSpace(*req.AvatarURL)
+		columns = append(columns, "avatar_url")
+	}
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+		columns = append(columns, "locale")
+	}
+	if req.SocialLinks != nil {
+		links := make(map[string]string, len(*req.SocialLinks))
+		for network, link := range *req.SocialLinks {
+			if link = strings.TrimSpace(link); link != "" {
+				links[network] = link
+			}
+		}
+		user.SocialLinks = links
+		columns = append(columns, "social_links")
+	}
+	if len(columns) == 0 {
+		return newAccount(user), nil
+	}
+
+	if err := s.userRepo.UpdateProfile(ctx, user, columns...); err != nil {
+		return nil, err
+	}
+	// Cached posts carry their author's name and avatar
+	if changesPostAuthor(columns) {
+		if err := s.postService.InvalidateAllPostCaches(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return newAccount(user), nil
+}
+
+// changesPostAuthor reports whether any of the user columns appear in PostAuthor.
+func changesPostAuthor(columns []string) bool {
+	for _, column := range columns {
+		if column == "display_name" || column == "avatar_url" {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangePassword replaces the password after checking the current one, and signs
+// the user out of every session but keepSessionID.
+func (s *ProfileService) ChangePassword(ctx context.Context, userID uint, keepSessionID, current, password string) error {
+	user, err := s.authService.FindUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)) != nil {
+		return utils.ErrWrongPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userID, string(hash)); err != nil {
+		return err
+	}
+	_, err = s.authService.RevokeOtherSessions(ctx, userID, keepSessionID)
+	return err
+}
+
+// PublicProfile returns the profile of the user called username.
+func (s *ProfileService) PublicProfile(ctx context.Context, username string) (*Profile, error) {
+	user, err := s.findByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	profile := newProfile(user)
+	return &profile, nil
+}
+
+// AuthorPosts lists the published posts of the user called username, as seen by
+// viewer, which is nil for anonymous readers.
+func (s *ProfileService) AuthorPosts(ctx context.Context, username string, filter repositories.PostFilter, page, limit int, viewer *policy.Subject) ([]PostResponse, error) {
+	user, err := s.findByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	filter.AuthorID = user.ID
+	filter.Status = models.PostStatusPublished
+	return s.postService.GetPosts(ctx, filter, page, limit, viewer)
+}
+
+func (s *ProfileService) findByUsername(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrUserNotFound
+	}
+	return user, err
+}
+
+// plainText strips any markup from s, leaving the text as it would be read.
+func plainText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(bluemonday.StrictPolicy().Sanitize(s)))
+}
@@ -0,0 +1,37 @@
+package services
+
+import "testing"
+
+func TestChangesPostAuthor(t *testing.T) {
+	tests := []struct {
+		columns []string
+		want    bool
+	}{
+		{nil, false},
+		{[]string{"bio", "locale", "social_links"}, false},
+		{[]string{"display_name"}, true},
+		{[]string{"bio", "avatar_url"}, true},
+	}
+	for _, tt := range tests {
+		if got := changesPostAuthor(tt.columns); got != tt.want {
+			t.Errorf("changesPostAuthor(%v) = %v, want %v", tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"  Ali Mosavi  ", "Ali Mosavi"},
+		{"<b>bold</b> move", "bold move"},
+		{"<script>alert(1)</script>R&D", "R&D"},
+		{"Tom & Jerry <3", "Tom & Jerry <3"},
+		{"برنامه‌نویس <i>Go</i>", "برنامه‌نویس Go"},
+	}
+	for _, tt := range tests {
+		if got := plainText(tt.in); got != tt.want {
+			t.Errorf("plainText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"gorm.io/gorm"
+)
+
+// protectedRoles can't be renamed or deleted: new users get "user", and "admin" is
+// how the first admin manages everything else.
+var protectedRoles = map[string]bool{"user": true, "admin": true}
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// RoleService lets admins manage roles, the permissions they grant and who has
+// them. Every change is audited and drops the affected cached permissions.
+type RoleService struct {
+	roleRepo    *repositories.RoleRepository
+	authService *AuthService
+	auditor     Auditor
+}
+
+func NewRoleService(roleRepo *repositories.RoleRepository, authService *AuthService, auditor Auditor) *RoleService {
+	return &RoleService{roleRepo: roleRepo, authService: authService, auditor: auditor}
+}
+
+// PermissionInfo is a permission as admins see it. Registered permissions are
+// checked by the application and can't be renamed or deleted.
+type PermissionInfo struct {
+	models.Permission
+	Description string `json:"description,omitempty"`
+	Registered  bool   `json:"registered"`
+}
+
+// SyncPermissions creates the registered permissions that don't exist yet. It runs
+// at startup, so a permission added to policy.Permissions can be granted at once.
+func (s *RoleService) SyncPermissions(ctx context.Context) error {
+	return s.roleRepo.EnsurePermissions(ctx, policy.PermissionNames())
+}
+
+func (s *RoleService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return s.roleRepo.ListRoles(ctx)
+}
+
+func (s *RoleService) GetRole(ctx context.Context, id uint) (*models.Role, error) {
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrRoleNotFound
+	}
+	return role, err
+}
+
+func (s *RoleService) CreateRole(ctx context.Context, actorID uint, name string, client ClientInfo) (*models.Role, error) {
+	name, err := s.availableRoleName(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	role := &models.Role{Name: name}
+	if err := s.roleRepo.CreateRole(ctx, role); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "role.create", actorID, role.ID, client, map[string]interface{}{"role": name})
+	return role, nil
+}
+
+func (s *RoleService) RenameRole(ctx context.Context, actorID, id uint, name string, client ClientInfo) (*models.Role, error) {
+	role, err := s.GetRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if protectedRoles[role.Name] {
+		return nil, utils.ErrProtectedRole
+	}
+	if name, err = s.availableRoleName(ctx, name, id); err != nil {
+		return nil, err
+	}
+	if err := s.roleRepo.RenameRole(ctx, id, name); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "role.rename", actorID, id, client, map[string]interface{}{"from": role.Name, "to": name})
+	role.Name = name
+	return role, nil
+}
+
+// DeleteRole removes a role from everyone who has it.
+func (s *RoleService) DeleteRole(ctx context.Context, actorID, id uint, client ClientInfo) error {
+	role, err := s.GetRole(ctx, id)
+	if err != nil {
+		return err
+	}
+	if protectedRoles[role.Name] {
+		return utils.ErrProtectedRole
+	}
+	if err := s.roleRepo.DeleteRole(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, "role.delete", actorID, id, client, map[string]interface{}{"role": role.Name})
+	return s.authService.InvalidateAllPermissions(ctx)
+}
+
+// ListPermissions returns every permission, marking the registered ones.
+func (s *RoleService) ListPermissions(ctx context.Context) ([]PermissionInfo, error) {
+	permissions, err := s.roleRepo.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	descriptions := make(map[string]string, len(policy.Permissions))
+	for _, p := range policy.Permissions {
+		descriptions[p.Name] = p.Description
+	}
+	infos := make([]PermissionInfo, len(permissions))
+	for i, p := range permissions {
+		infos[i] = PermissionInfo{Permission: p, Description: descriptions[p.Name], Registered: policy.IsRegistered(p.Name)}
+	}
+	return infos, nil
+}
+
+// CreatePermission adds a permission the application doesn't check itself, for
+// grouping grants or for API key scopes.
+func (s *RoleService) CreatePermission(ctx context.Context, actorID uint, name string, client ClientInfo) (*models.Permission, error) {
+	name, err := s.availablePermissionName(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	permission := &models.Permission{Name: name}
+	if err := s.roleRepo.CreatePermission(ctx, permission); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "permission.create", actorID, permission.ID, client, map[string]interface{}{"permission": name})
+	return permission, nil
+}
+
+func (s *RoleService) RenamePermission(ctx context.Context, actorID, id uint, name string, client ClientInfo) (*models.Permission, error) {
+	permission, err := s.findPermission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy.IsRegistered(permission.Name) {
+		return nil, utils.ErrProtectedPermission
+	}
+	if name, err = s.availablePermissionName(ctx, name, id); err != nil {
+		return nil, err
+	}
+	if err := s.roleRepo.RenamePermission(ctx, id, name); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "permission.rename", actorID, id, client, map[string]interface{}{"from": permission.Name, "to": name})
+	permission.Name = name
+	return permission, s.authService.InvalidateAllPermissions(ctx)
+}
+
+func (s *RoleService) DeletePermission(ctx context.Context, actorID, id uint, client ClientInfo) error {
+	permission, err := s.findPermission(ctx, id)
+	if err != nil {
+		return err
+	}
+	if policy.IsRegistered(permission.Name) {
+		return utils.ErrProtectedPermission
+	}
+	if err := s.roleRepo.DeletePermission(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, "permission.delete", actorID, id, client, map[string]interface{}{"permission": permission.Name})
+	return s.authService.InvalidateAllPermissions(ctx)
+}
+
+// AttachPermission grants the named permission to a role.
+func (s *RoleService) AttachPermission(ctx context.Context, actorID, roleID uint, name string, client ClientInfo) (*models.Role, error) {
+	role, permission, err := s.roleAndPermission(ctx, roleID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.roleRepo.AttachPermission(ctx, role.ID, permission.ID); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "role.permission.attach", actorID, role.ID, client, map[string]interface{}{"role": role.Name, "permission": name})
+	if err := s.authService.InvalidateAllPermissions(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetRole(ctx, role.ID)
+}
+
+// DetachPermission takes the named permission away from a role. The admin role
+// always keeps manage_users.
+func (s *RoleService) DetachPermission(ctx context.Context, actorID, roleID uint, name string, client ClientInfo) (*models.Role, error) {
+	role, permission, err := s.roleAndPermission(ctx, roleID, name)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == "admin" && name == "manage_users" {
+		return nil, utils.ErrAdminLockout
+	}
+	detached, err := s.roleRepo.DetachPermission(ctx, role.ID, permission.ID)
+	if err != nil {
+		return nil, err
+	}
+	if detached {
+		s.audit(ctx, "role.permission.detach", actorID, role.ID, client, map[string]interface{}{"role": role.Name, "permission": name})
+		if err := s.authService.InvalidateAllPermissions(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetRole(ctx, role.ID)
+}
+
+// GetUserRoles returns the roles a user has.
+func (s *RoleService) GetUserRoles(ctx context.Context, userID uint) ([]models.Role, error) {
+	if _, err := s.authService.FindUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.GetUserRoles(ctx, userID)
+}
+
+// AssignRole gives a user a role; assigning one they have is not an error.
+func (s *RoleService) AssignRole(ctx context.Context, actorID, userID, roleID uint, client ClientInfo) error {
+	if _, err := s.authService.FindUser(ctx, userID); err != nil {
+		return err
+	}
+	role, err := s.GetRole(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.roleRepo.AssignRoleToUser(ctx, userID, role.ID); err != nil {
+		return err
+	}
+	s.audit(ctx, "user.role.assign", actorID, userID, client, map[string]interface{}{"role": role.Name})
+	return s.authService.InvalidatePermissions(ctx, userID)
+}
+
+// RevokeRole takes a role away from a user. The last admin keeps the admin role,
+// so there is always someone who can give it back.
+func (s *RoleService) RevokeRole(ctx context.Context, actorID, userID, roleID uint, client ClientInfo) error {
+	if _, err := s.authService.FindUser(ctx, userID); err != nil {
+		return err
+	}
+	role, err := s.GetRole(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if role.Name == "admin" {
+		admins, err := s.roleRepo.CountRoleUsers(ctx, role.ID)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return utils.ErrAdminLockout
+		}
+	}
+
+	revoked, err := s.roleRepo.RevokeRoleFromUser(ctx, userID, role.ID)
+	if err != nil || !revoked {
+		return err
+	}
+	s.audit(ctx, "user.role.revoke", actorID, userID, client, map[string]interface{}{"role": role.Name})
+	return s.authService.InvalidatePermissions(ctx, userID)
+}
+
+func (s *RoleService) findPermission(ctx context.Context, id uint) (*models.Permission, error) {
+	permission, err := s.roleRepo.FindPermissionByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrPermissionNotFound
+	}
+	return permission, err
+}
+
+func (s *RoleService) roleAndPermission(ctx context.Context, roleID uint, name string) (*models.Role, *models.Permission, error) {
+	role, err := s.GetRole(ctx, roleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	permission, err := s.roleRepo.FindPermissionByName(ctx, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, utils.ErrPermissionNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return role, permission, nil
+}
+
+// availableRoleName normalizes name and checks no other role than exceptID has it.
+func (s *RoleService) availableRoleName(ctx context.Context, name string, exceptID uint) (string, error) {
+	name, err := normalizeName(name)
+	if err != nil {
+		return "", err
+	}
+	existing, err := s.roleRepo.FindByName(ctx, name)
+	if err == nil && existing.ID != exceptID {
+		return "", utils.ErrRoleExists
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	return name, nil
+}
+
+// availablePermissionName normalizes name and checks no other permission than
+// exceptID has it.
+func (s *RoleService) availablePermissionName(ctx context.Context, name string, exceptID uint) (string, error) {
+	name, err := normalizeName(name)
+	if err != nil {
+		return "", err
+	}
+	existing, err := s.roleRepo.FindPermissionByName(ctx, name)
+	if err == nil && existing.ID != exceptID {
+		return "", utils.ErrPermissionExists
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	return name, nil
+}
+
+// audit records a change; the action's first segment names the target's type.
+func (s *RoleService) audit(ctx context.Context, action string, actorID, targetID uint, client ClientInfo, details map[string]interface{}) {
+	targetType, _, _ := strings.Cut(action, ".")
+	s.auditor.Record(ctx, AuditEvent{Action: action, ActorID: actorID, TargetType: targetType, TargetID: targetID, IPAddress: client.IPAddress, Details: details})
+}
+
+// normalizeName lowercases a role or permission name and checks it is a plain
+// identifier like "edit_post".
+func normalizeName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !namePattern.MatchString(name) {
+		return "", utils.ErrInvalidName
+	}
+	return name, nil
+}
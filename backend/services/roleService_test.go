@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"reviewer", "reviewer", nil},
+		{"  Guest_Author2 ", "guest_author2", nil},
+		{"2fa_user", "", utils.ErrInvalidName},
+		{"content-editor", "", utils.ErrInvalidName},
+		{"edit post", "", utils.ErrInvalidName},
+		{"", "", utils.ErrInvalidName},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeName(tt.in)
+		if got != tt.want || err != tt.err {
+			t.Errorf("normalizeName(%q) = %q, %v; want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/requests"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// CategoryNode is a category in the tree returned by the listing endpoints.
+// PostCount includes published posts filed under descendant categories.
+type CategoryNode struct {
+	ID        uint              `json:"id"`
+	ParentID  *uint             `json:"parent_id"`
+	Slug      string            `json:"slug"`
+	Names     map[string]string `json:"names"`
+	PostCount int64             `json:"post_count"`
+	Children  []*CategoryNode   `json:"children"`
+}
+
+type TaxonomyService struct {
+	repo        *repositories.TaxonomyRepository
+	redisClient *redis.Client
+}
+
+func NewTaxonomyService(repo *repositories.TaxonomyRepository, redisClient *redis.Client) *TaxonomyService {
+	return &TaxonomyService{repo: repo, redisClient: redisClient}
+}
+
+// GetCategoryTree returns the root categories with their descendants and post counts
+// for lang (empty for every language).
+func (s *TaxonomyService) GetCategoryTree(ctx context.Context, lang string) ([]*CategoryNode, error) {
+	nodes, err := s.categoryNodes(ctx, lang)
+	if err != nil {
+		return nil, err
+	}
+	roots := []*CategoryNode{}
+	for _, node := range nodes {
+		if node.ParentID == nil {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// GetCategory returns one category, looked up by slug, with its subtree and post counts.
+func (s *TaxonomyService) GetCategory(ctx context.Context, slug string, lang string) (*CategoryNode, error) {
+	nodes, err := s.categoryNodes(ctx, lang)
+	if err != nil {
+		return nil, err
+	}
+	slug = utils.Slugify(slug)
+	for _, node := range nodes {
+		if node.Slug == slug {
+			return node, nil
+		}
+	}
+	return nil, utils.ErrCategoryNotFound
+}
+
+// categoryNodes loads every category with its counts and links children to parents,
+// keeping the order of IDs.
+func (s *TaxonomyService) categoryNodes(ctx context.Context, lang string) ([]*CategoryNode, error) {
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountCategoryPosts(ctx, lang)
+	if err != nil {
+		return nil, err
+	}
+	postCounts := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		postCounts[c.CategoryID] = c.PostCount
+	}
+
+	nodes := make([]*CategoryNode, len(categories))
+	byID := make(map[uint]*CategoryNode, len(categories))
+	for i, category := range categories {
+		names := make(map[string]string, len(category.Names))
+		for _, n := range category.Names {
+			names[n.Lang] = n.Name
+		}
+		nodes[i] = &CategoryNode{
+			ID:        category.ID,
+			ParentID:  category.ParentID,
+			Slug:      category.Slug,
+			Names:     names,
+			PostCount: postCounts[category.ID],
+			Children:  []*CategoryNode{},
+		}
+		byID[category.ID] = nodes[i]
+	}
+	for _, node := range nodes {
+		if node.ParentID == nil {
+			continue
+		}
+		if parent, ok := byID[*node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return nodes, nil
+}
+
+// CreateCategory adds a category. Without an explicit slug one is derived from the
+// English name, falling back to the Persian one.
+func (s *TaxonomyService) CreateCategory(ctx context.Context, req *requests.CategoryRequest) (*models.Category, error) {
+	slug := req.Slug
+	if slug == "" {
+		slug = req.Names["en"]
+		if slug == "" {
+			slug = req.Names["fa"]
+		}
+	}
+	slug, err := s.availableCategorySlug(ctx, slug, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	category := &models.Category{Slug: slug, Names: categoryNames(req.Names)}
+	if req.ParentID != nil && *req.ParentID != 0 {
+		if _, err := s.findCategory(ctx, *req.ParentID); err != nil {
+			if errors.Is(err, utils.ErrCategoryNotFound) {
+				return nil, utils.ErrInvalidCategoryParent
+			}
+			return nil, err
+		}
+		category.ParentID = req.ParentID
+	}
+
+	if err := s.repo.CreateCategory(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// UpdateCategory renames, re-slugs or moves a category. A parent ID of 0 makes it a root;
+// moving a category beneath itself or one of its descendants is rejected.
+func (s *TaxonomyService) UpdateCategory(ctx context.Context, id uint, req *requests.UpdateCategoryRequest) (*models.Category, error) {
+	category, err := s.findCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Slug != nil {
+		if category.Slug, err = s.availableCategorySlug(ctx, *req.Slug, id); err != nil {
+			return nil, err
+		}
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			category.ParentID = nil
+		} else {
+			if err := s.checkParent(ctx, id, *req.ParentID); err != nil {
+				return nil, err
+			}
+			category.ParentID = req.ParentID
+		}
+	}
+	var names []models.CategoryName
+	if req.Names != nil {
+		names = categoryNames(req.Names)
+	}
+	category.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateCategory(ctx, category, names); err != nil {
+		return nil, err
+	}
+	// Posts embed their categories, and a new slug or parent changes category listings
+	if err := s.invalidatePostCaches(ctx); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory removes a category that has no subcategories. Posts filed under it keep
+// their other categories.
+func (s *TaxonomyService) DeleteCategory(ctx context.Context, id uint) error {
+	if _, err := s.findCategory(ctx, id); err != nil {
+		return err
+	}
+	children, err := s.repo.CountChildren(ctx, id)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return utils.ErrCategoryHasChildren
+	}
+	if err := s.repo.DeleteCategory(ctx, id); err != nil {
+		return err
+	}
+	return s.invalidatePostCaches(ctx)
+}
+
+// ResolveCategories loads the categories a post is being filed under.
+func (s *TaxonomyService) ResolveCategories(ctx context.Context, ids []uint) ([]models.Category, error) {
+	ids = uniqueIDs(ids)
+	categories, err := s.repo.FindCategoriesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) != len(ids) {
+		return nil, utils.ErrCategoryNotFound
+	}
+	return categories, nil
+}
+
+// GetTags lists tags with post counts, optionally only those starting with prefix.
+func (s *TaxonomyService) GetTags(ctx context.Context, prefix string, limit int) ([]repositories.TagCount, error) {
+	return s.repo.GetTags(ctx, utils.Slugify(prefix), limit)
+}
+
+// CreateTag adds a tag, or reports ErrSlugTaken when a spelling variant already exists.
+func (s *TaxonomyService) CreateTag(ctx context.Context, req *requests.TagRequest) (*models.Tag, error) {
+	tag, err := newTag(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.FindTagBySlug(ctx, tag.Slug); err == nil {
+		return nil, utils.ErrSlugTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err := s.repo.CreateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// UpdateTag renames a tag. Renaming onto another tag's normalized name is rejected.
+func (s *TaxonomyService) UpdateTag(ctx context.Context, id uint, req *requests.TagRequest) (*models.Tag, error) {
+	tag, err := s.findTag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	renamed, err := newTag(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := s.repo.FindTagBySlug(ctx, renamed.Slug); err == nil && existing.ID != id {
+		return nil, utils.ErrSlugTaken
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	tag.Name, tag.Slug = renamed.Name, renamed.Slug
+	if err := s.repo.UpdateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	if err := s.invalidatePostCaches(ctx); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// DeleteTag removes a tag from every post.
+func (s *TaxonomyService) DeleteTag(ctx context.Context, id uint) error {
+	if _, err := s.findTag(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTag(ctx, id); err != nil {
+		return err
+	}
+	return s.invalidatePostCaches(ctx)
+}
+
+// ResolveTags turns free-form tag names into tags, creating the ones that don't exist.
+// Names that normalize to the same slug collapse into one tag.
+func (s *TaxonomyService) ResolveTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	seen := make(map[string]bool, len(names))
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tag, err := newTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag.Slug] {
+			continue
+		}
+		seen[tag.Slug] = true
+		tags = append(tags, *tag)
+	}
+	return s.repo.FindOrCreateTags(ctx, tags)
+}
+
+func (s *TaxonomyService) findCategory(ctx context.Context, id uint) (*models.Category, error) {
+	category, err := s.repo.FindCategoryByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrCategoryNotFound
+	}
+	return category, err
+}
+
+func (s *TaxonomyService) findTag(ctx context.Context, id uint) (*models.Tag, error) {
+	tag, err := s.repo.FindTagByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrTagNotFound
+	}
+	return tag, err
+}
+
+// availableCategorySlug normalizes slug and checks no other category than exceptID uses it.
+func (s *TaxonomyService) availableCategorySlug(ctx context.Context, slug string, exceptID uint) (string, error) {
+	slug = utils.Slugify(slug)
+	if slug == "" {
+		return "", utils.ErrInvalidSlug
+	}
+	existing, err := s.repo.FindCategoryBySlug(ctx, slug)
+	if err == nil && existing.ID != exceptID {
+		return "", utils.ErrSlugTaken
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	return slug, nil
+}
+
+// checkParent makes sure parentID exists and is not id itself or one of its descendants.
+func (s *TaxonomyService) checkParent(ctx context.Context, id, parentID uint) error {
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		return err
+	}
+	parents := make(map[uint]*uint, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+	if _, ok := parents[parentID]; !ok {
+		return utils.ErrInvalidCategoryParent
+	}
+	// Walk up from the new parent; reaching id would close a cycle
+	for current, steps := &parentID, 0; current != nil && steps <= len(categories); current, steps = parents[*current], steps+1 {
+		if *current == id {
+			return fmt.Errorf("%w: a category cannot be moved beneath itself", utils.ErrInvalidCategoryParent)
+		}
+	}
+	return nil
+}
+
+// invalidatePostCaches drops every cached post and listing, since they embed categories and tags.
+func (s *TaxonomyService) invalidatePostCaches(ctx context.Context) error {
+	return deleteKeys(ctx, s.redisClient, "posts:*", "post:*")
+}
+
+func newTag(name string) (*models.Tag, error) {
+	cleaned := utils.CleanTagName(name)
+	slug := utils.Slugify(cleaned)
+	if slug == "" {
+		return nil, utils.ErrInvalidSlug
+	}
+	return &models.Tag{Name: cleaned, Slug: slug}, nil
+}
+
+func categoryNames(names map[string]string) []models.CategoryName {
+	result := make([]models.CategoryName, 0, len(names))
+	for lang, name := range names {
+		result = append(result, models.CategoryName{Lang: lang, Name: name})
+	}
+	return result
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+func TestNewTag(t *testing.T) {
+	tests := []struct {
+		in   string
+		name string
+		slug string
+		err  error
+	}{
+		{"#Go Lang", "Go Lang", "go-lang", nil},
+		{"  برنامه‌نويسي ", "برنامه‌نویسی", "برنامه-نویسی", nil},
+		{"كتاب ۱۴۰۲", "کتاب 1402", "کتاب-1402", nil},
+		{"###", "", "", utils.ErrInvalidSlug},
+		{"!?", "", "", utils.ErrInvalidSlug},
+	}
+
+	for _, tt := range tests {
+		tag, err := newTag(tt.in)
+		if err != tt.err {
+			t.Errorf("newTag(%q) error = %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if err == nil && (tag.Name != tt.name || tag.Slug != tt.slug) {
+			t.Errorf("newTag(%q) = %q/%q, want %q/%q", tt.in, tag.Name, tag.Slug, tt.name, tt.slug)
+		}
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	if got, want := uniqueIDs([]uint{3, 1, 3, 2, 1}), []uint{3, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueIDs = %v, want %v", got, want)
+	}
+	if got := uniqueIDs(nil); len(got) != 0 {
+		t.Errorf("uniqueIDs(nil) = %v", got)
+	}
+}
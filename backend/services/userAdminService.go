@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/repositories"
+	"github.com/alimosavifard/zyros-backend/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// AdminUser is a user as shown in the admin console.
+type AdminUser struct {
+	Account
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	Suspended        bool       `json:"suspended"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+}
+
+func newAdminUser(user *models.User, now time.Time) AdminUser {
+	admin := AdminUser{
+		Account:          *newAccount(user),
+		SuspendedAt:      user.SuspendedAt,
+		SuspendedUntil:   user.SuspendedUntil,
+		SuspensionReason: user.SuspensionReason,
+		Suspended:        user.IsSuspended(now),
+	}
+	if user.DeletedAt.Valid {
+		deletedAt := user.DeletedAt.Time
+		admin.DeletedAt = &deletedAt
+	}
+	return admin
+}
+
+// UserListQuery filters a user listing. Sort is a field name, prefixed with "-" for
+// descending order; it defaults to the newest users first.
+type UserListQuery struct {
+	Search string
+	Role   string
+	Status string
+	Sort   string
+}
+
+// userSortColumns maps the sort fields a listing accepts to their columns.
+var userSortColumns = map[string]string{
+	"id":           "id",
+	"username":     "username",
+	"display_name": "display_name",
+	"email":        "email",
+	"created_at":   "created_at",
+}
+
+// parseUserSort turns a sort field like "-created_at" into a column and direction.
+func parseUserSort(sort string) (string, bool, error) {
+	if sort == "" {
+		return "created_at", true, nil
+	}
+	desc := strings.HasPrefix(sort, "-")
+	column, ok := userSortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return "", false, utils.ErrInvalidSortField
+	}
+	return column, desc, nil
+}
+
+// UserAdminService is the admin console for user accounts. Role assignment lives
+// in RoleService. Every change is audited.
+type UserAdminService struct {
+	userRepo       *repositories.UserRepository
+	authService    *AuthService
+	accountService *AccountService
+	apiKeyService  *APIKeyService
+	postService    *PostService
+	auditor        Auditor
+}
+
+func NewUserAdminService(userRepo *repositories.UserRepository, authService *AuthService, accountService *AccountService, apiKeyService *APIKeyService, postService *PostService, auditor Auditor) *UserAdminService {
+	return &UserAdminService{
+		userRepo:       userRepo,
+		authService:    authService,
+		accountService: accountService,
+		apiKeyService:  apiKeyService,
+		postService:    postService,
+		auditor:        auditor,
+	}
+}
+
+// ListUsers returns a page of matching users and the number of matches.
+func (s *UserAdminService) ListUsers(ctx context.Context, q UserListQuery, page, limit int) ([]AdminUser, int64, error) {
+	column, desc, err := parseUserSort(q.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	users, total, err := s.userRepo.Search(ctx, repositories.UserQuery{
+		Search:  strings.TrimSpace(q.Search),
+		Role:    q.Role,
+		Status:  q.Status,
+		OrderBy: column,
+		Desc:    desc,
+		Now:     now,
+	}, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]AdminUser, len(users))
+	for i := range users {
+		result[i] = newAdminUser(&users[i], now)
+	}
+	return result, total, nil
+}
+
+// GetUser returns a user, deleted or not.
+func (s *UserAdminService) GetUser(ctx context.Context, id uint) (*AdminUser, error) {
+	user, err := s.userRepo.FindByIDWithTrashed(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	admin := newAdminUser(user, time.Now())
+	return &admin, nil
+}
+
+// Suspend locks the user out until the given time, or bans them when until is nil.
+// Their sessions end at once rather than when their access tokens expire.
+func (s *UserAdminService) Suspend(ctx context.Context, actorID, userID uint, reason string, until *time.Time, client ClientInfo) (*AdminUser, error) {
+	now := time.Now()
+	if until != nil && !until.After(now) {
+		return nil, utils.ErrInvalidExpiry
+	}
+	if _, err := s.target(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+
+	reason = strings.TrimSpace(reason)
+	if err := s.userRepo.Suspend(ctx, userID, reason, now, until); err != nil {
+		return nil, err
+	}
+	if _, err := s.authService.RevokeOtherSessions(ctx, userID, ""); err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{"reason": reason}
+	action := "user.ban"
+	if until != nil {
+		action = "user.suspend"
+		details["until"] = until.UTC().Format(time.RFC3339)
+	}
+	s.audit(ctx, action, actorID, userID, client, details)
+	return s.GetUser(ctx, userID)
+}
+
+// Unsuspend lifts a suspension or ban.
+func (s *UserAdminService) Unsuspend(ctx context.Context, actorID, userID uint, client ClientInfo) (*AdminUser, error) {
+	user, err := s.target(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt == nil {
+		return s.GetUser(ctx, userID)
+	}
+
+	if err := s.userRepo.Unsuspend(ctx, userID); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "user.unsuspend", actorID, userID, client, nil)
+	return s.GetUser(ctx, userID)
+}
+
+// ForcePasswordReset replaces the user's password with one nobody knows, signs them
+// out everywhere, revokes their API keys and mails them a reset link. It reports
+// whether the link could be mailed; without a verified address the user has to ask
+// for one at an address they verify first, or an admin has to help them.
+func (s *UserAdminService) ForcePasswordReset(ctx context.Context, actorID, userID uint, client ClientInfo) (bool, error) {
+	user, err := s.target(ctx, actorID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	password, err := utils.RandomToken(32)
+	if err != nil {
+		return false, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userID, string(hash)); err != nil {
+		return false, err
+	}
+	if _, err := s.authService.RevokeOtherSessions(ctx, userID, ""); err != nil {
+		return false, err
+	}
+	if err := s.apiKeyService.RevokeAll(ctx, userID); err != nil {
+		return false, err
+	}
+
+	mailed, err := s.accountService.SendPasswordReset(ctx, user, true)
+	if err != nil {
+		return false, err
+	}
+	s.audit(ctx, "user.password.force_reset", actorID, userID, client, map[string]interface{}{"mailed": mailed})
+	return mailed, nil
+}
+
+// DeleteUser soft-deletes the user and ends their sessions. Their posts go to
+// reassignTo, which is required if they have any; their comments stay theirs.
+func (s *UserAdminService) DeleteUser(ctx context.Context, actorID, userID, reassignTo uint, client ClientInfo) error {
+	if _, err := s.target(ctx, actorID, userID); err != nil {
+		return err
+	}
+
+	if reassignTo == 0 {
+		posts, err := s.userRepo.CountPosts(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if posts > 0 {
+			return utils.ErrReassignRequired
+		}
+	} else {
+		if reassignTo == userID {
+			return utils.ErrInvalidReassign
+		}
+		author, err := s.userRepo.FindByID(ctx, reassignTo)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.ErrInvalidReassign
+		}
+		if err != nil {
+			return err
+		}
+		if author.IsSuspended(time.Now()) {
+			return utils.ErrInvalidReassign
+		}
+	}
+
+	moved, err := s.userRepo.DeleteAndReassign(ctx, userID, reassignTo)
+	if err != nil {
+		return err
+	}
+	if _, err := s.authService.RevokeOtherSessions(ctx, userID, ""); err != nil {
+		return err
+	}
+	details := map[string]interface{}{"reassigned_posts": moved}
+	if reassignTo != 0 {
+		details["reassigned_to"] = reassignTo
+	}
+	s.audit(ctx, "user.delete", actorID, userID, client, details)
+
+	if moved > 0 {
+		return s.postService.InvalidateAllPostCaches(ctx)
+	}
+	return nil
+}
+
+// target loads the user an admin action is aimed at. Admins can't act on their own
+// account, so nobody locks themselves out by accident.
+func (s *UserAdminService) target(ctx context.Context, actorID, userID uint) (*models.User, error) {
+	if actorID == userID {
+		return nil, utils.ErrSelfAction
+	}
+	return s.authService.FindUser(ctx, userID)
+}
+
+func (s *UserAdminService) audit(ctx context.Context, action string, actorID, targetID uint, client ClientInfo, details map[string]interface{}) {
+	s.auditor.Record(ctx, AuditEvent{Action: action, ActorID: actorID, TargetType: "user", TargetID: targetID, IPAddress: client.IPAddress, Details: details})
+}
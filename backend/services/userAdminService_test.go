@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alimosavifard/zyros-backend/utils"
+)
+
+func TestParseUserSort(t *testing.T) {
+	tests := []struct {
+		sort   string
+		column string
+		desc   bool
+		err    error
+	}{
+		{"", "created_at", true, nil},
+		{"username", "username", false, nil},
+		{"-created_at", "created_at", true, nil},
+		{"-email", "email", true, nil},
+		{"password", "", false, utils.ErrInvalidSortField},
+		{"--username", "", false, utils.ErrInvalidSortField},
+		{"username; DROP TABLE users", "", false, utils.ErrInvalidSortField},
+	}
+	for _, tt := range tests {
+		column, desc, err := parseUserSort(tt.sort)
+		if column != tt.column || desc != tt.desc || !errors.Is(err, tt.err) {
+			t.Errorf("parseUserSort(%q) = %q, %v, %v; want %q, %v, %v", tt.sort, column, desc, err, tt.column, tt.desc, tt.err)
+		}
+	}
+}
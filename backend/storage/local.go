@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LocalStorage keeps files in a directory on disk and serves them itself. Every
+// replica must see the same directory, e.g. through a shared volume; otherwise use
+// S3Storage.
+type LocalStorage struct {
+	dir     string
+	baseURL string
+	signer  urlSigner
+}
+
+// NewLocalStorage stores files under dir and serves them at baseURL, signing URLs
+// with signingKey.
+func NewLocalStorage(dir, baseURL string, signingKey []byte) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/"), signer: urlSigner{secret: signingKey}}, nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file and renames it into place, so readers never see
+// a partial file.
+func (s *LocalStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && written != size {
+		return errors.New("storage: body is shorter or longer than its size")
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	return s.open(key)
+}
+
+func (s *LocalStorage) open(key string) (io.ReadSeekCloser, *Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(path)
+	return opened(key, file, err)
+}
+
+// opened describes a file just opened for key, or maps the error opening it.
+func opened(key string, file http.File, err error) (io.ReadSeekCloser, *Object, error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, nil, ErrNotFound
+	}
+	return file, localObject(key, info), nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && info.IsDir() {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return localObject(key, info), nil
+}
+
+func (s *LocalStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	if err := checkExpiry(expiry); err != nil {
+		return "", err
+	}
+	return s.signer.sign(s.URL(key), key, time.Now().Add(expiry)), nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// ServeHTTP serves the file named by the request's path, which is expected to be
+// stripped of the base URL's path.
+func (s *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, s.signer, s.servable, s.openServed)
+}
+
+// Uploads from before keys were generated are named "<unix nanos>-<client file
+// name>" at the top of the directory, and posts still link to them. Their names
+// may hold anything the client sent, such as spaces or Persian letters, so they
+// don't pass ValidKey; they are served read-only through http.Dir, which rejects
+// paths leaving the directory.
+func (s *LocalStorage) servable(key string) bool {
+	return ValidKey(key) || legacyName(key)
+}
+
+func (s *LocalStorage) openServed(key string) (io.ReadSeekCloser, *Object, error) {
+	if ValidKey(key) {
+		return s.open(key)
+	}
+	file, err := http.Dir(s.dir).Open("/" + key)
+	return opened(key, file, err)
+}
+
+// legacyName reports whether name looks like a file saved by the old upload
+// handler: a single path segment starting with digits and a dash.
+func legacyName(name string) bool {
+	if len(name) > 512 || !utf8.ValidString(name) || strings.ContainsAny(name, "/\\\x00") {
+		return false
+	}
+	digits := 0
+	for digits < len(name) && name[digits] >= '0' && name[digits] <= '9' {
+		digits++
+	}
+	return digits > 0 && digits < len(name)-1 && name[digits] == '-'
+}
+
+// localObject describes a file on disk. The disk keeps no content type, so it is
+// inferred from the key's extension.
+func localObject(key string, info fs.FileInfo) *Object {
+	return &Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		ModTime:     info.ModTime(),
+	}
+}
@@ -0,0 +1,145 @@
+// Package storage keeps uploaded files, on local disk or in an S3-compatible
+// bucket, behind one interface.
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// MaxSignedURLExpiry is the longest a signed URL may stay valid, S3's own limit.
+const MaxSignedURLExpiry = 7 * 24 * time.Hour
+
+// Object describes a stored file.
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Storage keeps files under slash-separated keys such as "images/2026/10/x.png".
+type Storage interface {
+	// Put stores size bytes from body under key, replacing any file already there.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the file under key. The caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete removes the file under key; a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*Object, error)
+	// SignedURL returns a URL that reads the file until expiry has passed.
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// URL is the file's permanent public address.
+	URL(key string) string
+}
+
+// ValidKey reports whether key is safe as both a file path and an object name:
+// non-empty segments of letters, digits, dots, dashes and underscores, none of
+// them "." or "..".
+func ValidKey(key string) bool {
+	if key == "" || len(key) > 512 {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func checkExpiry(expiry time.Duration) error {
+	if expiry <= 0 || expiry > MaxSignedURLExpiry {
+		return errors.New("storage: signed URL expiry must be positive and at most 7 days")
+	}
+	return nil
+}
+
+// urlSigner signs the URLs of drivers that serve their own files, as an
+// HMAC-SHA256 over the key and the expiry time.
+type urlSigner struct {
+	secret []byte
+}
+
+func (s urlSigner) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign returns url with the expiry and signature for key appended.
+func (s urlSigner) sign(url, key string, expires time.Time) string {
+	unix := expires.Unix()
+	return url + "?expires=" + strconv.FormatInt(unix, 10) + "&signature=" + s.signature(key, unix)
+}
+
+// allowed reports whether r may read key. Requests without a signature are
+// allowed, as files are public; a signed URL that has expired or doesn't match is
+// refused rather than served as if unsigned.
+func (s urlSigner) allowed(r *http.Request, key string, now time.Time) bool {
+	query := r.URL.Query()
+	signature := query.Get("signature")
+	if signature == "" && query.Get("expires") == "" {
+		return true
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.signature(key, expires)))
+}
+
+// serve writes a file for the ServeHTTP of drivers that serve their own files.
+// The request's path is the key, as left by http.StripPrefix; paths valid doesn't
+// accept are not found.
+func serve(w http.ResponseWriter, r *http.Request, signer urlSigner, valid func(key string) bool, open func(key string) (io.ReadSeekCloser, *Object, error)) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if !valid(key) {
+		http.NotFound(w, r)
+		return
+	}
+	if !signer.allowed(r, key, time.Now()) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	file, object, err := open(key)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	if object.ContentType != "" {
+		w.Header().Set("Content-Type", object.ContentType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", object.ModTime, file)
+}
@@ -0,0 +1,88 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 appendix B test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code, _ := Code(rfcSecret, step)
+	previous, _ := Code(rfcSecret, step-1)
+	stale, _ := Code(rfcSecret, step-2)
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code, 0, step, true},
+		{"spaces are ignored", code[:3] + " " + code[3:], 0, step, true},
+		{"previous step within skew", previous, 0, step - 1, true},
+		{"outside skew", stale, 0, 0, false},
+		{"replayed step", code, step, 0, false},
+		{"wrong code", "000000", 0, 0, false},
+		{"wrong length", "12345", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Validate(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || got != tt.wantStep {
+				t.Errorf("Validate = (%d, %v), want (%d, %v)", got, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecretRoundTrips(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := Code(strings.ToLower(secret), 1)
+	if err != nil || len(code) != Digits {
+		t.Fatalf("Code with generated secret = %q, %v", code, err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	got := ProvisioningURI("Zyros", "ali reza", "JBSWY3DPEHPK3PXP")
+	want := "otpauth://totp/Zyros:ali%20reza?algorithm=SHA1&digits=6&issuer=Zyros&period=30&secret=JBSWY3DPEHPK3PXP"
+	if got != want {
+		t.Errorf("ProvisioningURI = %s, want %s", got, want)
+	}
+}
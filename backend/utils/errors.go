@@ -0,0 +1,60 @@
+package utils
+
+import "errors"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrWrongPassword      = errors.New("current password is incorrect")
+	ErrPostNotFound       = errors.New("post not found")
+	ErrForbidden          = errors.New("forbidden")
+	ErrRevisionNotFound   = errors.New("revision not found")
+	ErrVersionConflict    = errors.New("post was modified by someone else")
+
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrAccountExists       = errors.New("username or email is already registered")
+	ErrInvalidAccountToken = errors.New("invalid or expired link")
+	ErrLoginLocked         = errors.New("too many failed login attempts, try again later")
+	ErrUnknownProvider     = errors.New("unknown identity provider")
+	ErrInvalidOIDCState    = errors.New("invalid or expired login state")
+	ErrAccountSuspended    = errors.New("this account is suspended")
+
+	ErrRoleNotFound        = errors.New("role not found")
+	ErrPermissionNotFound  = errors.New("permission not found")
+	ErrRoleExists          = errors.New("a role with that name already exists")
+	ErrPermissionExists    = errors.New("a permission with that name already exists")
+	ErrProtectedRole       = errors.New("built-in roles can't be renamed or deleted")
+	ErrProtectedPermission = errors.New("permissions the application checks can't be renamed or deleted")
+	ErrAdminLockout        = errors.New("nobody would be left able to manage users")
+	ErrInvalidName         = errors.New("names may only contain lowercase letters, digits and underscores")
+	ErrSelfAction          = errors.New("you can't do this to your own account")
+	ErrReassignRequired    = errors.New("the user has posts; choose an author to reassign them to")
+	ErrInvalidReassign     = errors.New("posts can only be reassigned to another active account")
+	ErrInvalidSortField    = errors.New("invalid sort field")
+
+	ErrInvalidAPIKey  = errors.New("invalid or expired API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidScope   = errors.New("scopes must be permissions you hold")
+	ErrInvalidExpiry  = errors.New("expiry must be in the future")
+
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrMFARequired         = errors.New("two-factor authentication is required for this account")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired login challenge")
+
+	ErrCategoryNotFound      = errors.New("category not found")
+	ErrTagNotFound           = errors.New("tag not found")
+	ErrSlugTaken             = errors.New("slug already in use")
+	ErrInvalidSlug           = errors.New("name or slug has no letters or digits")
+	ErrInvalidCategoryParent = errors.New("invalid parent category")
+	ErrCategoryHasChildren   = errors.New("category has subcategories")
+
+	ErrCommentNotFound      = errors.New("comment not found")
+	ErrCommentsClosed       = errors.New("comments are only open on published posts")
+	ErrInvalidCommentParent = errors.New("parent comment not found on this post")
+	ErrEmptyComment         = errors.New("comment is empty")
+)
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+var persianReplacer = strings.NewReplacer(
+	"ي", "ی", // Arabic yeh
+	"ى", "ی", // Arabic alef maksura
+	"ك", "ک", // Arabic kaf
+	"۰", "0", "۱", "1", "۲", "2", "۳", "3", "۴", "4",
+	"۵", "5", "۶", "6", "۷", "7", "۸", "8", "۹", "9",
+	"٠", "0", "١", "1", "٢", "2", "٣", "3", "٤", "4",
+	"٥", "5", "٦", "6", "٧", "7", "٨", "8", "٩", "9",
+)
+
+// NormalizePersian maps the Arabic yeh and kaf that Arabic keyboards produce to their
+// Persian forms, converts Persian and Arabic-Indic digits to ASCII and drops
+// diacritics and tatweel, so the same word always has the same spelling.
+func NormalizePersian(s string) string {
+	s = persianReplacer.Replace(s)
+	return strings.Map(func(r rune) rune {
+		if isArabicMark(r) || r == '\u0640' { // tatweel
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// isArabicMark reports whether r is a harakat or other combining mark of the Arabic block.
+func isArabicMark(r rune) bool {
+	return (r >= '\u064b' && r <= '\u065f') || r == '\u0670'
+}
+
+// MaxSlugLength caps the length of generated slugs, in runes.
+const MaxSlugLength = 80
+
+// Slugify turns a title or name into a lowercase URL slug. Letters and digits of any
+// script are kept, so Persian slugs stay readable once percent-decoded; spaces,
+// half-spaces (ZWNJ) and punctuation collapse into single hyphens. Long slugs are cut
+// at a word boundary.
+func Slugify(s string) string {
+	s = strings.ToLower(NormalizePersian(s))
+
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		pendingHyphen = true
+	}
+	return truncateSlug(b.String())
+}
+
+func truncateSlug(slug string) string {
+	runes := []rune(slug)
+	if len(runes) <= MaxSlugLength {
+		return slug
+	}
+	runes = runes[:MaxSlugLength]
+	for i := len(runes) - 1; i > MaxSlugLength/2; i-- {
+		if runes[i] == '-' {
+			return string(runes[:i])
+		}
+	}
+	return strings.TrimRight(string(runes), "-")
+}
+
+// CleanTagName tidies a tag as typed by a user for display: the leading '#' is
+// dropped, Persian spelling is normalized and whitespace collapsed.
+func CleanTagName(s string) string {
+	s = strings.TrimLeft(strings.TrimSpace(s), "#")
+	return strings.Join(strings.Fields(NormalizePersian(s)), " ")
+}
+
+// NormalizeSearchText prepares Persian text for full-text search the way the
+// fa_normalize SQL function does: NormalizePersian plus half-spaces (ZWNJ) read as
+// spaces, so "کتاب" also finds "کتاب‌ها".
+func NormalizeSearchText(s string) string {
+	return strings.ReplaceAll(NormalizePersian(s), "\u200c", " ")
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizePersian(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"علي", "علی"},
+		{"مصطفى", "مصطفی"},
+		{"كتاب", "کتاب"},
+		{"سال ۱۴۰۲ و ٢٠٢٣", "سال 1402 و 2023"},
+		{"مُحَمَّد", "محمد"},
+		{"کتـــاب", "کتاب"},
+		{"plain English 42", "plain English 42"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizePersian(tt.in); got != tt.want {
+			t.Errorf("NormalizePersian(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCleanTagName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"  #برنامه‌نويسي  ", "برنامه‌نویسی"},
+		{"##Go   Lang", "Go Lang"},
+		{"web\tdev\n", "web dev"},
+		{"#", ""},
+		{"   ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := CleanTagName(tt.in); got != tt.want {
+			t.Errorf("CleanTagName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"  Go 1.22 -- released  ", "go-1-22-released"},
+		{"برنامه‌نويسي با گو", "برنامه-نویسی-با-گو"},
+		{"سال ۱۴۰۲", "سال-1402"},
+		{"مُحَمَّد", "محمد"},
+		{"Café au lait", "café-au-lait"},
+		{"!!!", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Slugify(tt.in); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateSlug(t *testing.T) {
+	words := strings.Repeat("word-", 20) // 100 runes
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"short", "short-slug", "short-slug"},
+		{"exactly the limit", strings.Repeat("a", MaxSlugLength), strings.Repeat("a", MaxSlugLength)},
+		{"cut at a word boundary", words, strings.Repeat("word-", 15) + "word"},
+		{"one long word", strings.Repeat("a", 100), strings.Repeat("a", MaxSlugLength)},
+		{"persian counts runes", strings.Repeat("ب", 100), strings.Repeat("ب", MaxSlugLength)},
+		{"no boundary in the second half", strings.Repeat("a", 30) + "-" + strings.Repeat("b", 70), strings.Repeat("a", 30) + "-" + strings.Repeat("b", 49)},
+	}
+
+	for _, tt := range tests {
+		if got := truncateSlug(tt.in); got != tt.want {
+			t.Errorf("%s: truncateSlug = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -21,6 +21,14 @@ func SendSuccess(ctx *gin.Context, message string, data interface{}, meta interf
     ctx.JSON(http.StatusOK, response)
 }
 
+// SendErrorWithMeta sends an error response with structured details, such as a policy decision, in meta.
+func SendErrorWithMeta(ctx *gin.Context, statusCode int, message string, meta interface{}) {
+    ctx.JSON(statusCode, StandardResponse{
+        Error: message,
+        Meta:  meta,
+    })
+}
+
 func SendError(ctx *gin.Context, statusCode int, message string, err error) {
     if err != nil {
         InitLogger().Error().Err(err).Msg(message)
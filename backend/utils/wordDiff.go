@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DiffOp marks how a chunk of text changed between two versions.
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffChunk is a run of consecutive tokens sharing the same DiffOp.
+type DiffChunk struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// Past these limits WordDiff stops looking for the shortest edit script and
+// reports the changed middle as deleted and inserted whole. The search keeps
+// O(D²) state for D edits, so the edit limit bounds its memory.
+const (
+	maxDiffTokens = 50000
+	maxDiffEdits  = 1000
+)
+
+// WordDiff compares two texts word by word. HTML tags and whitespace runs count
+// as single tokens, so markup changes never split a word in two.
+func WordDiff(from, to string) []DiffChunk {
+	a, b := tokenize(from), tokenize(to)
+
+	var chunks []DiffChunk
+	emit := func(op DiffOp, token string) {
+		if n := len(chunks); n > 0 && chunks[n-1].Op == op {
+			chunks[n-1].Text += token
+			return
+		}
+		chunks = append(chunks, DiffChunk{Op: op, Text: token})
+	}
+
+	// Strip the common prefix and suffix before running the O(ND) search on the rest
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && a[endA-1] == b[endB-1] {
+		endA--
+		endB--
+	}
+
+	for _, token := range a[:start] {
+		emit(DiffEqual, token)
+	}
+	middleA, middleB := a[start:endA], b[start:endB]
+	edits, ok := myers(middleA, middleB, maxDiffEdits)
+	if !ok {
+		edits = replaceAll(middleA, middleB)
+	}
+	for _, e := range edits {
+		emit(e.op, e.token)
+	}
+	for _, token := range a[endA:] {
+		emit(DiffEqual, token)
+	}
+	return chunks
+}
+
+type edit struct {
+	op    DiffOp
+	token string
+}
+
+// replaceAll is the edit script that deletes all of a and inserts all of b.
+func replaceAll(a, b []string) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, token := range a {
+		edits = append(edits, edit{DiffDelete, token})
+	}
+	for _, token := range b {
+		edits = append(edits, edit{DiffInsert, token})
+	}
+	return edits
+}
+
+// myers returns the shortest edit script turning a into b (Myers, 1986), or false
+// if it takes more than maxEdits edits.
+func myers(a, b []string, maxEdits int) ([]edit, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil, true
+	}
+	if max > maxDiffTokens {
+		return nil, false
+	}
+	if maxEdits > max {
+		maxEdits = max
+	}
+
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds the frontier before step d, but only diagonals -d-1..d+1:
+	// step d can't have reached any others, and they are all the walk back reads
+	var trace [][]int
+
+	found := false
+search:
+	for d := 0; d <= maxEdits; d++ {
+		band := make([]int, 2*d+3)
+		copy(band, v[offset-d-1:offset+d+2])
+		trace = append(trace, band)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break search
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	// Walk the recorded frontiers backwards to recover the path
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		band, bandOffset := trace[d], d+1
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && band[bandOffset+k-1] < band[bandOffset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := band[bandOffset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{DiffEqual, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				edits = append(edits, edit{DiffInsert, b[y]})
+			} else {
+				x--
+				edits = append(edits, edit{DiffDelete, a[x]})
+			}
+		}
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits, true
+}
+
+// tokenize splits text into HTML tags, whitespace runs and words.
+func tokenize(s string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '<':
+			flush()
+			j := i
+			for j < len(runes) && runes[j] != '>' {
+				j++
+			}
+			if j == len(runes) {
+				j--
+			}
+			tokens = append(tokens, string(runes[i:j+1]))
+			i = j
+		case unicode.IsSpace(r):
+			flush()
+			j := i
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j - 1
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWordDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     []DiffChunk
+	}{
+		{"identical", "same old text", "same old text", []DiffChunk{{DiffEqual, "same old text"}}},
+		{"both empty", "", "", nil},
+		{"from empty", "", "new text", []DiffChunk{{DiffInsert, "new text"}}},
+		{"to empty", "old text", "", []DiffChunk{{DiffDelete, "old text"}}},
+		{"insert only", "a c", "a b c", []DiffChunk{{DiffEqual, "a "}, {DiffInsert, "b "}, {DiffEqual, "c"}}},
+		{"delete only", "a b c", "a c", []DiffChunk{{DiffEqual, "a "}, {DiffDelete, "b "}, {DiffEqual, "c"}}},
+		{"replace a word", "the quick fox", "the slow fox", []DiffChunk{{DiffEqual, "the "}, {DiffDelete, "quick"}, {DiffInsert, "slow"}, {DiffEqual, " fox"}}},
+		{"persian", "سلام دنیای زیبا", "سلام دنیای بزرگ", []DiffChunk{{DiffEqual, "سلام دنیای "}, {DiffDelete, "زیبا"}, {DiffInsert, "بزرگ"}}},
+		{"markup is one token", "<p>hello</p>", "<p><b>hello</b></p>", []DiffChunk{{DiffEqual, "<p>"}, {DiffInsert, "<b>"}, {DiffEqual, "hello"}, {DiffInsert, "</b>"}, {DiffEqual, "</p>"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WordDiff(tt.from, tt.to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WordDiff(%q, %q) = %+v, want %+v", tt.from, tt.to, got, tt.want)
+			}
+			checkDiff(t, tt.from, tt.to, got)
+		})
+	}
+}
+
+// Every change is found past the edit limit too, only less precisely.
+func TestWordDiffEditLimit(t *testing.T) {
+	var from, to []string
+	for i := 0; i < maxDiffEdits; i++ {
+		from = append(from, "a")
+		to = append(to, "b")
+	}
+	a, b := strings.Join(from, " "), strings.Join(to, " ")
+
+	got := WordDiff(a, b)
+	checkDiff(t, a, b, got)
+	want := []DiffChunk{{DiffDelete, a}, {DiffInsert, b}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WordDiff past the edit limit returned %d chunks, want a whole replacement", len(got))
+	}
+
+	// Under the limit the shortest script keeps the shared whitespace
+	got = WordDiff("a b", "c b")
+	want = []DiffChunk{{DiffDelete, "a"}, {DiffInsert, "c"}, {DiffEqual, " b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WordDiff under the edit limit = %+v", got)
+	}
+}
+
+// checkDiff checks that chunks rebuild both texts.
+func checkDiff(t *testing.T, from, to string, chunks []DiffChunk) {
+	t.Helper()
+	var gotFrom, gotTo strings.Builder
+	for _, c := range chunks {
+		if c.Op != DiffInsert {
+			gotFrom.WriteString(c.Text)
+		}
+		if c.Op != DiffDelete {
+			gotTo.WriteString(c.Text)
+		}
+	}
+	if gotFrom.String() != from || gotTo.String() != to {
+		t.Errorf("chunks rebuild %q → %q, want %q → %q", gotFrom.String(), gotTo.String(), from, to)
+	}
+}
@@ -0,0 +1,66 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+)
+
+var (
+	// ErrInvalidTransition is returned when a transition is not allowed from the post's current status.
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrInvalidSchedule is returned when publish_at/unpublish_at are missing or out of order.
+	ErrInvalidSchedule = errors.New("invalid publishing schedule")
+)
+
+// Transition names a move between two workflow states.
+type Transition string
+
+const (
+	Submit     Transition = "submit"
+	Withdraw   Transition = "withdraw"
+	Approve    Transition = "approve"
+	Reject     Transition = "reject"
+	Publish    Transition = "publish"
+	Schedule   Transition = "schedule"
+	Unschedule Transition = "unschedule"
+	Archive    Transition = "archive"
+	Reopen     Transition = "reopen"
+)
+
+// Step describes where a transition may start, where it ends and which policy action guards it.
+type Step struct {
+	From   []string
+	To     string
+	Action policy.Action
+}
+
+// Steps is the editorial state machine:
+// draft → in_review → approved → scheduled/published → archived.
+var Steps = map[Transition]Step{
+	Submit:     {From: []string{models.PostStatusDraft}, To: models.PostStatusInReview, Action: policy.ActionPostSubmit},
+	Withdraw:   {From: []string{models.PostStatusInReview}, To: models.PostStatusDraft, Action: policy.ActionPostSubmit},
+	Approve:    {From: []string{models.PostStatusInReview}, To: models.PostStatusApproved, Action: policy.ActionPostReview},
+	Reject:     {From: []string{models.PostStatusInReview, models.PostStatusApproved}, To: models.PostStatusDraft, Action: policy.ActionPostReview},
+	Publish:    {From: []string{models.PostStatusApproved, models.PostStatusScheduled}, To: models.PostStatusPublished, Action: policy.ActionPostPublish},
+	Schedule:   {From: []string{models.PostStatusApproved}, To: models.PostStatusScheduled, Action: policy.ActionPostPublish},
+	Unschedule: {From: []string{models.PostStatusScheduled}, To: models.PostStatusApproved, Action: policy.ActionPostPublish},
+	Archive:    {From: []string{models.PostStatusPublished}, To: models.PostStatusArchived, Action: policy.ActionPostPublish},
+	Reopen:     {From: []string{models.PostStatusArchived}, To: models.PostStatusDraft, Action: policy.ActionPostPublish},
+}
+
+// Next returns the step for applying transition t to a post in status current.
+func Next(current string, t Transition) (Step, error) {
+	step, ok := Steps[t]
+	if !ok {
+		return Step{}, fmt.Errorf("%w: unknown transition %q", ErrInvalidTransition, t)
+	}
+	for _, from := range step.From {
+		if from == current {
+			return step, nil
+		}
+	}
+	return Step{}, fmt.Errorf("%w: cannot %s a post that is %s", ErrInvalidTransition, t, current)
+}
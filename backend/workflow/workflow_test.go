@@ -0,0 +1,85 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alimosavifard/zyros-backend/models"
+	"github.com/alimosavifard/zyros-backend/policy"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		t       Transition
+		to      string
+		action  policy.Action
+		allowed bool
+	}{
+		{"submit draft", models.PostStatusDraft, Submit, models.PostStatusInReview, policy.ActionPostSubmit, true},
+		{"withdraw from review", models.PostStatusInReview, Withdraw, models.PostStatusDraft, policy.ActionPostSubmit, true},
+		{"approve", models.PostStatusInReview, Approve, models.PostStatusApproved, policy.ActionPostReview, true},
+		{"reject in review", models.PostStatusInReview, Reject, models.PostStatusDraft, policy.ActionPostReview, true},
+		{"reject approved", models.PostStatusApproved, Reject, models.PostStatusDraft, policy.ActionPostReview, true},
+		{"publish approved", models.PostStatusApproved, Publish, models.PostStatusPublished, policy.ActionPostPublish, true},
+		{"publish scheduled early", models.PostStatusScheduled, Publish, models.PostStatusPublished, policy.ActionPostPublish, true},
+		{"schedule approved", models.PostStatusApproved, Schedule, models.PostStatusScheduled, policy.ActionPostPublish, true},
+		{"unschedule", models.PostStatusScheduled, Unschedule, models.PostStatusApproved, policy.ActionPostPublish, true},
+		{"archive published", models.PostStatusPublished, Archive, models.PostStatusArchived, policy.ActionPostPublish, true},
+		{"reopen archived", models.PostStatusArchived, Reopen, models.PostStatusDraft, policy.ActionPostPublish, true},
+
+		{"publish draft", models.PostStatusDraft, Publish, "", "", false},
+		{"publish in review", models.PostStatusInReview, Publish, "", "", false},
+		{"approve draft", models.PostStatusDraft, Approve, "", "", false},
+		{"submit twice", models.PostStatusInReview, Submit, "", "", false},
+		{"schedule published", models.PostStatusPublished, Schedule, "", "", false},
+		{"schedule draft", models.PostStatusDraft, Schedule, "", "", false},
+		{"unschedule approved", models.PostStatusApproved, Unschedule, "", "", false},
+		{"archive draft", models.PostStatusDraft, Archive, "", "", false},
+		{"withdraw approved", models.PostStatusApproved, Withdraw, "", "", false},
+		{"reopen published", models.PostStatusPublished, Reopen, "", "", false},
+		{"unknown transition", models.PostStatusDraft, Transition("teleport"), "", "", false},
+		{"unknown status", "deleted", Submit, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := Next(tt.current, tt.t)
+			if !tt.allowed {
+				if !errors.Is(err, ErrInvalidTransition) {
+					t.Fatalf("Next(%q, %q) = %+v, %v; want ErrInvalidTransition", tt.current, tt.t, step, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Next(%q, %q): %v", tt.current, tt.t, err)
+			}
+			if step.To != tt.to || step.Action != tt.action {
+				t.Errorf("Next(%q, %q) = %s via %s, want %s via %s", tt.current, tt.t, step.To, step.Action, tt.to, tt.action)
+			}
+		})
+	}
+}
+
+// Every transition must start from somewhere and lead to a real status.
+func TestStepsAreComplete(t *testing.T) {
+	statuses := map[string]bool{
+		models.PostStatusDraft:     true,
+		models.PostStatusInReview:  true,
+		models.PostStatusApproved:  true,
+		models.PostStatusScheduled: true,
+		models.PostStatusPublished: true,
+		models.PostStatusArchived:  true,
+	}
+	for name, step := range Steps {
+		if len(step.From) == 0 || !statuses[step.To] || step.Action == "" {
+			t.Errorf("%s = %+v", name, step)
+		}
+		for _, from := range step.From {
+			if !statuses[from] {
+				t.Errorf("%s starts from unknown status %q", name, from)
+			}
+		}
+	}
+}
@@ -53,11 +53,12 @@ const UserProfile = () => {
         {/* User Profile Card */}
         <div className="bg-white rounded-lg shadow-md p-6 mb-8 text-center">
           <img
-            src={profile.profilePictureUrl || 'https://via.placeholder.com/150'}
+            src={profile.avatar_url || 'https://via.placeholder.com/150'}
             alt={`${profile.username}'s profile`}
             className="w-32 h-32 rounded-full mx-auto mb-4 border-4 border-gray-300"
           />
-          <h1 className="text-3xl font-bold text-gray-900">{profile.username}</h1>
+          <h1 className="text-3xl font-bold text-gray-900">{profile.display_name || profile.username}</h1>
+          {profile.display_name && <p className="text-gray-500">@{profile.username}</p>}
           {profile.bio && <p className="text-gray-600 mt-2">{profile.bio}</p>}
         </div>
 
@@ -77,4 +78,4 @@ const UserProfile = () => {
   );
 };
 
-export default UserProfile;
\ No newline at end of file
+export default UserProfile;
\ No newline at end of file